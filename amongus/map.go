@@ -5,11 +5,15 @@ import (
 	"fmt"
 	"log"
 	"net/url"
-	"os"
 	"strings"
 	"github.com/automuteus/utils/pkg/settings"
 )
 
+const DefaultBaseMapURL = "https://github.com/automuteus/automuteus/blob/master/assets/maps/"
+
+// BaseMapURL is where the map images are hosted, in a folder per language
+var BaseMapURL = DefaultBaseMapURL
+
 type MapItem struct {
 	Name     string
 	MapImage MapImage
@@ -40,14 +44,7 @@ func NewMapItem(name string, sett *settings.GuildSettings) (*MapItem, error) {
 		return nil, errors.New(fmt.Sprintf("Invalid map name: %s", name))
 	}
 
-	BaseMapURL := os.Getenv("BASE_MAP_URL")
-	if BaseMapURL == "" {
-		BaseMapURL = "https://github.com/automuteus/automuteus/blob/master/assets/maps/"
-	}
-
-	BaseMapURL += sett.GetLanguage() + "/"
-
-	base, err := url.Parse(BaseMapURL)
+	base, err := url.Parse(BaseMapURL + sett.GetLanguage() + "/")
 	if err != nil {
 		log.Println(err)
 	}
@@ -0,0 +1,102 @@
+// Command replay feeds the recorded capture events of a game through the bot offline, and prints every
+// mute/deafen request the bot would have made. Events are read from a JSONL file (one job per line, in the same
+// format the capture pushes to Redis), or from the game_events of a match in Postgres.
+//
+//	replay -config guild.json -events game.jsonl
+//	POSTGRES_ADDR=... POSTGRES_USER=... POSTGRES_PASS=... replay -config guild.json -match 1234
+//
+// The config is a JSON ReplayConfig: the guild, connect code and channels of the game, the members that were
+// in voice (and the in-game names they were linked to), and optionally the guild's settings.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/automuteus/automuteus/discord"
+	"github.com/automuteus/utils/pkg/locale"
+	"github.com/automuteus/utils/pkg/storage"
+	"github.com/automuteus/utils/pkg/task"
+)
+
+func main() {
+	err := replayMainWrapper()
+	if err != nil {
+		log.Println("Replay exited with the following error:")
+		log.Println(err)
+		os.Exit(1)
+	}
+}
+
+func replayMainWrapper() error {
+	configPath := flag.String("config", "", "path to the JSON replay config")
+	eventsPath := flag.String("events", "", "path to a JSONL file of recorded jobs")
+	matchID := flag.String("match", "", "ID of a match to load the recorded events of from Postgres")
+	asJSON := flag.Bool("json", false, "print the decisions as JSON instead of text")
+	flag.Parse()
+
+	if *configPath == "" {
+		return errors.New("no -config specified")
+	}
+	if (*eventsPath == "") == (*matchID == "") {
+		return errors.New("specify exactly one of -events or -match")
+	}
+
+	// the bot's own logging goes to stderr, so the decisions can be piped
+	log.SetOutput(os.Stderr)
+
+	configBytes, err := ioutil.ReadFile(*configPath)
+	if err != nil {
+		return err
+	}
+	config := discord.ReplayConfig{}
+	err = json.Unmarshal(configBytes, &config)
+	if err != nil {
+		return err
+	}
+
+	var jobs []task.Job
+	if *eventsPath != "" {
+		f, err := os.Open(*eventsPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		jobs, err = discord.ReadReplayJobs(f)
+		if err != nil {
+			return err
+		}
+	} else {
+		psql := storage.PsqlInterface{}
+		err := psql.Init(storage.ConstructPsqlConnectURL(os.Getenv("POSTGRES_ADDR"), os.Getenv("POSTGRES_USER"), os.Getenv("POSTGRES_PASS")))
+		if err != nil {
+			return err
+		}
+		jobs, err = discord.ReplayJobsFromPostgres(&psql, *matchID)
+		if err != nil {
+			return err
+		}
+	}
+
+	locale.InitLang(os.Getenv("LOCALE_PATH"), os.Getenv("BOT_LANG"))
+
+	decisions, err := discord.Replay(config, jobs)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(decisions)
+	}
+	for _, v := range decisions {
+		fmt.Println(v.String())
+	}
+	return nil
+}
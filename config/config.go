@@ -0,0 +1,262 @@
+// Package config loads the bot's configuration from a TOML file and/or the environment, and validates all of it up
+// front. Every setting can be provided either way; the environment takes precedence over the file, so a file can hold
+// the defaults of a deployment while secrets are passed as env vars. See config.example.toml for every key.
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/logging"
+)
+
+const (
+	DefaultURL            = "http://localhost:8123"
+	DefaultMaxActiveGames = 150
+	DefaultLogPath        = "./"
+	DefaultLogMaxSizeMB   = 10
+	DefaultLogMaxBackups  = 5
+
+	// VoiceBackendGalactus mutes/deafens through Galactus, which spreads the requests over the worker tokens
+	VoiceBackendGalactus = "galactus"
+	// VoiceBackendDiscord mutes/deafens directly through the Discord API, so Galactus doesn't have to be deployed
+	VoiceBackendDiscord = "discord"
+
+	redacted = "REDACTED"
+)
+
+// Config is every setting of the bot. The `toml` tag is the key in the config file, `env` the env var that overrides it,
+// and `secret` fields are redacted when the config is printed
+type Config struct {
+	DiscordBotToken string `toml:"discord_bot_token" env:"DISCORD_BOT_TOKEN" secret:"true"`
+	// DiscordBotToken2 is deprecated; it's treated like one more worker token
+	DiscordBotToken2 string   `toml:"discord_bot_token_2" env:"DISCORD_BOT_TOKEN_2" secret:"true"`
+	WorkerBotTokens  []string `toml:"worker_bot_tokens" env:"WORKER_BOT_TOKENS" secret:"true"`
+	EmojiGuildID     string   `toml:"emoji_guild_id" env:"EMOJI_GUILD_ID"`
+	NumShards        int      `toml:"num_shards" env:"NUM_SHARDS"`
+	ShardID          int      `toml:"shard_id" env:"SHARD_ID"`
+	Host             string   `toml:"host" env:"HOST"`
+
+	RedisAddr    string `toml:"redis_addr" env:"REDIS_ADDR"`
+	RedisPass    string `toml:"redis_pass" env:"REDIS_PASS" secret:"true"`
+	VoiceBackend string `toml:"voice_backend" env:"VOICE_BACKEND"`
+	GalactusAddr string `toml:"galactus_addr" env:"GALACTUS_ADDR"`
+	PostgresAddr string `toml:"postgres_addr" env:"POSTGRES_ADDR"`
+	PostgresUser string `toml:"postgres_user" env:"POSTGRES_USER"`
+	PostgresPass string `toml:"postgres_pass" env:"POSTGRES_PASS" secret:"true"`
+
+	LocalePath     string `toml:"locale_path" env:"LOCALE_PATH"`
+	BotLang        string `toml:"bot_lang" env:"BOT_LANG"`
+	Official       bool   `toml:"official" env:"AUTOMUTEUS_OFFICIAL"`
+	GlobalPrefix   string `toml:"global_prefix" env:"AUTOMUTEUS_GLOBAL_PREFIX"`
+	Listening      string `toml:"listening" env:"AUTOMUTEUS_LISTENING"`
+	MaxActiveGames int    `toml:"max_active_games" env:"MAX_ACTIVE_GAMES"`
+	BaseMapURL     string `toml:"base_map_url" env:"BASE_MAP_URL"`
+
+	SettingsAPIPort string `toml:"settings_api_port" env:"SETTINGS_API_PORT"`
+	NodeID          string `toml:"node_id" env:"SCW_NODE_ID"`
+
+	LogPath        string `toml:"log_path" env:"LOG_PATH"`
+	LogLevel       string `toml:"log_level" env:"LOG_LEVEL"`
+	LogFormat      string `toml:"log_format" env:"LOG_FORMAT"`
+	DisableLogFile bool   `toml:"disable_log_file" env:"DISABLE_LOG_FILE"`
+	LogMaxSizeMB   int    `toml:"log_max_size_mb" env:"LOG_MAX_SIZE_MB"`
+	LogMaxBackups  int    `toml:"log_max_backups" env:"LOG_MAX_BACKUPS"`
+}
+
+func Default() *Config {
+	return &Config{
+		NumShards:      1,
+		ShardID:        0,
+		Host:           DefaultURL,
+		VoiceBackend:   VoiceBackendGalactus,
+		MaxActiveGames: DefaultMaxActiveGames,
+		BaseMapURL:     amongus.DefaultBaseMapURL,
+		LogPath:        DefaultLogPath,
+		LogLevel:       logging.LevelInfo.String(),
+		LogFormat:      string(logging.FormatLogfmt),
+		LogMaxSizeMB:   DefaultLogMaxSizeMB,
+		LogMaxBackups:  DefaultLogMaxBackups,
+	}
+}
+
+// Errors are all the problems found with a config, so they can be fixed at once instead of one restart at a time
+type Errors []string
+
+func (errs Errors) Error() string {
+	return "invalid config:\n  " + strings.Join(errs, "\n  ")
+}
+
+// Load reads the config file at path (if there is one), then the environment on top of it, and validates the result.
+// The config is returned even if it's invalid, so it can still be printed
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	var errs Errors
+	if path != "" {
+		md, err := toml.DecodeFile(path, cfg)
+		if err != nil {
+			return cfg, fmt.Errorf("error reading the config file %s: %w", path, err)
+		}
+		for _, key := range md.Undecoded() {
+			errs = append(errs, fmt.Sprintf("unknown key %s in the config file", key))
+		}
+	}
+
+	errs = append(errs, cfg.loadEnv(os.LookupEnv)...)
+	errs = append(errs, cfg.Validate()...)
+	if len(errs) > 0 {
+		return cfg, errs
+	}
+	return cfg, nil
+}
+
+// loadEnv overrides the fields of the config that have their env var set. Empty env vars are ignored, as if unset
+func (cfg *Config) loadEnv(lookup func(string) (string, bool)) Errors {
+	var errs Errors
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("env")
+		str, ok := lookup(name)
+		if !ok || str == "" {
+			continue
+		}
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(str)
+		case reflect.Int:
+			num, err := strconv.Atoi(strings.TrimSpace(str))
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s must be a number, not %q", name, str))
+				continue
+			}
+			field.SetInt(int64(num))
+		case reflect.Bool:
+			b, err := strconv.ParseBool(strings.TrimSpace(str))
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s must be true or false, not %q", name, str))
+				continue
+			}
+			field.SetBool(b)
+		case reflect.Slice:
+			var values []string
+			for _, value := range strings.Split(strings.ReplaceAll(str, " ", ""), ",") {
+				if value != "" {
+					values = append(values, value)
+				}
+			}
+			field.Set(reflect.ValueOf(values))
+		}
+	}
+	return errs
+}
+
+// Validate checks the whole config, and returns every problem with it
+func (cfg *Config) Validate() Errors {
+	var errs Errors
+	required := []struct{ name, value string }{
+		{"DISCORD_BOT_TOKEN", cfg.DiscordBotToken},
+		{"REDIS_ADDR", cfg.RedisAddr},
+		{"POSTGRES_ADDR", cfg.PostgresAddr},
+		{"POSTGRES_USER", cfg.PostgresUser},
+		{"POSTGRES_PASS", cfg.PostgresPass},
+	}
+	for _, req := range required {
+		if req.value == "" {
+			errs = append(errs, fmt.Sprintf("no %s provided", req.name))
+		}
+	}
+
+	switch cfg.VoiceBackend {
+	case VoiceBackendGalactus:
+		if cfg.GalactusAddr == "" {
+			errs = append(errs, "no GALACTUS_ADDR provided")
+		}
+	case VoiceBackendDiscord:
+	default:
+		errs = append(errs, fmt.Sprintf("VOICE_BACKEND must be %s or %s", VoiceBackendGalactus, VoiceBackendDiscord))
+	}
+
+	if cfg.NumShards < 1 {
+		errs = append(errs, "NUM_SHARDS must be at least 1")
+	}
+	if cfg.ShardID < 0 || cfg.ShardID >= cfg.NumShards {
+		errs = append(errs, fmt.Sprintf("SHARD_ID must be between 0 and NUM_SHARDS-1 (%d)", cfg.NumShards-1))
+	}
+	if cfg.MaxActiveGames < 1 {
+		errs = append(errs, "MAX_ACTIVE_GAMES must be at least 1")
+	}
+	if _, err := url.Parse(cfg.Host); err != nil || cfg.Host == "" {
+		errs = append(errs, "HOST must be a valid URL")
+	}
+	if _, err := url.Parse(cfg.BaseMapURL); err != nil || cfg.BaseMapURL == "" {
+		errs = append(errs, "BASE_MAP_URL must be a valid URL")
+	}
+	if cfg.SettingsAPIPort != "" {
+		if port, err := strconv.Atoi(cfg.SettingsAPIPort); err != nil || port < 1 || port > 65535 {
+			errs = append(errs, "SETTINGS_API_PORT must be a port number")
+		}
+	}
+
+	if _, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		errs = append(errs, "LOG_LEVEL: "+err.Error())
+	}
+	if _, err := logging.ParseFormat(cfg.LogFormat); err != nil {
+		errs = append(errs, "LOG_FORMAT: "+err.Error())
+	}
+	if cfg.LogMaxSizeMB < 1 {
+		errs = append(errs, "LOG_MAX_SIZE_MB must be at least 1")
+	}
+	if cfg.LogMaxBackups < 0 {
+		errs = append(errs, "LOG_MAX_BACKUPS can't be negative")
+	}
+	return errs
+}
+
+// AllWorkerTokens are the worker tokens, plus the deprecated second bot token
+func (cfg *Config) AllWorkerTokens() []string {
+	tokens := append([]string{}, cfg.WorkerBotTokens...)
+	if cfg.DiscordBotToken2 != "" {
+		tokens = append(tokens, cfg.DiscordBotToken2)
+	}
+	return tokens
+}
+
+// Redacted is a copy of the config with every secret replaced, so it's safe to print or log
+func (cfg *Config) Redacted() *Config {
+	cpy := *cfg
+	v := reflect.ValueOf(&cpy).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("secret") != "true" {
+			continue
+		}
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			if field.String() != "" {
+				field.SetString(redacted)
+			}
+		case reflect.Slice:
+			values := make([]string, field.Len())
+			for j := range values {
+				values[j] = redacted
+			}
+			field.Set(reflect.ValueOf(values))
+		}
+	}
+	return &cpy
+}
+
+// Print writes the config, with its secrets redacted, in the format of the config file
+func (cfg *Config) Print(w io.Writer) error {
+	return toml.NewEncoder(w).Encode(cfg.Redacted())
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func validConfig() *Config {
+	cfg := Default()
+	cfg.DiscordBotToken = "token"
+	cfg.RedisAddr = "localhost:6379"
+	cfg.GalactusAddr = "http://localhost:5858"
+	cfg.PostgresAddr = "localhost:5432"
+	cfg.PostgresUser = "postgres"
+	cfg.PostgresPass = "pass"
+	return cfg
+}
+
+func TestLoadEnv(t *testing.T) {
+	env := map[string]string{
+		"NUM_SHARDS":          "4",
+		"SHARD_ID":            "2",
+		"WORKER_BOT_TOKENS":   "a, b,,c",
+		"AUTOMUTEUS_OFFICIAL": "true",
+		"HOST":                "",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	cfg := validConfig()
+	errs := cfg.loadEnv(lookup)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if cfg.NumShards != 4 || cfg.ShardID != 2 || !cfg.Official {
+		t.Error("env vars weren't loaded")
+	}
+	if strings.Join(cfg.WorkerBotTokens, ",") != "a,b,c" {
+		t.Error("worker tokens weren't split", cfg.WorkerBotTokens)
+	}
+	if cfg.Host != DefaultURL {
+		t.Error("an empty env var shouldn't override the default")
+	}
+
+	env = map[string]string{
+		"NUM_SHARDS":       "many",
+		"DISABLE_LOG_FILE": "sure",
+	}
+	errs = cfg.loadEnv(lookup)
+	if len(errs) != 2 {
+		t.Error("all the invalid env vars should be reported", errs)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := validConfig()
+	if errs := cfg.Validate(); len(errs) > 0 {
+		t.Error(errs)
+	}
+
+	cfg = Default()
+	cfg.ShardID = 1
+	cfg.LogLevel = "verbose"
+	errs := cfg.Validate()
+	// the 6 required values, plus the shard ID and log level
+	if len(errs) != 8 {
+		t.Error("all the problems should be reported", errs)
+	}
+
+	cfg = validConfig()
+	cfg.VoiceBackend = VoiceBackendDiscord
+	cfg.GalactusAddr = ""
+	if errs := cfg.Validate(); len(errs) > 0 {
+		t.Error("Galactus isn't needed to mute through Discord directly", errs)
+	}
+	cfg.VoiceBackend = "carrier pigeon"
+	if errs := cfg.Validate(); len(errs) != 1 {
+		t.Error("an unknown voice backend should be reported", errs)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := path.Join(dir, "config.toml")
+	err = ioutil.WriteFile(file, []byte("num_shards = 0\nunknown_key = 1\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Load(file)
+	if err == nil || !strings.Contains(err.Error(), "unknown_key") {
+		t.Error("unknown keys should be reported", err)
+	}
+	// along with every other problem, not instead of them
+	if err == nil || !strings.Contains(err.Error(), "NUM_SHARDS must be at least 1") {
+		t.Error("invalid settings should be reported with the unknown keys", err)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	cfg := validConfig()
+	cfg.WorkerBotTokens = []string{"a", "b"}
+
+	buf := bytes.NewBuffer(nil)
+	err := cfg.Print(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Contains(out, `"token"`) || strings.Contains(out, `"pass"`) || strings.Contains(out, `"a"`) {
+		t.Error("secrets should be redacted", out)
+	}
+	if !strings.Contains(out, `redis_addr = "localhost:6379"`) {
+		t.Error("the config should be printed", out)
+	}
+	if cfg.DiscordBotToken != "token" {
+		t.Error("redacting shouldn't change the config")
+	}
+}
@@ -3,17 +3,17 @@ package discord
 import (
 	"context"
 	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/config"
+	"github.com/automuteus/automuteus/logging"
 	"github.com/automuteus/automuteus/metrics"
 	"github.com/automuteus/automuteus/storage"
 	"github.com/automuteus/utils/pkg/discord"
 	"github.com/automuteus/utils/pkg/game"
 	"github.com/automuteus/utils/pkg/rediskey"
-	"github.com/automuteus/utils/pkg/settings"
 	storageutils "github.com/automuteus/utils/pkg/storage"
 	"github.com/automuteus/utils/pkg/token"
 	"github.com/bwmarrin/discordgo"
 	"log"
-	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -32,72 +32,104 @@ type Bot struct {
 
 	ChannelsMapLock sync.RWMutex
 
+	// closing is set (under ChannelsMapLock) once the bot stops accepting new games, to hand off the running ones
+	closing bool
+
+	// subscriptions tracks the running game subscriptions, so closing can wait for them to be handed off
+	subscriptions sync.WaitGroup
+
+	// subscribedGames are the games this instance is subscribed to, by connect code (under ChannelsMapLock)
+	subscribedGames map[string]GameStateRequest
+
+	// nodeID identifies this instance as the owner of the game subscriptions it runs
+	nodeID string
+
 	PrimarySession *discordgo.Session
 
-	GalactusClient *GalactusClient
+	// VoiceModifier mutes/deafens the players
+	VoiceModifier VoiceModifier
 
 	RedisInterface *RedisInterface
 
+	GameStateStore GameStateStore
+
 	StorageInterface *storage.StorageInterface
 
 	PostgresInterface *storageutils.PsqlInterface
 
-	logPath string
+	ScheduleInterface *storage.ScheduleInterface
+	IdentityInterface *storage.IdentityInterface
+
+	logger *logging.Logger
+
+	config *config.Config
 
 	captureTimeout int
 }
 
 // MakeAndStartBot does what it sounds like
-// TODO collapse these fields into proper structs?
-func MakeAndStartBot(version, commit, botToken, url, emojiGuildID string, extraTokens []string, numShards, shardID int, redisInterface *RedisInterface, storageInterface *storage.StorageInterface, psql *storageutils.PsqlInterface, gc *GalactusClient, logPath string) *Bot {
-	dg, err := discordgo.New("Bot " + botToken)
+func MakeAndStartBot(version, commit string, cfg *config.Config, redisInterface *RedisInterface, gameStateStore GameStateStore, storageInterface *storage.StorageInterface, psql *storageutils.PsqlInterface, gc *GalactusClient, logger *logging.Logger) *Bot {
+	dg, err := discordgo.New("Bot " + cfg.DiscordBotToken)
 	if err != nil {
 		log.Println("error creating Discord session,", err)
 		return nil
 	}
 
-	for _, v := range extraTokens {
-		err := gc.AddToken(v)
+	var voiceModifier VoiceModifier = gc
+	if cfg.VoiceBackend == config.VoiceBackendDiscord {
+		voiceModifier = NewDiscordVoiceModifier(dg, logger)
+	}
+	for _, v := range cfg.AllWorkerTokens() {
+		err := voiceModifier.AddToken(v)
 		if err != nil {
-			log.Println("error adding extra bot token to galactus:", err)
+			log.Println("error adding extra bot token:", err)
 		}
 	}
 
-	if numShards > 1 {
-		log.Printf("Identifying to the Discord API with %d total shards, and shard ID=%d\n", numShards, shardID)
-		dg.ShardCount = numShards
-		dg.ShardID = shardID
+	if cfg.NumShards > 1 {
+		log.Printf("Identifying to the Discord API with %d total shards, and shard ID=%d\n", cfg.NumShards, cfg.ShardID)
+		dg.ShardCount = cfg.NumShards
+		dg.ShardID = cfg.ShardID
 	}
 
 	bot := Bot{
-		url:          url,
+		url:          cfg.Host,
 		ConnsToGames: make(map[string]string),
 		StatusEmojis: emptyStatusEmojis(),
 
 		EndGameChannels:   make(map[string]chan EndGameMessage),
 		ChannelsMapLock:   sync.RWMutex{},
+		subscribedGames:   make(map[string]GameStateRequest),
 		PrimarySession:    dg,
-		GalactusClient:    gc,
+		VoiceModifier:     voiceModifier,
 		RedisInterface:    redisInterface,
+		GameStateStore:    gameStateStore,
 		StorageInterface:  storageInterface,
 		PostgresInterface: psql,
-		logPath:           logPath,
+		ScheduleInterface: storage.NewScheduleInterface(psql),
+		IdentityInterface: storage.NewIdentityInterface(psql),
+		logger:            logger,
+		config:            cfg,
 		captureTimeout:    GameTimeoutSeconds,
+		nodeID:            instanceID(),
 	}
 	dg.LogLevel = discordgo.LogInformational
 
+	voiceModifier.OnDegradedChange(bot.galactusDegradedChanged)
+
 	dg.AddHandler(bot.handleVoiceStateChange)
 	// Register the messageCreate func as a callback for MessageCreate events.
 	dg.AddHandler(bot.handleMessageCreate)
 	dg.AddHandler(bot.handleReactionGameStartAdd)
-	dg.AddHandler(bot.newGuild(emojiGuildID))
+	dg.AddHandler(bot.handleInteractionCreate)
+	dg.AddHandler(bot.newGuild(cfg.EmojiGuildID))
 	dg.AddHandler(bot.leaveGuild)
 	dg.AddHandler(bot.rateLimitEventCallback)
 
 	dg.Identify.Intents = discordgo.MakeIntent(discordgo.IntentsGuildVoiceStates | discordgo.IntentsGuildMessages | discordgo.IntentsGuilds | discordgo.IntentsGuildMessageReactions)
 
-	token.WaitForToken(bot.RedisInterface.client, botToken)
-	token.LockForToken(bot.RedisInterface.client, botToken)
+	token.WaitForToken(bot.RedisInterface.client, cfg.DiscordBotToken)
+	token.LockForToken(bot.RedisInterface.client, cfg.DiscordBotToken)
 	// Open a websocket connection to Discord and begin listening.
 	err = dg.Open()
 	if err != nil {
@@ -105,21 +137,29 @@ func MakeAndStartBot(version, commit, botToken, url, emojiGuildID string, extraT
 		return nil
 	}
 
+	// application commands are global, so only one shard needs to register them
+	if cfg.ShardID == 0 {
+		go bot.registerApplicationCommands(dg)
+	}
+
 	rediskey.SetVersionAndCommit(context.Background(), bot.RedisInterface.client, version, commit)
 
-	nodeID := os.Getenv("SCW_NODE_ID")
-	go metrics.PrometheusMetricsServer(bot.RedisInterface.client, nodeID, "2112")
+	go metrics.PrometheusMetricsServer(bot.RedisInterface.client, cfg.NodeID, "2112")
+
+	go metrics.StartHealthCheckServer("8080", bot.healthChecks())
 
-	go metrics.StartHealthCheckServer("8080")
+	if cfg.SettingsAPIPort != "" {
+		go bot.StartSettingsAPIServer(cfg.SettingsAPIPort)
+	}
 
 	log.Println("Finished identifying to the Discord API. Now ready for incoming events")
 
-	listeningTo := os.Getenv("AUTOMUTEUS_LISTENING")
+	listeningTo := cfg.Listening
 	if listeningTo == "" {
-		prefix := os.Getenv("AUTOMUTEUS_GLOBAL_PREFIX")
-		if prefix == "" && os.Getenv("AUTOMUTEUS_OFFICIAL") == "" {
+		prefix := cfg.GlobalPrefix
+		if prefix == "" && !cfg.Official {
 			prefix = ".au"
-		} else if os.Getenv("AUTOMUTEUS_OFFICIAL") != "" {
+		} else if cfg.Official {
 			prefix = "@AutoMuteUs"
 		}
 
@@ -146,6 +186,10 @@ func MakeAndStartBot(version, commit, botToken, url, emojiGuildID string, extraT
 	// TODO this is ugly. Should make a proper cronjob to refresh the stats regularly
 	go bot.statsRefreshWorker(rediskey.TotalUsersExpiration)
 
+	go bot.orphanedGamesWorker(OrphanedGamesInterval)
+
+	go bot.scheduledGamesWorker(ScheduleInterval)
+
 	return &bot
 }
 
@@ -202,7 +246,7 @@ func (bot *Bot) newGuild(emojiGuildID string) func(s *discordgo.Session, m *disc
 				bot.addAllMissingEmojis(s, m.Guild.ID, false, allEmojis)
 
 				// if we specified the guild ID, then any subsequent guilds should just use the existing emojis
-				if os.Getenv("EMOJI_GUILD_ID") != "" {
+				if bot.config.EmojiGuildID != "" {
 					AllEmojisStartup = allEmojis
 					log.Println("Skipping subsequent guilds; emojis added successfully")
 				}
@@ -214,30 +258,13 @@ func (bot *Bot) newGuild(emojiGuildID string) func(s *discordgo.Session, m *disc
 		}
 		EmojiLock.Unlock()
 
-		games := bot.RedisInterface.LoadAllActiveGames(m.Guild.ID)
+		games := bot.GameStateStore.LoadAllActiveGames(m.Guild.ID)
 
 		for _, connCode := range games {
-			gsr := GameStateRequest{
+			bot.resumeGame(GameStateRequest{
 				GuildID:     m.Guild.ID,
 				ConnectCode: connCode,
-			}
-			lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
-			for lock == nil {
-				lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
-			}
-			if dgs != nil && dgs.ConnectCode != "" {
-				log.Println("Resubscribing to Redis events for an old game: " + connCode)
-				killChan := make(chan EndGameMessage)
-				go bot.SubscribeToGameByConnectCode(gsr.GuildID, dgs.ConnectCode, killChan)
-				dgs.Subscribed = true
-
-				bot.RedisInterface.SetDiscordGameState(dgs, lock)
-
-				bot.ChannelsMapLock.Lock()
-				bot.EndGameChannels[dgs.ConnectCode] = killChan
-				bot.ChannelsMapLock.Unlock()
-			}
-			lock.Release(ctx)
+			}, false)
 		}
 	}
 }
@@ -250,6 +277,10 @@ func (bot *Bot) leaveGuild(_ *discordgo.Session, m *discordgo.GuildDelete) {
 	if err != nil {
 		log.Println(err)
 	}
+	err = bot.StorageInterface.DeleteSettingsHistory(m.ID)
+	if err != nil {
+		log.Println(err)
+	}
 }
 
 func (bot *Bot) linkPlayer(g *discordgo.Guild, dgs *GameState, args []string) {
@@ -275,10 +306,11 @@ func (bot *Bot) linkPlayer(g *discordgo.Guild, dgs *GameState, args []string) {
 		foundID := dgs.AttemptPairingByUserIDs(auData, map[string]interface{}{userID: ""})
 		if foundID != "" {
 			log.Printf("Successfully linked %s to a color\n", userID)
-			err := bot.RedisInterface.AddUsernameLink(dgs.GuildID, userID, auData.Name)
+			err := bot.GameStateStore.AddUsernameLink(dgs.GuildID, userID, auData.Name)
 			if err != nil {
 				log.Println(err)
 			}
+			go bot.linkIdentity(dgs.GuildID, userID, auData.Name, storage.LinkManual)
 		} else {
 			log.Printf("No player was found with id %s\n", userID)
 		}
@@ -287,21 +319,24 @@ func (bot *Bot) linkPlayer(g *discordgo.Guild, dgs *GameState, args []string) {
 
 func (bot *Bot) forceEndGame(gsr GameStateRequest) {
 	// lock because we don't want anyone else modifying while we delete
-	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
 
 	for lock == nil {
-		lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+		lock, dgs = bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
 	}
 
 	dgs.DeleteGameStateMsg(bot.PrimarySession)
 	metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageCreateDelete, 1)
 
-	bot.RedisInterface.SetDiscordGameState(dgs, lock)
+	bot.GameStateStore.SetDiscordGameState(dgs, lock)
 
-	bot.RedisInterface.RemoveOldGame(dgs.GuildID, dgs.ConnectCode)
+	// don't leave the dead players stranded in the ghost channel
+	bot.moveGhostsBack(bot.gameLogger(dgs), dgs, bot.StorageInterface.GetGuildSettings(dgs.GuildID))
+
+	bot.GameStateStore.RemoveOldGame(dgs.GuildID, dgs.ConnectCode)
 
 	// Note, this shouldn't be necessary with the TTL of the keys, but it can't hurt to clean up...
-	bot.RedisInterface.DeleteDiscordGameState(dgs)
+	bot.GameStateStore.DeleteDiscordGameState(dgs)
 }
 
 func MessageDeleteWorker(s *discordgo.Session, msgChannelID, msgID string, waitDur time.Duration) {
@@ -310,10 +345,10 @@ func MessageDeleteWorker(s *discordgo.Session, msgChannelID, msgID string, waitD
 	deleteMessage(s, msgChannelID, msgID)
 }
 
-func (bot *Bot) RefreshGameStateMessage(gsr GameStateRequest, sett *settings.GuildSettings) {
-	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+func (bot *Bot) RefreshGameStateMessage(gsr GameStateRequest, sett *storage.GuildSettings) {
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
 	for lock == nil {
-		lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+		lock, dgs = bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
 	}
 	// log.Println("Refreshing game state message")
 
@@ -322,15 +357,9 @@ func (bot *Bot) RefreshGameStateMessage(gsr GameStateRequest, sett *settings.Gui
 
 	if dgs.GameStateMsg.MessageChannelID != "" {
 		dgs.DeleteGameStateMsg(bot.PrimarySession) // delete the old message
-		dgs.CreateMessage(bot.PrimarySession, bot.gameStateResponse(dgs, sett), dgs.GameStateMsg.MessageChannelID, dgs.GameStateMsg.LeaderID)
+		dgs.CreateMessage(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett), dgs.GameStateMsg.MessageChannelID, dgs.GameStateMsg.LeaderID)
 		metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageCreateDelete, 2)
 	}
 
-	bot.RedisInterface.SetDiscordGameState(dgs, lock)
-
-	// add the emojis to the refreshed message
-	if dgs.GameStateMsg.MessageChannelID != "" && dgs.GameStateMsg.MessageID != "" {
-		metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.ReactionAdd, 1)
-		dgs.AddReaction(bot.PrimarySession, "▶️")
-	}
+	bot.GameStateStore.SetDiscordGameState(dgs, lock)
 }
@@ -0,0 +1,83 @@
+package discord
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// GalactusFailureThreshold is how many requests to Galactus in a row can fail before the circuit breaker opens
+	GalactusFailureThreshold = 5
+	// GalactusBreakerCooldown is how long the breaker stays open before letting a request through to try again
+	GalactusBreakerCooldown = 15 * time.Second
+)
+
+// circuitBreaker stops sending requests to a service that keeps failing, so every mute doesn't wait on retries that
+// are bound to fail. Once the cooldown is over, a single request is let through; if it succeeds, the breaker closes
+type circuitBreaker struct {
+	lock      sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	open      bool
+	retryAt   time.Time
+	// onChange is called (in its own goroutine) whenever the breaker opens or closes
+	onChange func(open bool)
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// allow reports whether a request can be sent
+func (cb *circuitBreaker) allow() bool {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	if !cb.open {
+		return true
+	}
+	if time.Now().Before(cb.retryAt) {
+		return false
+	}
+	// let this request through to try again, but not the ones after it until it's done
+	cb.retryAt = time.Now().Add(cb.cooldown)
+	return true
+}
+
+func (cb *circuitBreaker) success() {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	cb.failures = 0
+	if cb.open {
+		cb.open = false
+		cb.changed(false)
+	}
+}
+
+func (cb *circuitBreaker) failure() {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	cb.failures++
+	if cb.open {
+		cb.retryAt = time.Now().Add(cb.cooldown)
+	} else if cb.failures >= cb.threshold {
+		cb.open = true
+		cb.retryAt = time.Now().Add(cb.cooldown)
+		cb.changed(true)
+	}
+}
+
+func (cb *circuitBreaker) isOpen() bool {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	return cb.open
+}
+
+func (cb *circuitBreaker) changed(open bool) {
+	if cb.onChange != nil {
+		go cb.onChange(open)
+	}
+}
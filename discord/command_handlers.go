@@ -3,7 +3,7 @@ package discord
 import (
 	"fmt"
 	"github.com/automuteus/automuteus/metrics"
-	"github.com/automuteus/utils/pkg/settings"
+	"github.com/automuteus/automuteus/storage"
 	"log"
 	"regexp"
 	"strings"
@@ -24,7 +24,7 @@ var MatchIDRegex = regexp.MustCompile(`^[A-Z0-9]{8}:[0-9]+$`)
 // TODO cache/preconstruct these (no reason to make them fresh everytime help is called, except for the prefix...)
 func ConstructEmbedForCommand(
 	cmd Command,
-	sett *settings.GuildSettings,
+	sett *storage.GuildSettings,
 ) *discordgo.MessageEmbed {
 	return &discordgo.MessageEmbed{
 		URL:   "",
@@ -70,10 +70,28 @@ func ConstructEmbedForCommand(
 	}
 }
 
+func (cmd *Command) isPermitted(isAdmin, isPermissioned bool) bool {
+	if cmd.IsAdmin && !isAdmin {
+		return false
+	}
+	// admins can invoke moderator commands
+	if cmd.IsOperator && (!isPermissioned && !isAdmin) {
+		return false
+	}
+	return true
+}
+
+func noPermsResponse(sett *storage.GuildSettings) string {
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "message_handlers.handleMessageCreate.noPerms",
+		Other: "User does not have the required permissions to execute this command!",
+	})
+}
+
 func (bot *Bot) HandleCommand(
 	isAdmin bool,
 	isPermissioned bool,
-	sett *settings.GuildSettings,
+	sett *storage.GuildSettings,
 	session *discordgo.Session,
 	guild *discordgo.Guild,
 	message *discordgo.MessageCreate,
@@ -102,20 +120,8 @@ func (bot *Bot) HandleCommand(
 		return false
 	}
 
-	if command.IsAdmin && !isAdmin {
-		session.ChannelMessageSend(message.ChannelID, sett.LocalizeMessage(&i18n.Message{
-			ID:    "message_handlers.handleMessageCreate.noPerms",
-			Other: "User does not have the required permissions to execute this command!",
-		}))
-		return false
-	}
-
-	// admins can invoke moderator commands
-	if command.IsOperator && (!isPermissioned && !isAdmin) {
-		session.ChannelMessageSend(message.ChannelID, sett.LocalizeMessage(&i18n.Message{
-			ID:    "message_handlers.handleMessageCreate.noPerms",
-			Other: "User does not have the required permissions to execute this command!",
-		}))
+	if !command.isPermitted(isAdmin, isPermissioned) {
+		session.ChannelMessageSend(message.ChannelID, noPermsResponse(sett))
 		return false
 	}
 
@@ -137,6 +143,9 @@ func (bot *Bot) HandleCommand(
 	case *discordgo.MessageEmbed:
 		session.ChannelMessageSendEmbed(channelID, msgToSend.(*discordgo.MessageEmbed))
 		msgsSent = 1
+	case *discordgo.MessageSend:
+		session.ChannelMessageSendComplex(channelID, msgToSend.(*discordgo.MessageSend))
+		msgsSent = 1
 	case nil:
 		// do nothing
 	default:
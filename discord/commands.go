@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/discord/setting"
+	"github.com/automuteus/automuteus/logging"
+	"github.com/automuteus/automuteus/metrics"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/automuteus/utils/pkg/discord"
-	"github.com/automuteus/utils/pkg/settings"
 	"log"
 	"strconv"
 	"strings"
@@ -37,6 +40,12 @@ const (
 	CommandEnumASCII
 	CommandEnumStats
 	CommandEnumWorkerBOT
+	CommandEnumOverride
+	CommandEnumTrack
+	CommandEnumMode
+	CommandEnumGame
+	CommandEnumSchedule
+	CommandEnumWhois
 )
 
 const NoLock string = "Could not obtain lock"
@@ -54,11 +63,16 @@ type Command struct {
 	IsAdmin     bool
 	IsOperator  bool
 
+	// Options are the typed arguments when invoked as an application command; they're
+	// converted back into args, in order, before calling fn
+	Options     []*discordgo.ApplicationCommandOption
+	IsEphemeral bool
+
 	fn func(
 		bot *Bot,
 		isAdmin bool,
 		isPermissioned bool,
-		sett *settings.GuildSettings,
+		sett *storage.GuildSettings,
 		guild *discordgo.Guild,
 		message *discordgo.MessageCreate,
 		args []string,
@@ -99,6 +113,13 @@ func init() {
 			Emoji:      "❓",
 			IsAdmin:    false,
 			IsOperator: false,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "command",
+					Description: "Command to see info for",
+				},
+			},
 
 			fn: commandFnHelp,
 		},
@@ -112,17 +133,24 @@ func init() {
 			},
 			Description: &i18n.Message{
 				ID:    "commands.AllCommands.New.desc",
-				Other: "Start a new game",
+				Other: "Start a new game. `--mode=<preset>` starts it with the voice rules and delays of a voice preset (see `{{.CommandPrefix}} mode`)",
 			},
 			Arguments: &i18n.Message{
 				ID:    "commands.AllCommands.New.args",
-				Other: "None",
+				Other: "--mode=<preset> (optional)",
 			},
 			Aliases:    []string{"start", "n"},
 			IsSecret:   false,
 			Emoji:      "🕹",
 			IsAdmin:    false,
 			IsOperator: true,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "mode",
+					Description: "Voice preset of the game (like classic, ghosts-talk, hide-and-seek, streamer-safe or casual)",
+				},
+			},
 
 			fn: commandFnNew,
 		},
@@ -219,6 +247,20 @@ func init() {
 			Emoji:      "🔗",
 			IsAdmin:    false,
 			IsOperator: true,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "Discord User to link",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "player",
+					Description: "In-game color or name",
+					Required:    true,
+				},
+			},
 
 			fn: commandFnLink,
 		},
@@ -243,9 +285,92 @@ func init() {
 			Emoji:      "🚷",
 			IsAdmin:    false,
 			IsOperator: true,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "Discord User to unlink",
+					Required:    true,
+				},
+			},
 
 			fn: commandFnUnlink,
 		},
+		{
+			CommandType: CommandEnumTrack,
+			Command:     "track",
+			Example:     "track #among-us #dead ghosts #overflow spectators",
+			ShortDesc: &i18n.Message{
+				ID:    "commands.AllCommands.Track.shortDesc",
+				Other: "Track voice channels",
+			},
+			Description: &i18n.Message{
+				ID:    "commands.AllCommands.Track.desc",
+				Other: "Track one or more voice channels for the current game, each optionally followed by its role: `players` (the default), `ghosts` (everyone is treated as dead) or `spectators` (everyone is treated as a spectator)",
+			},
+			Arguments: &i18n.Message{
+				ID:    "commands.AllCommands.Track.args",
+				Other: "<voice channel> [players/ghosts/spectators] ...",
+			},
+			Aliases:    []string{"tr"},
+			IsSecret:   false,
+			Emoji:      "📡",
+			IsAdmin:    false,
+			IsOperator: true,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					Name:         "channel1",
+					Description:  "Voice channel to track",
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice},
+					Required:     true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "role1",
+					Description: "Role of the voice channel (players by default)",
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "players", Value: "players"},
+						{Name: "ghosts", Value: "ghosts"},
+						{Name: "spectators", Value: "spectators"},
+					},
+				},
+				{
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					Name:         "channel2",
+					Description:  "Voice channel to track",
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "role2",
+					Description: "Role of the voice channel (players by default)",
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "players", Value: "players"},
+						{Name: "ghosts", Value: "ghosts"},
+						{Name: "spectators", Value: "spectators"},
+					},
+				},
+				{
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					Name:         "channel3",
+					Description:  "Voice channel to track",
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "role3",
+					Description: "Role of the voice channel (players by default)",
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "players", Value: "players"},
+						{Name: "ghosts", Value: "ghosts"},
+						{Name: "spectators", Value: "spectators"},
+					},
+				},
+			},
+
+			fn: commandFnTrack,
+		},
 		{
 			CommandType: CommandEnumUnmuteAll,
 			Command:     "unmuteall",
@@ -291,12 +416,25 @@ func init() {
 			Emoji:      "📢",
 			IsAdmin:    false,
 			IsOperator: true,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "phase",
+					Description: "Game stage to transition to",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "task", Value: "task"},
+						{Name: "discuss", Value: "discuss"},
+						{Name: "lobby", Value: "lobby"},
+					},
+				},
+			},
 
 			fn: func(
 				bot *Bot,
 				isAdmin bool,
 				isPermissioned bool,
-				sett *settings.GuildSettings,
+				sett *storage.GuildSettings,
 				guild *discordgo.Guild,
 				message *discordgo.MessageCreate,
 				args []string,
@@ -325,6 +463,29 @@ func init() {
 			Emoji:      "🗺",
 			IsAdmin:    false,
 			IsOperator: false,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "name",
+					Description: "Map to display",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "skeld", Value: "skeld"},
+						{Name: "mira_hq", Value: "mira_hq"},
+						{Name: "polus", Value: "polus"},
+						{Name: "airship", Value: "airship"},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "version",
+					Description: "Map image version",
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "simple", Value: "simple"},
+						{Name: detailedMapString, Value: detailedMapString},
+					},
+				},
+			},
 
 			fn: commandFnMap,
 		},
@@ -349,6 +510,20 @@ func init() {
 			Emoji:      "📖",
 			IsAdmin:    false,
 			IsOperator: true,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "Discord User to view cached names for",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        clearArgumentString,
+					Description: "Clear the cached names",
+				},
+			},
+			IsEphemeral: true,
 
 			fn: commandFnCache,
 		},
@@ -373,6 +548,19 @@ func init() {
 			Emoji:      "🔍",
 			IsAdmin:    false,
 			IsOperator: false,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "action",
+					Description: "Privacy action",
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "showme", Value: "showme"},
+						{Name: "optin", Value: "optin"},
+						{Name: "optout", Value: "optout"},
+					},
+				},
+			},
+			IsEphemeral: true,
 
 			fn: commandFnPrivacy,
 		},
@@ -397,9 +585,224 @@ func init() {
 			Emoji:      "🛠",
 			IsAdmin:    true,
 			IsOperator: true,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "setting",
+					Description: "Setting to view or change",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "value",
+					Description: "New value(s) for the setting",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionAttachment,
+					Name:        "file",
+					Description: "Settings file to import",
+				},
+			},
 
 			fn: commandFnSettings,
 		},
+		{
+			CommandType: CommandEnumOverride,
+			Command:     "override",
+			Example:     "override @Soup exempt",
+			ShortDesc: &i18n.Message{
+				ID:    "commands.AllCommands.Override.shortDesc",
+				Other: "Override the voice rules for a user",
+			},
+			Description: &i18n.Message{
+				ID:    "commands.AllCommands.Override.desc",
+				Other: "Mark a user as `exempt` (never muted or deafened), `spectator` (always treated like a dead spectator) or `deafentasks` (always deafened during tasks). Shortcut for `{{.CommandPrefix}} settings voiceOverrides`",
+			},
+			Arguments: &i18n.Message{
+				ID:    "commands.AllCommands.Override.args",
+				Other: "<@discord user> <exempt/spectator/deafentasks/clear>",
+			},
+			Aliases:    []string{"voiceoverride", "ov"},
+			IsSecret:   false,
+			Emoji:      "🎙",
+			IsAdmin:    true,
+			IsOperator: true,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "User to override the voice rules for",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "override",
+					Description: "Voice override",
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "exempt", Value: "exempt"},
+						{Name: "spectator", Value: "spectator"},
+						{Name: "deafentasks", Value: "deafentasks"},
+						{Name: "clear", Value: "clear"},
+					},
+				},
+			},
+
+			fn: commandFnOverride,
+		},
+		{
+			CommandType: CommandEnumMode,
+			Command:     "mode",
+			Example:     "mode hide-and-seek",
+			ShortDesc: &i18n.Message{
+				ID:    "commands.AllCommands.Mode.shortDesc",
+				Other: "Switch the voice preset of the game",
+			},
+			Description: &i18n.Message{
+				ID:    "commands.AllCommands.Mode.desc",
+				Other: "Switch the voice rules and delays of the current game to a voice preset, without changing the settings of the server. `default` goes back to the server settings. Manage the presets with `{{.CommandPrefix}} settings voicePresets`",
+			},
+			Arguments: &i18n.Message{
+				ID:    "commands.AllCommands.Mode.args",
+				Other: "<preset name> or default",
+			},
+			Aliases:    []string{"preset", "md"},
+			IsSecret:   false,
+			Emoji:      "🎛",
+			IsAdmin:    false,
+			IsOperator: true,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "preset",
+					Description: "Voice preset (like classic, ghosts-talk, hide-and-seek, streamer-safe or casual), or default",
+				},
+			},
+
+			fn: commandFnMode,
+		},
+		{
+			CommandType: CommandEnumGame,
+			Command:     "game",
+			Example:     "game set delays lobby tasks 5",
+			ShortDesc: &i18n.Message{
+				ID:    "commands.AllCommands.Game.shortDesc",
+				Other: "Change the settings of the game",
+			},
+			Description: &i18n.Message{
+				ID:    "commands.AllCommands.Game.desc",
+				Other: "Change the voice rules, delays, `unmuteDeadDuringTasks`, `muteSpectators`, `matchSummary` or `matchSummaryChannel` settings for the current game only, like with `{{.CommandPrefix}} settings`. They're discarded when the game ends. `reset` goes back to the server settings",
+			},
+			Arguments: &i18n.Message{
+				ID:    "commands.AllCommands.Game.args",
+				Other: "show, set <setting> <value>, or reset [setting]",
+			},
+			Aliases:    []string{"g"},
+			IsSecret:   false,
+			Emoji:      "🎲",
+			IsAdmin:    false,
+			IsOperator: true,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "action",
+					Description: "Show, set or reset the settings of the game",
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "show", Value: "show"},
+						{Name: "set", Value: "set"},
+						{Name: "reset", Value: "reset"},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "setting",
+					Description: "Setting to change for the game",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "value",
+					Description: "New value(s) for the setting",
+				},
+			},
+
+			fn: commandFnGame,
+		},
+		{
+			CommandType: CommandEnumSchedule,
+			Command:     "schedule",
+			Example:     "schedule 2026-10-20 20:00 #among-us 30",
+			ShortDesc: &i18n.Message{
+				ID:    "commands.AllCommands.Schedule.shortDesc",
+				Other: "Schedule a game",
+			},
+			Description: &i18n.Message{
+				ID:    "commands.AllCommands.Schedule.desc",
+				Other: "Schedule a game to start on its own in this channel, tracking a voice channel (yours, by default). Everyone can RSVP to it, and gets pinged some minutes before it starts (15, by default). The time is a delay like `2h30m` or `7d`, a UTC date like `2026-10-20 20:00`, or a Discord timestamp. With no arguments, lists the scheduled games; `{{.CommandPrefix}} schedule cancel <ID>` cancels one",
+			},
+			Arguments: &i18n.Message{
+				ID:    "commands.AllCommands.Schedule.args",
+				Other: "<time> [#voice channel] [reminder minutes], or cancel <ID>",
+			},
+			Aliases:    []string{"sched", "sc"},
+			IsSecret:   false,
+			Emoji:      "📅",
+			IsAdmin:    false,
+			IsOperator: true,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "when",
+					Description: "When the game starts (like 2h30m, 2026-10-20 20:00 UTC or a Discord timestamp), or cancel <ID>",
+				},
+				{
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					Name:         "voice",
+					Description:  "Voice channel of the game",
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "reminder",
+					Description: "How many minutes before the game to ping the players that RSVP'd",
+				},
+			},
+
+			fn: commandFnSchedule,
+		},
+		{
+			CommandType: CommandEnumWhois,
+			Command:     "whois",
+			Example:     "whois @Soup",
+			ShortDesc: &i18n.Message{
+				ID:    "commands.AllCommands.Whois.shortDesc",
+				Other: "View linked in-game names",
+			},
+			Description: &i18n.Message{
+				ID:    "commands.AllCommands.Whois.desc",
+				Other: "View the in-game names linked to a user, or the users linked to an in-game name, with how they were linked (manually, by reaction or by matching names). Names claimed by several users are flagged",
+			},
+			Arguments: &i18n.Message{
+				ID:    "commands.AllCommands.Whois.args",
+				Other: "<@user or in-game name>",
+			},
+			Aliases:    []string{"who"},
+			IsSecret:   false,
+			Emoji:      "🔎",
+			IsAdmin:    false,
+			IsOperator: true,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "Discord User to view the linked in-game names of",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "name",
+					Description: "In-game name to view the linked users of",
+				},
+			},
+			IsEphemeral: true,
+
+			fn: commandFnWhois,
+		},
 		{
 			CommandType: CommandEnumWorkerBOT,
 			Command:     "workerbot",
@@ -445,6 +848,28 @@ func init() {
 			Emoji:      "📊",
 			IsAdmin:    false,
 			IsOperator: false,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "Discord User to view stats for",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "query",
+					Description: "\"guild\", or a Match ID",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "reset",
+					Description: "Reset the stats (Admin only)",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "confirm",
+					Description: "Confirm the reset",
+				},
+			},
 
 			fn: commandFnStats,
 		},
@@ -492,6 +917,27 @@ func init() {
 			IsSecret:   true,
 			IsAdmin:    false,
 			IsOperator: false,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "Discord User to print",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "imposter",
+					Description: "Is the User an imposter",
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: trueString, Value: trueString},
+						{Name: "false", Value: "false"},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "count",
+					Description: "Imposters remaining",
+				},
+			},
 
 			fn: commandFnASCII,
 		},
@@ -511,10 +957,11 @@ func init() {
 				ID:    "commands.AllCommands.DebugState.args",
 				Other: "None",
 			},
-			Aliases:    []string{"debug", "ds", "state"},
-			IsSecret:   true,
-			IsAdmin:    false,
-			IsOperator: true,
+			Aliases:     []string{"debug", "ds", "state"},
+			IsSecret:    true,
+			IsAdmin:     false,
+			IsOperator:  true,
+			IsEphemeral: true,
 
 			fn: commandFnDebugState,
 		},
@@ -546,7 +993,7 @@ func commandFnHelp(
 	_ *Bot,
 	isAdmin bool,
 	isPermissioned bool,
-	sett *settings.GuildSettings,
+	sett *storage.GuildSettings,
 	_ *discordgo.Guild,
 	message *discordgo.MessageCreate,
 	args []string,
@@ -573,20 +1020,45 @@ func commandFnNew(
 	bot *Bot,
 	_ bool,
 	_ bool,
-	sett *settings.GuildSettings,
+	sett *storage.GuildSettings,
 	guild *discordgo.Guild,
 	message *discordgo.MessageCreate,
-	_ []string,
+	args []string,
 	_ *Command,
 ) (string, interface{}) {
-	return bot.handleNewGameMessage(message, guild, sett)
+	mode := newGameMode(sett, args[1:])
+	if mode != "" {
+		if _, ok := sett.GetVoicePreset(mode); !ok {
+			return message.ChannelID, unknownModeResponse(sett, mode)
+		}
+	}
+	return bot.handleNewGameMessage(message, guild, sett, mode)
+}
+
+// newGameMode is the mode passed to `new` as `--mode=<preset>` or `--mode <preset>`. The slash command passes the
+// preset on its own, so a lone preset name works too
+func newGameMode(sett *storage.GuildSettings, args []string) string {
+	for i, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--mode="):
+			return strings.TrimPrefix(arg, "--mode=")
+		case arg == "--mode" && i+1 < len(args):
+			return args[i+1]
+		}
+	}
+	if len(args) == 1 {
+		if _, ok := sett.GetVoicePreset(args[0]); ok {
+			return args[0]
+		}
+	}
+	return ""
 }
 
 func commandFnEnd(
 	bot *Bot,
 	_ bool,
 	_ bool,
-	_ *settings.GuildSettings,
+	_ *storage.GuildSettings,
 	_ *discordgo.Guild,
 	message *discordgo.MessageCreate,
 	_ []string,
@@ -598,13 +1070,13 @@ func commandFnEnd(
 		GuildID:     message.GuildID,
 		TextChannel: message.ChannelID,
 	}
-	dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(gsr)
+	dgs := bot.GameStateStore.GetReadOnlyDiscordGameState(gsr)
 	if v, ok := bot.EndGameChannels[dgs.ConnectCode]; ok {
-		v <- true
+		v <- EndGame
 	}
 	delete(bot.EndGameChannels, dgs.ConnectCode)
 
-	bot.applyToAll(dgs, false, false)
+	bot.applyToAll(bot.gameLogger(dgs), dgs, false, false)
 	return message.ChannelID, nil
 }
 
@@ -612,7 +1084,7 @@ func commandFnPause(
 	bot *Bot,
 	_ bool,
 	_ bool,
-	sett *settings.GuildSettings,
+	sett *storage.GuildSettings,
 	_ *discordgo.Guild,
 	message *discordgo.MessageCreate,
 	_ []string,
@@ -622,19 +1094,19 @@ func commandFnPause(
 		GuildID:     message.GuildID,
 		TextChannel: message.ChannelID,
 	}
-	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
 	if lock == nil {
 		return message.ChannelID, NoLock
 	}
 	dgs.Running = !dgs.Running
 
-	bot.RedisInterface.SetDiscordGameState(dgs, lock)
+	bot.GameStateStore.SetDiscordGameState(dgs, lock)
 	if !dgs.Running {
-		bot.applyToAll(dgs, false, false)
+		bot.applyToAll(bot.gameLogger(dgs), dgs, false, false)
 	}
 
 	// TODO refactor to return the edit, not perform it
-	dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett))
+	dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
 
 	return "", nil
 }
@@ -643,7 +1115,7 @@ func commandFnRefresh(
 	bot *Bot,
 	_ bool,
 	_ bool,
-	sett *settings.GuildSettings,
+	sett *storage.GuildSettings,
 	_ *discordgo.Guild,
 	message *discordgo.MessageCreate,
 	_ []string,
@@ -663,7 +1135,7 @@ func commandFnLink(
 	bot *Bot,
 	_ bool,
 	_ bool,
-	sett *settings.GuildSettings,
+	sett *storage.GuildSettings,
 	guild *discordgo.Guild,
 	message *discordgo.MessageCreate,
 	args []string,
@@ -676,15 +1148,15 @@ func commandFnLink(
 			GuildID:     message.GuildID,
 			TextChannel: message.ChannelID,
 		}
-		lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+		lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
 		if lock == nil {
 			return message.ChannelID, NoLock
 		}
 		bot.linkPlayer(guild, dgs, args[1:])
-		bot.RedisInterface.SetDiscordGameState(dgs, lock)
+		bot.GameStateStore.SetDiscordGameState(dgs, lock)
 
 		// TODO refactor to return the edit, not perform it
-		dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett))
+		dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
 
 		return "", nil
 	}
@@ -694,7 +1166,7 @@ func commandFnUnlink(
 	bot *Bot,
 	_ bool,
 	_ bool,
-	sett *settings.GuildSettings,
+	sett *storage.GuildSettings,
 	_ *discordgo.Guild,
 	message *discordgo.MessageCreate,
 	args []string,
@@ -713,27 +1185,67 @@ func commandFnUnlink(
 				GuildID:     message.GuildID,
 				TextChannel: message.ChannelID,
 			}
-			lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+			lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
 			if lock == nil {
 				return message.ChannelID, NoLock
 			}
 			dgs.ClearPlayerData(userID)
 
-			bot.RedisInterface.SetDiscordGameState(dgs, lock)
+			bot.GameStateStore.SetDiscordGameState(dgs, lock)
 
 			// TODO refactor to return the edit, not perform it
-			dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett))
+			dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
 
 			return "", nil
 		}
 	}
 }
 
+func commandFnTrack(
+	bot *Bot,
+	_ bool,
+	_ bool,
+	sett *storage.GuildSettings,
+	_ *discordgo.Guild,
+	message *discordgo.MessageCreate,
+	args []string,
+	cmd *Command,
+) (string, interface{}) {
+	if len(args[1:]) == 0 {
+		return message.ChannelID, ConstructEmbedForCommand(*cmd, sett)
+	}
+	channels, err := bot.PrimarySession.GuildChannels(message.GuildID)
+	if err != nil {
+		log.Println(err)
+		return "", nil
+	}
+
+	gsr := GameStateRequest{
+		GuildID:     message.GuildID,
+		TextChannel: message.ChannelID,
+	}
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
+	if lock == nil {
+		return message.ChannelID, NoLock
+	}
+	msg := dgs.trackChannels(bot.gameLogger(dgs), args[1:], channels, sett)
+	bot.GameStateStore.SetDiscordGameState(dgs, lock)
+	sett = dgs.gameSettings(bot.gameLogger(dgs), sett)
+
+	// apply the roles of the channels to anyone already in them
+	bot.handleTrackedMembers(bot.gameLogger(dgs), bot.PrimarySession, sett, 0, NoPriority, gsr, metrics.NoTransition)
+
+	// TODO refactor to return the edit, not perform it
+	dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
+
+	return message.ChannelID, msg
+}
+
 func commandFnUnmuteAll(
 	bot *Bot,
 	_ bool,
 	_ bool,
-	_ *settings.GuildSettings,
+	_ *storage.GuildSettings,
 	_ *discordgo.Guild,
 	message *discordgo.MessageCreate,
 	_ []string,
@@ -743,8 +1255,8 @@ func commandFnUnmuteAll(
 		GuildID:     message.GuildID,
 		TextChannel: message.ChannelID,
 	}
-	dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(gsr)
-	bot.applyToAll(dgs, false, false)
+	dgs := bot.GameStateStore.GetReadOnlyDiscordGameState(gsr)
+	bot.applyToAll(bot.gameLogger(dgs), dgs, false, false)
 	return "", nil
 }
 
@@ -752,7 +1264,7 @@ func commandFnSettings(
 	bot *Bot,
 	_ bool,
 	_ bool,
-	sett *settings.GuildSettings,
+	sett *storage.GuildSettings,
 	_ *discordgo.Guild,
 	message *discordgo.MessageCreate,
 	args []string,
@@ -763,11 +1275,252 @@ func commandFnSettings(
 	return bot.HandleSettingsCommand(message, sett, args, isPrem)
 }
 
+func commandFnOverride(
+	bot *Bot,
+	_ bool,
+	_ bool,
+	sett *storage.GuildSettings,
+	_ *discordgo.Guild,
+	message *discordgo.MessageCreate,
+	args []string,
+	_ *Command,
+) (string, interface{}) {
+	// same as `settings voiceOverrides <args>`
+	settArgs := append([]string{args[0], strings.ToLower(setting.AllSettings[setting.VoiceOverrides].Name)}, args[1:]...)
+	before := copyGuildSettings(sett)
+	// voice overrides aren't premium
+	sendMsg, isValid, _ := applySetting(sett, setting.VoiceOverrides, settArgs, false)
+	if isValid {
+		err := bot.StorageInterface.SetGuildSettings(message.GuildID, sett)
+		if err != nil {
+			log.Println(err)
+		} else {
+			bot.recordSettingsChanges(message.GuildID, before, sett, settingsChanges(message.Author.ID, before, sett))
+		}
+	}
+	return message.ChannelID, sendMsg
+}
+
+func commandFnMode(
+	bot *Bot,
+	_ bool,
+	_ bool,
+	sett *storage.GuildSettings,
+	_ *discordgo.Guild,
+	message *discordgo.MessageCreate,
+	args []string,
+	_ *Command,
+) (string, interface{}) {
+	gsr := GameStateRequest{
+		GuildID:     message.GuildID,
+		TextChannel: message.ChannelID,
+	}
+	if len(args[1:]) == 0 {
+		dgs := bot.GameStateStore.GetReadOnlyDiscordGameState(gsr)
+		return message.ChannelID, modeResponse(dgs, sett)
+	}
+
+	mode := args[1]
+	if mode == "default" || mode == "clear" {
+		mode = ""
+	} else if _, ok := sett.GetVoicePreset(mode); !ok {
+		return message.ChannelID, unknownModeResponse(sett, args[1])
+	}
+
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
+	if lock == nil {
+		return message.ChannelID, NoLock
+	}
+	if dgs.ConnectCode == "" {
+		lock.Release(ctx)
+		return message.ChannelID, sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.commandFnMode.noGame",
+			Other: "There's no game running in this channel! Start one with `{{.CommandPrefix}} new` first",
+		},
+			map[string]interface{}{
+				"CommandPrefix": sett.GetCommandPrefix(),
+			})
+	}
+	dgs.Settings.Mode = mode
+	bot.GameStateStore.SetDiscordGameState(dgs, lock)
+
+	// apply the new rules to everyone right away
+	bot.handleTrackedMembers(bot.gameLogger(dgs), bot.PrimarySession, sett, 0, NoPriority, gsr, metrics.NoTransition)
+
+	// TODO refactor to return the edit, not perform it
+	dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
+
+	return message.ChannelID, modeResponse(dgs, sett)
+}
+
+func commandFnGame(
+	bot *Bot,
+	_ bool,
+	_ bool,
+	sett *storage.GuildSettings,
+	_ *discordgo.Guild,
+	message *discordgo.MessageCreate,
+	args []string,
+	cmd *Command,
+) (string, interface{}) {
+	gsr := GameStateRequest{
+		GuildID:     message.GuildID,
+		TextChannel: message.ChannelID,
+	}
+	action := "show"
+	if len(args[1:]) > 0 {
+		action = args[1]
+	}
+	switch action {
+	case "show", "sh":
+		dgs := bot.GameStateStore.GetReadOnlyDiscordGameState(gsr)
+		return message.ChannelID, gameSettingsResponse(bot.gameLogger(dgs), dgs, sett)
+	case "set", "s":
+		if len(args[2:]) == 0 {
+			return message.ChannelID, ConstructEmbedForCommand(*cmd, sett)
+		}
+	case "reset", "r":
+	default:
+		return message.ChannelID, ConstructEmbedForCommand(*cmd, sett)
+	}
+
+	// `game reset` on its own resets all of them
+	settType := setting.NullSetting
+	if len(args[2:]) > 0 {
+		settType = getSetting(args[2])
+		if !isGameSetting(settType) {
+			names := make([]string, len(GameSettingTypes))
+			for i, t := range GameSettingTypes {
+				names[i] = setting.AllSettings[t].Name
+			}
+			return message.ChannelID, sett.LocalizeMessage(&i18n.Message{
+				ID:    "commands.commandFnGame.notGameSetting",
+				Other: "Sorry, `{{.Setting}}` can't be changed for a single game. These can: `{{.Settings}}`",
+			},
+				map[string]interface{}{
+					"Setting":  args[2],
+					"Settings": strings.Join(names, "`, `"),
+				})
+		}
+	}
+
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
+	if lock == nil {
+		return message.ChannelID, NoLock
+	}
+	if dgs.ConnectCode == "" {
+		lock.Release(ctx)
+		return message.ChannelID, sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.commandFnGame.noGame",
+			Other: "There's no game running in this channel! Start one with `{{.CommandPrefix}} new` first",
+		},
+			map[string]interface{}{
+				"CommandPrefix": sett.GetCommandPrefix(),
+			})
+	}
+
+	var sendMsg interface{}
+	switch {
+	case action == "set" || action == "s":
+		premStatus, days := bot.PostgresInterface.GetGuildPremiumStatus(message.GuildID)
+		isPrem := !premium.IsExpired(premStatus, days)
+		// same args as `settings <setting> <value>`, but applied to the settings of the game
+		gameSett := copyGuildSettings(dgs.gameSettings(bot.gameLogger(dgs), sett))
+		msg, isValid, _ := applySetting(gameSett, settType, args[1:], isPrem)
+		if !isValid {
+			lock.Release(ctx)
+			return message.ChannelID, msg
+		}
+		dgs.Settings.Set(settType, gameSett)
+		sendMsg = msg
+	case settType == setting.NullSetting:
+		dgs.Settings = GameSettings{}
+		sendMsg = gameSettingsResponse(bot.gameLogger(dgs), dgs, sett)
+	default:
+		dgs.Settings.Clear(settType)
+		sendMsg = gameSettingsResponse(bot.gameLogger(dgs), dgs, sett)
+	}
+	bot.GameStateStore.SetDiscordGameState(dgs, lock)
+
+	// apply the new rules to everyone right away
+	bot.handleTrackedMembers(bot.gameLogger(dgs), bot.PrimarySession, sett, 0, NoPriority, gsr, metrics.NoTransition)
+
+	// TODO refactor to return the edit, not perform it
+	dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
+
+	return message.ChannelID, sendMsg
+}
+
+// gameSettingsResponse lists the settings of the game that differ from the ones of the server
+func gameSettingsResponse(logger *logging.Logger, dgs *GameState, sett *storage.GuildSettings) string {
+	if dgs == nil || dgs.Settings.IsEmpty() {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.gameSettingsResponse.default",
+			Other: "This game follows the settings of the server",
+		})
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	if dgs.Settings.Mode != "" {
+		buf.WriteString(sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.gameSettingsResponse.mode",
+			Other: "Voice preset: `{{.Mode}}`",
+		},
+			map[string]interface{}{
+				"Mode": dgs.Settings.Mode,
+			}))
+		buf.WriteString("\n")
+	}
+	guildValues := setting.Values(sett)
+	gameValues := setting.Values(dgs.gameSettings(logger, sett))
+	for _, name := range setting.ChangedValues(guildValues, gameValues) {
+		buf.WriteString(fmt.Sprintf("`%s`: %s → %s\n", name, settingsHistoryValue(guildValues[name]), settingsHistoryValue(gameValues[name])))
+	}
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "commands.gameSettingsResponse.changed",
+		Other: "This game's settings differ from the server's:\n{{.Changes}}",
+	},
+		map[string]interface{}{
+			"Changes": buf.String(),
+		})
+}
+
+func unknownModeResponse(sett *storage.GuildSettings, mode string) string {
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "commands.unknownModeResponse",
+		Other: "Sorry, there's no `{{.Mode}}` preset. The presets are:\n{{.Presets}}",
+	},
+		map[string]interface{}{
+			"Mode":    mode,
+			"Presets": setting.VoicePresetList(sett),
+		})
+}
+
+func modeResponse(dgs *GameState, sett *storage.GuildSettings) string {
+	if dgs == nil || dgs.Settings.Mode == "" {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.modeResponse.default",
+			Other: "This game follows the voice settings of the server. The presets are:\n{{.Presets}}",
+		},
+			map[string]interface{}{
+				"Presets": setting.VoicePresetList(sett),
+			})
+	}
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "commands.modeResponse.current",
+		Other: "This game uses the `{{.Mode}}` preset. The presets are:\n{{.Presets}}",
+	},
+		map[string]interface{}{
+			"Mode":    dgs.Settings.Mode,
+			"Presets": setting.VoicePresetList(sett),
+		})
+}
+
 func commandFnMap(
 	_ *Bot,
 	_ bool,
 	_ bool,
-	sett *settings.GuildSettings,
+	sett *storage.GuildSettings,
 	_ *discordgo.Guild,
 	message *discordgo.MessageCreate,
 	args []string,
@@ -786,7 +1539,7 @@ func commandFnMap(
 			mapName = strings.Join(args[1:], " ")
 			mapVersion = sett.GetMapVersion()
 		}
-		mapItem, err := amongus.NewMapItem(mapName, sett)
+		mapItem, err := amongus.NewMapItem(mapName, &sett.GuildSettings)
 		if err != nil {
 			log.Println(err)
 			return message.ChannelID, sett.LocalizeMessage(&i18n.Message{
@@ -810,7 +1563,7 @@ func commandFnCache(
 	bot *Bot,
 	_ bool,
 	_ bool,
-	sett *settings.GuildSettings,
+	sett *storage.GuildSettings,
 	_ *discordgo.Guild,
 	message *discordgo.MessageCreate,
 	args []string,
@@ -825,7 +1578,7 @@ func commandFnCache(
 			return message.ChannelID, "I couldn't find a user by that name or ID!"
 		}
 		if len(args[2:]) == 0 {
-			cached := bot.RedisInterface.GetUsernameOrUserIDMappings(message.GuildID, userID)
+			cached := bot.GameStateStore.GetUsernameOrUserIDMappings(message.GuildID, userID)
 			if len(cached) == 0 {
 				return message.ChannelID, sett.LocalizeMessage(&i18n.Message{
 					ID:    "commands.HandleCommand.Cache.emptyCachedNames",
@@ -845,7 +1598,10 @@ func commandFnCache(
 				return message.ChannelID, buf.String()
 			}
 		} else if strings.ToLower(args[2]) == clearArgumentString || strings.ToLower(args[2]) == "c" {
-			err := bot.RedisInterface.DeleteLinksByUserID(message.GuildID, userID)
+			err := bot.GameStateStore.DeleteLinksByUserID(message.GuildID, userID)
+			if err == nil {
+				err = bot.deleteIdentityLinks(message.GuildID, userID)
+			}
 			if err != nil {
 				log.Println(err)
 				return message.ChannelID, err
@@ -864,7 +1620,7 @@ func commandFnPrivacy(
 	bot *Bot,
 	_ bool,
 	_ bool,
-	sett *settings.GuildSettings,
+	sett *storage.GuildSettings,
 	_ *discordgo.Guild,
 	message *discordgo.MessageCreate,
 	args []string,
@@ -888,7 +1644,7 @@ func commandFnInfo(
 	bot *Bot,
 	_ bool,
 	_ bool,
-	sett *settings.GuildSettings,
+	sett *storage.GuildSettings,
 	_ *discordgo.Guild,
 	message *discordgo.MessageCreate,
 	_ []string,
@@ -902,7 +1658,7 @@ func commandFnDebugState(
 	bot *Bot,
 	_ bool,
 	_ bool,
-	_ *settings.GuildSettings,
+	_ *storage.GuildSettings,
 	_ *discordgo.Guild,
 	message *discordgo.MessageCreate,
 	_ []string,
@@ -913,7 +1669,7 @@ func commandFnDebugState(
 			GuildID:     message.GuildID,
 			TextChannel: message.ChannelID,
 		}
-		state := bot.RedisInterface.GetReadOnlyDiscordGameState(gsr)
+		state := bot.GameStateStore.GetReadOnlyDiscordGameState(gsr)
 		if state != nil {
 			jBytes, err := json.MarshalIndent(state, "", "  ")
 			if err != nil {
@@ -938,7 +1694,7 @@ func commandFnASCII(
 	_ *Bot,
 	_ bool,
 	_ bool,
-	sett *settings.GuildSettings,
+	sett *storage.GuildSettings,
 	_ *discordgo.Guild,
 	message *discordgo.MessageCreate,
 	args []string,
@@ -972,7 +1728,7 @@ func commandFnStats(
 	bot *Bot,
 	isAdmin bool,
 	_ bool,
-	sett *settings.GuildSettings,
+	sett *storage.GuildSettings,
 	_ *discordgo.Guild,
 	message *discordgo.MessageCreate,
 	args []string,
@@ -1075,7 +1831,7 @@ func commandFnPremium(
 	bot *Bot,
 	isAdmin bool,
 	_ bool,
-	sett *settings.GuildSettings,
+	sett *storage.GuildSettings,
 	_ *discordgo.Guild,
 	message *discordgo.MessageCreate,
 	args []string,
@@ -1106,7 +1862,7 @@ func commandFnWorkerBOT(
 	bot *Bot,
 	_ bool,
 	_ bool,
-	sett *settings.GuildSettings,
+	sett *storage.GuildSettings,
 	guild *discordgo.Guild,
 	message *discordgo.MessageCreate,
 	_ []string,
@@ -1,19 +1,37 @@
 package discord
 
 import (
+	"bytes"
 	"fmt"
-	"github.com/automuteus/utils/pkg/settings"
+	"github.com/automuteus/automuteus/storage"
 	"log"
 	"strings"
 
 	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/logging"
+	"github.com/automuteus/utils/pkg/discord"
 	"github.com/bwmarrin/discordgo"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 )
 
+// ChannelRole is how the voice rules treat everyone in a tracked voice channel
+type ChannelRole string
+
+const (
+	// RolePlayers channels follow the voice rules as usual. This is the role of the channel a game is started in
+	RolePlayers ChannelRole = "players"
+	// RoleGhosts channels are where dead players go; everyone in them is treated as dead
+	RoleGhosts ChannelRole = "ghosts"
+	// RoleSpectators channels are for spectators; everyone in them is treated as a spectator, even if linked
+	RoleSpectators ChannelRole = "spectators"
+)
+
+var AllChannelRoles = []ChannelRole{RolePlayers, RoleGhosts, RoleSpectators}
+
 type TrackingChannel struct {
-	ChannelID   string `json:"channelID"`
-	ChannelName string `json:"channelName"`
+	ChannelID   string      `json:"channelID"`
+	ChannelName string      `json:"channelName"`
+	Role        ChannelRole `json:"role,omitempty"`
 }
 
 type GameState struct {
@@ -30,10 +48,19 @@ type GameState struct {
 
 	UserData UserDataSet     `json:"userData"`
 	Tracking TrackingChannel `json:"tracking"`
+	// ExtraTracking are any other voice channels tracked for the game, in addition to Tracking (the main players channel)
+	ExtraTracking []TrackingChannel `json:"extraTracking"`
+	// GhostTracking is the guild's ghost channel, tracked as a ghosts channel once dead players are moved there
+	GhostTracking TrackingChannel `json:"ghostTracking"`
 
 	GameStateMsg GameStateMessage `json:"gameStateMessage"`
 
 	AmongUsData amongus.AmongUsData `json:"amongUsData"`
+
+	// Settings are the settings changed for this game only; they're discarded with the game
+	Settings GameSettings `json:"settings"`
+
+	Waitlist Waitlist `json:"waitlist"`
 }
 
 func NewDiscordGameState(guildID string) *GameState {
@@ -52,8 +79,12 @@ func (dgs *GameState) Reset() {
 	dgs.MatchStartUnix = -1
 	dgs.UserData = map[string]UserData{}
 	dgs.Tracking = TrackingChannel{}
+	dgs.ExtraTracking = []TrackingChannel{}
+	dgs.GhostTracking = TrackingChannel{}
 	dgs.GameStateMsg = MakeGameStateMessage()
 	dgs.AmongUsData = amongus.NewAmongUsData()
+	dgs.Settings = GameSettings{}
+	dgs.Waitlist = NewWaitlist()
 }
 
 func (dgs *GameState) checkCacheAndAddUser(g *discordgo.Guild, s *discordgo.Session, userID string) (UserData, bool) {
@@ -84,35 +115,154 @@ func (dgs *GameState) clearGameTracking(s *discordgo.Session) {
 
 	// reset all the Tracking channels
 	dgs.Tracking = TrackingChannel{}
+	dgs.ExtraTracking = []TrackingChannel{}
+	dgs.GhostTracking = TrackingChannel{}
 
 	dgs.DeleteGameStateMsg(s)
 }
 
-func (dgs *GameState) trackChannel(channelName string, allChannels []*discordgo.Channel, sett *settings.GuildSettings) string {
-	for _, c := range allChannels {
-		if (strings.ToLower(c.Name) == strings.ToLower(channelName) || c.ID == channelName) && c.Type == 2 {
-			dgs.Tracking = TrackingChannel{ChannelName: c.Name, ChannelID: c.ID}
+// TrackedChannels returns every voice channel tracked for the game, starting with the main players channel. The ghost
+// channel comes last, unless it's tracked with another role already
+func (dgs *GameState) TrackedChannels() []TrackingChannel {
+	channels := make([]TrackingChannel, 0, len(dgs.ExtraTracking)+2)
+	if dgs.Tracking.ChannelID != "" {
+		main := dgs.Tracking
+		if main.Role == "" {
+			main.Role = RolePlayers
+		}
+		channels = append(channels, main)
+	}
+	for _, c := range dgs.ExtraTracking {
+		if c.ChannelID != "" {
+			channels = append(channels, c)
+		}
+	}
+	if dgs.GhostTracking.ChannelID != "" {
+		for _, c := range channels {
+			if c.ChannelID == dgs.GhostTracking.ChannelID {
+				return channels
+			}
+		}
+		channels = append(channels, dgs.GhostTracking)
+	}
+	return channels
+}
+
+// TrackedChannelRole returns the role of a voice channel, and false if the channel isn't tracked for the game
+func (dgs *GameState) TrackedChannelRole(channelID string) (ChannelRole, bool) {
+	if channelID == "" {
+		return "", false
+	}
+	for _, c := range dgs.TrackedChannels() {
+		if c.ChannelID == channelID {
+			return c.Role, true
+		}
+	}
+	return "", false
+}
+
+func (dgs *GameState) trackedChannelsString() string {
+	buf := bytes.NewBuffer([]byte{})
+	for i, c := range dgs.TrackedChannels() {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(discord.MentionByChannelID(c.ChannelID))
+		if c.Role != RolePlayers {
+			buf.WriteString(fmt.Sprintf(" (%s)", c.Role))
+		}
+	}
+	return buf.String()
+}
+
+func getChannelRole(arg string) (ChannelRole, bool) {
+	arg = strings.ToLower(arg)
+	for _, role := range AllChannelRoles {
+		if arg == string(role) || arg == strings.TrimSuffix(string(role), "s") {
+			return role, true
+		}
+	}
+	return "", false
+}
 
-			log.Println(fmt.Sprintf("Now Tracking \"%s\" Voice Channel for Automute!", c.Name))
+// trackChannels tracks the voice channels provided, each optionally followed by its role (players by default).
+// The first players channel replaces the main channel of the game, and all the other tracked channels are replaced
+func (dgs *GameState) trackChannels(logger *logging.Logger, args []string, allChannels []*discordgo.Channel, sett *storage.GuildSettings) string {
+	channels := make([]TrackingChannel, 0, len(args))
+	for _, arg := range args {
+		if role, isRole := getChannelRole(arg); isRole {
+			if len(channels) == 0 {
+				return sett.LocalizeMessage(&i18n.Message{
+					ID:    "discordGameState.trackChannels.roleWithoutChannel",
+					Other: "Please provide a voice channel before the role `{{.role}}`!",
+				},
+					map[string]interface{}{
+						"role": arg,
+					})
+			}
+			channels[len(channels)-1].Role = role
+			continue
+		}
+
+		channelID, err := discord.ExtractChannelIDFromMention(arg)
+		if err != nil {
+			channelID = arg
+		}
+		var found *discordgo.Channel
+		for _, c := range allChannels {
+			if (strings.ToLower(c.Name) == strings.ToLower(arg) || c.ID == channelID) && c.Type == discordgo.ChannelTypeGuildVoice {
+				found = c
+				break
+			}
+		}
+		if found == nil {
 			return sett.LocalizeMessage(&i18n.Message{
-				ID:    "discordGameState.trackChannel.voiceChannelSet",
-				Other: "Now Tracking \"{{.channelName}}\" Voice Channel for Automute!",
+				ID:    "discordGameState.trackChannel.voiceChannelNotfound",
+				Other: "No channel found by the name {{.channelName}}!\n",
 			},
 				map[string]interface{}{
-					"channelName": c.Name,
+					"channelName": arg,
 				})
 		}
+		channels = append(channels, TrackingChannel{ChannelID: found.ID, ChannelName: found.Name, Role: RolePlayers})
+	}
+
+	main := dgs.Tracking
+	extra := make([]TrackingChannel, 0, len(channels))
+	mainSet := false
+	for _, c := range channels {
+		if !mainSet && c.Role == RolePlayers {
+			main = c
+			mainSet = true
+			continue
+		}
+		extra = append(extra, c)
+	}
+	dgs.Tracking = main
+	dgs.ExtraTracking = []TrackingChannel{}
+	seen := map[string]bool{main.ChannelID: true}
+	for _, c := range extra {
+		if !seen[c.ChannelID] {
+			seen[c.ChannelID] = true
+			dgs.ExtraTracking = append(dgs.ExtraTracking, c)
+		}
+	}
+
+	names := make([]string, 0, len(channels))
+	for _, c := range dgs.TrackedChannels() {
+		names = append(names, fmt.Sprintf("\"%s\" (%s)", c.ChannelName, c.Role))
 	}
+	logger.Info("tracking voice channels", "channels", strings.Join(names, ", "))
 	return sett.LocalizeMessage(&i18n.Message{
-		ID:    "discordGameState.trackChannel.voiceChannelNotfound",
-		Other: "No channel found by the name {{.channelName}}!\n",
+		ID:    "discordGameState.trackChannels.voiceChannelsSet",
+		Other: "Now Tracking {{.channels}} for Automute!",
 	},
 		map[string]interface{}{
-			"channelName": channelName,
+			"channels": strings.Join(names, ", "),
 		})
 }
 
-func (dgs *GameState) ToEmojiEmbedFields(emojis AlivenessEmojis, sett *settings.GuildSettings) []*discordgo.MessageEmbedField {
+func (dgs *GameState) ToEmojiEmbedFields(emojis AlivenessEmojis, sett *storage.GuildSettings) []*discordgo.MessageEmbedField {
 	unsorted := make([]*discordgo.MessageEmbedField, 18)
 	num := 0
 
@@ -6,13 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/logging"
 	"github.com/automuteus/automuteus/metrics"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/automuteus/utils/pkg/game"
-	"github.com/automuteus/utils/pkg/settings"
-	"github.com/automuteus/utils/pkg/storage"
+	storageutils "github.com/automuteus/utils/pkg/storage"
 	"github.com/automuteus/utils/pkg/task"
 	"github.com/go-redis/redis/v8"
-	"log"
 	"strconv"
 	"strings"
 	"time"
@@ -20,188 +20,128 @@ import (
 
 type EndGameMessage bool
 
-func (bot *Bot) SubscribeToGameByConnectCode(guildID, connectCode string, endGameChannel chan EndGameMessage) {
-	log.Println("Started Redis Subscription worker for " + connectCode)
-
-	notify := task.Subscribe(ctx, bot.RedisInterface.client, connectCode)
+// JobNotificationBuffer is how many notifications of new jobs are timestamped ahead of the jobs being processed
+const JobNotificationBuffer = 1000
 
-	timer := time.NewTimer(time.Second * time.Duration(bot.captureTimeout))
+// SubscribeToGameByConnectCode pops and processes the jobs of a game until it ends or is handed off. Callers must
+// call bot.subscriptions.Add(1) before starting it, so a concurrent GracefulClose always waits for it
+func (bot *Bot) SubscribeToGameByConnectCode(guildID, connectCode string, endGameChannel chan EndGameMessage) {
+	defer bot.subscriptions.Done()
 
 	dgsRequest := GameStateRequest{
 		GuildID:     guildID,
 		ConnectCode: connectCode,
 	}
 
+	gameLog := bot.logger.With("guildID", guildID, "connectCode", connectCode)
+
+	// only one instance may pop the jobs for a game
+	if !bot.GameStateStore.AcquireGameLease(dgsRequest, bot.nodeID, GameLeaseTTL) {
+		gameLog.Info("another instance is already subscribed to the game")
+		bot.stopSubscription(connectCode, endGameChannel)
+		return
+	}
+	gameLog.Info("started Redis subscription worker")
+
+	bot.ChannelsMapLock.Lock()
+	bot.subscribedGames[connectCode] = dgsRequest
+	bot.ChannelsMapLock.Unlock()
+	defer func() {
+		bot.ChannelsMapLock.Lock()
+		delete(bot.subscribedGames, connectCode)
+		bot.ChannelsMapLock.Unlock()
+	}()
+
+	reconcileDone := make(chan struct{})
+	defer close(reconcileDone)
+	go bot.voiceReconcileWorker(dgsRequest, ReconcileInterval, reconcileDone)
+
+	notify := task.Subscribe(ctx, bot.RedisInterface.client, connectCode)
+	// the capture notifies every job it pushes, in order, so the time each notification is received is when its job
+	// was pushed. They're timestamped as soon as they arrive, even while the bot is busy processing other jobs
+	notifications := make(chan time.Time, JobNotificationBuffer)
+	go func() {
+		for range notify.Channel() {
+			notifications <- time.Now()
+		}
+		close(notifications)
+	}()
+
+	timer := time.NewTimer(time.Second * time.Duration(bot.captureTimeout))
+
+	heartbeat := time.NewTicker(GameLeaseTTL / 3)
+	defer heartbeat.Stop()
+
 	// indicate to the broker that we're online and ready to start processing messages
 	task.Ack(ctx, bot.RedisInterface.client, connectCode)
 
 	for {
 		select {
-		case message := <-notify.Channel():
-			timer.Reset(time.Second * time.Duration(bot.captureTimeout))
-			if message == nil {
+		case notified, ok := <-notifications:
+			if !ok {
+				notifications = nil
 				break
 			}
+			timer.Reset(time.Second * time.Duration(bot.captureTimeout))
+			// when the jobs not popped yet were pushed, oldest first
+			pushed := []time.Time{notified}
 
-			// anytime we get a notification message, continue pulling messages off the list until there are no more
+			// anytime we get a notification message, continue pulling messages off the list until there are no more.
+			// Once the list is empty, any notification left in pushed belongs to a job that was already popped
 			for {
+				pushed = append(pushed, receivedNotifications(notifications)...)
 				job, err := task.PopJob(ctx, bot.RedisInterface.client, connectCode)
 				if errors.Is(err, redis.Nil) {
 					break
 				} else if err != nil {
-					log.Println(err)
+					gameLog.Error("failed to pop job", "err", err)
 					break
 				}
-				log.Printf("Popped job of type %d w/ payload %s\n", job.JobType, job.Payload.(string))
+				popped := time.Now()
+				before := bot.GameStateStore.GetReadOnlyDiscordGameState(dgsRequest)
+				jobLog := gameLog.With("matchID", before.MatchID)
+				jobLog.Debug("popped job", "jobType", job.JobType, "payload", job.Payload)
 				bot.refreshGameLiveness(connectCode)
-				bot.RedisInterface.RefreshActiveGame(guildID, connectCode)
+				bot.GameStateStore.RefreshActiveGame(guildID, connectCode)
 
-				gameEvent := storage.PostgresGameEvent{
+				gameEvent := storageutils.PostgresGameEvent{
 					GameID:    -1,
 					UserID:    nil,
 					EventTime: int32(time.Now().Unix()),
 					EventType: int16(job.JobType),
 					Payload:   job.Payload.(string),
 				}
-				correlatedUserID := ""
-
-				switch job.JobType {
-				case task.ConnectionJob:
-					lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
-					for lock == nil {
-						lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
-					}
-					if job.Payload == trueString {
-						dgs.Linked = true
-					} else {
-						dgs.Linked = false
-					}
-					dgs.ConnectCode = connectCode
-					bot.RedisInterface.SetDiscordGameState(dgs, lock)
-
-					sett := bot.StorageInterface.GetGuildSettings(guildID)
-					bot.handleTrackedMembers(bot.PrimarySession, sett, 0, NoPriority, dgsRequest)
-
-					edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett))
-					if edited {
-						metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageEdit, 1)
-					}
-				case task.LobbyJob:
-					var lobby game.Lobby
-					err := json.Unmarshal([]byte(job.Payload.(string)), &lobby)
-					if err != nil {
-						log.Println(err)
-						break
-					}
-
-					sett := bot.StorageInterface.GetGuildSettings(guildID)
-					bot.processLobby(sett, lobby, dgsRequest)
-				case task.StateJob:
-					num, err := strconv.ParseInt(job.Payload.(string), 10, 64)
-					if err != nil {
-						log.Println(err)
-						break
-					}
-
-					bot.processTransition(game.Phase(num), dgsRequest)
-				case task.PlayerJob:
-					var player game.Player
-					err := json.Unmarshal([]byte(job.Payload.(string)), &player)
-					if err != nil {
-						log.Println(err)
-						break
-					}
-					if player.Color > 17 || player.Color < 0 {
-						break
-					}
 
-					sett := bot.StorageInterface.GetGuildSettings(guildID)
-					shouldHandleTracked, userID := bot.processPlayer(sett, player, dgsRequest)
-					if shouldHandleTracked {
-						bot.handleTrackedMembers(bot.PrimarySession, sett, 0, NoPriority, dgsRequest)
-					}
-					correlatedUserID = userID
-				case task.GameOverJob:
-					var gameOverResult game.Gameover
-					// log.Println("Successfully identified game over event:")
-					// log.Println(job.Payload)
-					err := json.Unmarshal([]byte(job.Payload.(string)), &gameOverResult)
-					if err != nil {
-						log.Println(err)
-						break
-					}
+				sett := before.gameSettings(jobLog, bot.StorageInterface.GetGuildSettings(guildID))
+				correlatedUserID := bot.processJob(jobLog, sett, job, dgsRequest)
 
-					sett := bot.StorageInterface.GetGuildSettings(guildID)
-
-					// we only need a read-only state for making the game summary message
-					dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(dgsRequest)
-					if dgs != nil {
-						delTime := sett.GetDeleteGameSummaryMinutes()
-						if delTime != 0 {
-							winners := getWinners(*dgs, gameOverResult)
-							buf := bytes.NewBuffer([]byte{})
-							for i, v := range winners {
-								roleStr := "Crewmate"
-								if v.role == game.ImposterRole {
-									roleStr = "Imposter"
-								}
-								buf.WriteString(fmt.Sprintf("<@%s>", v.userID))
-								if i < len(winners)-1 {
-									buf.WriteRune(',')
-								} else {
-									buf.WriteString(fmt.Sprintf(" won as %s", roleStr))
-								}
-							}
-							embed := gameOverMessage(dgs, bot.StatusEmojis, sett, buf.String())
-							channelID := dgs.GameStateMsg.MessageChannelID
-							if sett.GetMatchSummaryChannelID() != "" {
-								channelID = sett.GetMatchSummaryChannelID()
-							}
-							msg, err := bot.PrimarySession.ChannelMessageSendEmbed(channelID, embed)
-							if delTime > 0 && err == nil {
-								metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageCreateDelete, 2)
-								go MessageDeleteWorker(bot.PrimarySession, msg.ChannelID, msg.ID, time.Minute*time.Duration(delTime))
-							} else if err == nil {
-								metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageCreateDelete, 1)
-							}
-						}
-						go dumpGameToPostgres(*dgs, bot.PostgresInterface, gameOverResult)
-
-						// refresh the game message if the setting is marked (it is not locked, the previous dgs is
-						// read-only). This means the original msg is refreshed, not the gameover message
-						if sett.AutoRefresh {
-							bot.RefreshGameStateMessage(dgsRequest, sett)
-						}
-
-						// now we need to fetch the state again (AFTER refreshing) to mark the game as complete/
-						lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
-						for lock == nil {
-							lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
-						}
-						dgs.MatchID = -1
-						dgs.MatchStartUnix = -1
-						bot.RedisInterface.SetDiscordGameState(dgs, lock)
-					}
+				after := bot.GameStateStore.GetReadOnlyDiscordGameState(dgsRequest)
+				transition := jobTransition(job.JobType, before.AmongUsData.GetPhase(), after.AmongUsData.GetPhase())
+				// the notification of a job pushed right before it was popped may not have been received yet
+				if len(pushed) > 0 {
+					metrics.JobPopLatency.WithLabelValues(transition).Observe(popped.Sub(pushed[0]).Seconds())
+					pushed = pushed[1:]
 				}
+
 				if job.JobType != task.ConnectionJob {
-					go func(userID string, ge storage.PostgresGameEvent) {
-						dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(dgsRequest)
+					go func(userID string, ge storageutils.PostgresGameEvent) {
+						dgs := bot.GameStateStore.GetReadOnlyDiscordGameState(dgsRequest)
 						if dgs.MatchID > 0 && dgs.MatchStartUnix > 0 {
 							ge.GameID = dgs.MatchID
 							if userID != "" {
 								num, err := strconv.ParseUint(userID, 10, 64)
 								if err != nil {
-									log.Println(err)
+									jobLog.Warn("invalid user ID for the postgres event", "userID", userID, "err", err)
 									ge.UserID = nil
 								} else {
 									ge.UserID = &num
 								}
-								log.Printf("Adding postgres event with user id %d\n", ge.UserID)
+								jobLog.Debug("adding postgres event", "userID", userID)
 							}
 
 							err := bot.PostgresInterface.AddEvent(&ge)
 							if err != nil {
-								log.Println(err)
+								jobLog.Error("failed to add postgres event", "err", err)
 							}
 						}
 					}(correlatedUserID, gameEvent)
@@ -209,31 +149,191 @@ func (bot *Bot) SubscribeToGameByConnectCode(guildID, connectCode string, endGam
 			}
 			break
 
+		case <-heartbeat.C:
+			if !bot.GameStateStore.RenewGameLease(connectCode, bot.nodeID, GameLeaseTTL) {
+				// we couldn't renew in time, so another instance may have taken over already
+				gameLog.Warn("lost the lease for the game, stopping the subscription")
+				err := notify.Close()
+				if err != nil {
+					gameLog.Error("failed to close the subscription", "err", err)
+				}
+				bot.stopSubscription(connectCode, endGameChannel)
+				return
+			}
 		case <-timer.C:
 			timer.Stop()
-			log.Printf("Killing game w/ code %s after %d seconds of inactivity!\n", connectCode, bot.captureTimeout)
+			gameLog.Info("killing game after inactivity", "timeoutSeconds", bot.captureTimeout)
 			err := notify.Close()
 			if err != nil {
-				log.Println(err)
+				gameLog.Error("failed to close the subscription", "err", err)
 			}
 			go bot.forceEndGame(dgsRequest)
-			bot.ChannelsMapLock.Lock()
-			delete(bot.EndGameChannels, connectCode)
-			bot.ChannelsMapLock.Unlock()
+			bot.stopSubscription(connectCode, endGameChannel)
+			bot.GameStateStore.ReleaseGameLease(connectCode, bot.nodeID)
 
 			return
-		case <-endGameChannel:
-			log.Println("Redis subscriber received kill signal, closing all pubsubs")
+		case msg := <-endGameChannel:
+			gameLog.Info("Redis subscriber received kill signal, closing all pubsubs", "orphan", msg == OrphanGame)
 			err := notify.Close()
 			if err != nil {
-				log.Println(err)
+				gameLog.Error("failed to close the subscription", "err", err)
+			}
+			if msg == OrphanGame {
+				bot.orphanGame(dgsRequest)
+			} else {
+				bot.forceEndGame(dgsRequest)
 			}
-			bot.forceEndGame(dgsRequest)
+			bot.GameStateStore.ReleaseGameLease(connectCode, bot.nodeID)
 			return
 		}
 	}
 }
 
+// receivedNotifications returns the times of the notifications received already, without waiting for more
+func receivedNotifications(notifications <-chan time.Time) []time.Time {
+	var received []time.Time
+	for {
+		select {
+		case notified, ok := <-notifications:
+			if !ok {
+				return received
+			}
+			received = append(received, notified)
+		default:
+			return received
+		}
+	}
+}
+
+// jobTransition is the transition label of the latencies of a job: the phase transition it caused, if it's a state job
+func jobTransition(jobType task.JobType, oldPhase, phase game.Phase) string {
+	if jobType != task.StateJob || oldPhase == phase {
+		return metrics.NoTransition
+	}
+	return metrics.Transition(oldPhase, phase)
+}
+
+// processJob applies a single job from the capture to the game state, and returns the ID of the User the job
+// was correlated with (if any). It's shared by the live Redis subscription and by replays of recorded games
+func (bot *Bot) processJob(logger *logging.Logger, sett *storage.GuildSettings, job task.Job, dgsRequest GameStateRequest) string {
+	correlatedUserID := ""
+
+	switch job.JobType {
+	case task.ConnectionJob:
+		lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(dgsRequest)
+		for lock == nil {
+			lock, dgs = bot.GameStateStore.GetDiscordGameStateAndLock(dgsRequest)
+		}
+		if job.Payload == trueString {
+			dgs.Linked = true
+		} else {
+			dgs.Linked = false
+		}
+		dgs.ConnectCode = dgsRequest.ConnectCode
+		bot.GameStateStore.SetDiscordGameState(dgs, lock)
+
+		bot.handleTrackedMembers(logger, bot.PrimarySession, sett, 0, NoPriority, dgsRequest, metrics.NoTransition)
+
+		edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
+		if edited {
+			metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageEdit, 1)
+		}
+	case task.LobbyJob:
+		var lobby game.Lobby
+		err := json.Unmarshal([]byte(job.Payload.(string)), &lobby)
+		if err != nil {
+			logger.Error("invalid lobby job", "err", err)
+			break
+		}
+
+		bot.processLobby(sett, lobby, dgsRequest)
+	case task.StateJob:
+		num, err := strconv.ParseInt(job.Payload.(string), 10, 64)
+		if err != nil {
+			logger.Error("invalid state job", "err", err)
+			break
+		}
+
+		bot.processTransition(logger, sett, game.Phase(num), dgsRequest)
+	case task.PlayerJob:
+		var player game.Player
+		err := json.Unmarshal([]byte(job.Payload.(string)), &player)
+		if err != nil {
+			logger.Error("invalid player job", "err", err)
+			break
+		}
+		if player.Color > 17 || player.Color < 0 {
+			break
+		}
+
+		shouldHandleTracked, userID := bot.processPlayer(logger, sett, player, dgsRequest)
+		if shouldHandleTracked {
+			bot.handleTrackedMembers(logger, bot.PrimarySession, sett, 0, NoPriority, dgsRequest, metrics.NoTransition)
+		}
+		correlatedUserID = userID
+	case task.GameOverJob:
+		var gameOverResult game.Gameover
+		// log.Println("Successfully identified game over event:")
+		// log.Println(job.Payload)
+		err := json.Unmarshal([]byte(job.Payload.(string)), &gameOverResult)
+		if err != nil {
+			logger.Error("invalid game over job", "err", err)
+			break
+		}
+
+		// we only need a read-only state for making the game summary message
+		dgs := bot.GameStateStore.GetReadOnlyDiscordGameState(dgsRequest)
+		if dgs != nil {
+			delTime := sett.GetDeleteGameSummaryMinutes()
+			if delTime != 0 {
+				winners := getWinners(*dgs, gameOverResult)
+				buf := bytes.NewBuffer([]byte{})
+				for i, v := range winners {
+					roleStr := "Crewmate"
+					if v.role == game.ImposterRole {
+						roleStr = "Imposter"
+					}
+					buf.WriteString(fmt.Sprintf("<@%s>", v.userID))
+					if i < len(winners)-1 {
+						buf.WriteRune(',')
+					} else {
+						buf.WriteString(fmt.Sprintf(" won as %s", roleStr))
+					}
+				}
+				embed := gameOverMessage(dgs, bot.StatusEmojis, sett, buf.String())
+				channelID := dgs.GameStateMsg.MessageChannelID
+				if sett.GetMatchSummaryChannelID() != "" {
+					channelID = sett.GetMatchSummaryChannelID()
+				}
+				msg, err := bot.PrimarySession.ChannelMessageSendEmbed(channelID, embed)
+				if delTime > 0 && err == nil {
+					metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageCreateDelete, 2)
+					go MessageDeleteWorker(bot.PrimarySession, msg.ChannelID, msg.ID, time.Minute*time.Duration(delTime))
+				} else if err == nil {
+					metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageCreateDelete, 1)
+				}
+			}
+			go dumpGameToPostgres(logger, *dgs, bot.PostgresInterface, gameOverResult)
+
+			// refresh the game message if the setting is marked (it is not locked, the previous dgs is
+			// read-only). This means the original msg is refreshed, not the gameover message
+			if sett.AutoRefresh {
+				bot.RefreshGameStateMessage(dgsRequest, sett)
+			}
+
+			// now we need to fetch the state again (AFTER refreshing) to mark the game as complete/
+			lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(dgsRequest)
+			for lock == nil {
+				lock, dgs = bot.GameStateStore.GetDiscordGameStateAndLock(dgsRequest)
+			}
+			dgs.MatchID = -1
+			dgs.MatchStartUnix = -1
+			bot.GameStateStore.SetDiscordGameState(dgs, lock)
+		}
+	}
+	return correlatedUserID
+}
+
 type winnerRecord struct {
 	userID string
 	role   game.GameRole
@@ -269,19 +369,19 @@ func getWinners(dgs GameState, gameOver game.Gameover) []winnerRecord {
 	return winners
 }
 
-func (bot *Bot) processPlayer(sett *settings.GuildSettings, player game.Player, dgsRequest GameStateRequest) (bool, string) {
+func (bot *Bot) processPlayer(logger *logging.Logger, sett *storage.GuildSettings, player game.Player, dgsRequest GameStateRequest) (bool, string) {
 	if player.Name != "" {
-		lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
+		lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(dgsRequest)
 		for lock == nil {
-			lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
+			lock, dgs = bot.GameStateStore.GetDiscordGameStateAndLock(dgsRequest)
 		}
 		dgs.Linked = true
 
-		defer bot.RedisInterface.SetDiscordGameState(dgs, lock)
+		defer bot.GameStateStore.SetDiscordGameState(dgs, lock)
 
 		if player.Disconnected || player.Action == game.LEFT {
 			if player.Disconnected {
-				log.Println("I detected that " + player.Name + " disconnected, I'm purging their player data!")
+				logger.Info("player disconnected, purging their player data", "player", player.Name)
 				dgs.ClearPlayerDataByPlayerName(player.Name)
 			}
 			_, _, data := dgs.AmongUsData.UpdatePlayer(player)
@@ -289,17 +389,16 @@ func (bot *Bot) processPlayer(sett *settings.GuildSettings, player game.Player,
 			userID := dgs.AttemptPairingByMatchingNames(data)
 			// try pairing via the cached usernames
 			if userID == "" {
-				uids := bot.RedisInterface.GetUsernameOrUserIDMappings(dgs.GuildID, player.Name)
-				userID = dgs.AttemptPairingByUserIDs(data, uids)
+				userID = bot.attemptPairingByLinks(dgs, data)
 			} else {
-				bot.applyToSingle(dgs, userID, false, false)
+				bot.applyToSingle(logger, dgs, userID, false, false)
 			}
 
 			dgs.AmongUsData.ClearPlayerData(player.Name)
 
 			// only update the message if we're not in the tasks phase (info leaks)
 			if dgs.AmongUsData.GetPhase() != game.TASKS {
-				edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett))
+				edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
 				if edited {
 					metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageEdit, 1)
 				}
@@ -310,13 +409,14 @@ func (bot *Bot) processPlayer(sett *settings.GuildSettings, player game.Player,
 		updated, isAliveUpdated, data := dgs.AmongUsData.UpdatePlayer(player)
 		switch {
 		case player.Action == game.JOINED:
-			log.Println("Detected a player joined, refreshing User data mappings")
+			logger.Debug("player joined, refreshing User data mappings", "player", player.Name)
 			userID := dgs.AttemptPairingByMatchingNames(data)
-			if userID == "" {
-				uids := bot.RedisInterface.GetUsernameOrUserIDMappings(dgs.GuildID, player.Name)
-				userID = dgs.AttemptPairingByUserIDs(data, uids)
+			if userID != "" {
+				go bot.linkIdentity(dgs.GuildID, userID, data.Name, storage.LinkByNameMatch)
+			} else {
+				userID = bot.attemptPairingByLinks(dgs, data)
 			}
-			edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett))
+			edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
 			if edited {
 				metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageEdit, 1)
 			}
@@ -324,22 +424,23 @@ func (bot *Bot) processPlayer(sett *settings.GuildSettings, player game.Player,
 		case updated:
 			userID := dgs.AttemptPairingByMatchingNames(data)
 			if userID == "" {
-				uids := bot.RedisInterface.GetUsernameOrUserIDMappings(dgs.GuildID, player.Name)
-
-				userID = dgs.AttemptPairingByUserIDs(data, uids)
+				userID = bot.attemptPairingByLinks(dgs, data)
 			}
 			if isAliveUpdated && dgs.AmongUsData.GetPhase() == game.TASKS {
+				if player.IsDead {
+					bot.moveDeadPlayers(logger, dgs, sett)
+				}
 				if sett.GetUnmuteDeadDuringTasks() || player.Action == game.EXILED {
-					edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett))
+					edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
 					if edited {
 						metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageEdit, 1)
 					}
 					return true, userID
 				}
-				log.Println("NOT updating the discord status message; would leak info")
+				logger.Debug("not updating the status message; would leak info")
 				return false, userID
 			}
-			edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett))
+			edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
 			if edited {
 				metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageEdit, 1)
 			}
@@ -354,11 +455,10 @@ func (bot *Bot) processPlayer(sett *settings.GuildSettings, player game.Player,
 	return false, ""
 }
 
-func (bot *Bot) processTransition(phase game.Phase, dgsRequest GameStateRequest) {
-	sett := bot.StorageInterface.GetGuildSettings(dgsRequest.GuildID)
-	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
+func (bot *Bot) processTransition(logger *logging.Logger, sett *storage.GuildSettings, phase game.Phase, dgsRequest GameStateRequest) {
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(dgsRequest)
 	for lock == nil {
-		lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
+		lock, dgs = bot.GameStateStore.GetDiscordGameStateAndLock(dgsRequest)
 	}
 
 	oldPhase := dgs.AmongUsData.UpdatePhase(phase)
@@ -366,33 +466,42 @@ func (bot *Bot) processTransition(phase game.Phase, dgsRequest GameStateRequest)
 		lock.Release(ctx)
 		return
 	}
+	transition := metrics.Transition(oldPhase, phase)
+	defer metrics.ObserveSince(metrics.TransitionDuration, transition, time.Now())
 	dgs.Linked = true
 	// if we started a new game
 	if oldPhase == game.LOBBY && phase == game.TASKS {
 		matchStart := time.Now().Unix()
 		dgs.MatchStartUnix = matchStart
-		gameID := startGameInPostgres(*dgs, bot.PostgresInterface)
+		gameID := startGameInPostgres(logger, *dgs, bot.PostgresInterface)
 		dgs.MatchID = int64(gameID)
-		log.Printf("New match has begun. ID %d and starttime %d\n", gameID, matchStart)
+		logger = bot.gameLogger(dgs)
+		logger.Info("new match has begun", "matchStart", matchStart)
 	}
 
-	bot.RedisInterface.SetDiscordGameState(dgs, lock)
+	bot.GameStateStore.SetDiscordGameState(dgs, lock)
+	gameOver := phase == game.GAMEOVER
 	switch phase {
 	case game.MENU:
-		edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett))
+		edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
 		if edited {
 			metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageEdit, 1)
 		}
-		bot.applyToAll(dgs, false, false)
+		bot.applyToAll(logger, dgs, false, false)
+		bot.moveGhostsBack(logger, dgs, sett)
 		// on a gameover event from the capture, it's like going to the lobby; use that delay
 	case game.GAMEOVER:
 		phase = game.LOBBY
 		fallthrough
 	case game.LOBBY:
 		delay := sett.Delays.GetDelay(oldPhase, phase)
-		bot.handleTrackedMembers(bot.PrimarySession, sett, delay, NoPriority, dgsRequest)
+		bot.handleTrackedMembers(logger, bot.PrimarySession, sett, delay, NoPriority, dgsRequest, transition)
+		bot.moveGhostsBack(logger, dgs, sett)
+		if gameOver {
+			dgs = bot.rotateWaitlist(logger, sett, dgsRequest)
+		}
 
-		edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett))
+		edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
 		if edited {
 			metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageEdit, 1)
 		}
@@ -405,20 +514,23 @@ func (bot *Bot) processTransition(phase game.Phase, dgsRequest GameStateRequest)
 			priority = NoPriority
 		}
 
-		bot.handleTrackedMembers(bot.PrimarySession, sett, delay, priority, dgsRequest)
-		edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett))
+		bot.handleTrackedMembers(logger, bot.PrimarySession, sett, delay, priority, dgsRequest, transition)
+		if oldPhase == game.DISCUSS {
+			bot.moveExiledPlayers(logger, sett, dgsRequest)
+		}
+		edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
 		if edited {
 			metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageEdit, 1)
 		}
 
 	case game.DISCUSS:
 		delay := sett.Delays.GetDelay(oldPhase, phase)
-		bot.handleTrackedMembers(bot.PrimarySession, sett, delay, DeadPriority, dgsRequest)
+		bot.handleTrackedMembers(logger, bot.PrimarySession, sett, delay, DeadPriority, dgsRequest, transition)
 
 		if sett.AutoRefresh {
 			bot.RefreshGameStateMessage(dgsRequest, sett)
 		} else {
-			edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett))
+			edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
 			if edited {
 				metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageEdit, 1)
 			}
@@ -426,31 +538,46 @@ func (bot *Bot) processTransition(phase game.Phase, dgsRequest GameStateRequest)
 	}
 }
 
-func (bot *Bot) processLobby(sett *settings.GuildSettings, lobby game.Lobby, dgsRequest GameStateRequest) {
-	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
+// moveExiledPlayers moves the players that died during the discussion (or before it, if they weren't moved yet) to the
+// ghost channel, once tasks resume
+func (bot *Bot) moveExiledPlayers(logger *logging.Logger, sett *storage.GuildSettings, dgsRequest GameStateRequest) {
+	if !sett.GetMoveDeadPlayers() {
+		return
+	}
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(dgsRequest)
 	for lock == nil {
-		lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
+		lock, dgs = bot.GameStateStore.GetDiscordGameStateAndLock(dgsRequest)
+	}
+	bot.moveDeadPlayers(logger, dgs, sett)
+	bot.GameStateStore.SetDiscordGameState(dgs, lock)
+}
+
+func (bot *Bot) processLobby(sett *storage.GuildSettings, lobby game.Lobby, dgsRequest GameStateRequest) {
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(dgsRequest)
+	for lock == nil {
+		lock, dgs = bot.GameStateStore.GetDiscordGameStateAndLock(dgsRequest)
 	}
 
 	dgs.AmongUsData.SetRoomRegionMap(lobby.LobbyCode, lobby.Region.ToString(), lobby.PlayMap)
-	bot.RedisInterface.SetDiscordGameState(dgs, lock)
+	bot.GameStateStore.SetDiscordGameState(dgs, lock)
 
-	edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett))
+	edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
 	if edited {
 		metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageEdit, 1)
 	}
 }
 
-func startGameInPostgres(dgs GameState, psql *storage.PsqlInterface) uint64 {
-	if dgs.MatchStartUnix < 0 {
+func startGameInPostgres(logger *logging.Logger, dgs GameState, psql *storageutils.PsqlInterface) uint64 {
+	// no postgres when replaying a recorded game
+	if psql == nil || dgs.MatchStartUnix < 0 {
 		return 0
 	}
 	gid, err := strconv.ParseUint(dgs.GuildID, 10, 64)
 	if err != nil {
-		log.Println(err)
+		logger.Error("invalid guild ID", "err", err)
 		return 0
 	}
-	pgame := &storage.PostgresGame{
+	pgame := &storageutils.PostgresGame{
 		GameID:      -1,
 		GuildID:     gid,
 		ConnectCode: dgs.ConnectCode,
@@ -460,19 +587,22 @@ func startGameInPostgres(dgs GameState, psql *storage.PsqlInterface) uint64 {
 	}
 	i, err := psql.AddInitialGame(pgame)
 	if err != nil {
-		log.Println(err)
+		logger.Error("failed to add the game to postgres", "err", err)
 	}
 	return i
 }
 
-func dumpGameToPostgres(dgs GameState, psql *storage.PsqlInterface, gameOver game.Gameover) {
+func dumpGameToPostgres(logger *logging.Logger, dgs GameState, psql *storageutils.PsqlInterface, gameOver game.Gameover) {
+	if psql == nil {
+		return
+	}
 	if dgs.MatchID < 0 || dgs.MatchStartUnix < 0 {
-		log.Println("dgs match id or start time is <0; not dumping game to Postgres")
+		logger.Warn("match id or start time is <0; not dumping game to Postgres")
 		return
 	}
 	end := time.Now().Unix()
 
-	userGames := make([]*storage.PostgresUserGame, 0)
+	userGames := make([]*storageutils.PostgresUserGame, 0)
 
 	imposterWin := gameOver.GameOverReason == game.ImpostorByKill ||
 		gameOver.GameOverReason == game.ImpostorBySabotage ||
@@ -483,24 +613,24 @@ func dumpGameToPostgres(dgs GameState, psql *storage.PsqlInterface, gameOver gam
 		if v.GetPlayerName() != amongus.UnlinkedPlayerName {
 			inGameData, found := dgs.AmongUsData.GetByName(v.GetPlayerName())
 			if !found {
-				log.Println("No game data found for that player")
+				logger.Warn("no game data found for player", "player", v.GetPlayerName())
 				continue
 			}
 
 			uid, err := strconv.ParseUint(v.User.UserID, 10, 64)
 			if err != nil {
-				log.Println(err)
+				logger.Error("invalid user ID", "err", err)
 				continue
 			}
 			gid, err := strconv.ParseUint(dgs.GuildID, 10, 64)
 			if err != nil {
-				log.Println(err)
+				logger.Error("invalid guild ID", "err", err)
 				continue
 			}
 
 			puser, err := psql.EnsureUserExists(uid)
 			if err != nil || puser == nil {
-				log.Println(err)
+				logger.Error("failed to ensure the user exists in postgres", "err", err)
 				continue
 			}
 
@@ -518,7 +648,7 @@ func dumpGameToPostgres(dgs GameState, psql *storage.PsqlInterface, gameOver gam
 				}
 			}
 
-			userGames = append(userGames, &storage.PostgresUserGame{
+			userGames = append(userGames, &storageutils.PostgresUserGame{
 				UserID:      puser.UserID,
 				GuildID:     gid,
 				GameID:      dgs.MatchID,
@@ -529,10 +659,10 @@ func dumpGameToPostgres(dgs GameState, psql *storage.PsqlInterface, gameOver gam
 			})
 		}
 	}
-	log.Printf("Game %d has been completed and recorded in postgres\n", dgs.MatchID)
+	logger.Info("game completed and recorded in postgres")
 
 	err := psql.UpdateGameAndPlayers(dgs.MatchID, int16(gameOver.GameOverReason), end, userGames)
 	if err != nil {
-		log.Println(err)
+		logger.Error("failed to update the game in postgres", "err", err)
 	}
 }
@@ -0,0 +1,51 @@
+package discord
+
+import (
+	"testing"
+	"time"
+
+	"github.com/automuteus/automuteus/metrics"
+	"github.com/automuteus/utils/pkg/game"
+	"github.com/automuteus/utils/pkg/task"
+)
+
+func TestJobTransition(t *testing.T) {
+	tests := []struct {
+		name     string
+		jobType  task.JobType
+		oldPhase game.Phase
+		phase    game.Phase
+		expected string
+	}{
+		{"game start", task.StateJob, game.LOBBY, game.TASKS, "lobby_tasks"},
+		{"meeting", task.StateJob, game.TASKS, game.DISCUSS, "tasks_discussion"},
+		{"repeated phase", task.StateJob, game.TASKS, game.TASKS, metrics.NoTransition},
+		{"player job", task.PlayerJob, game.LOBBY, game.TASKS, metrics.NoTransition},
+		{"connection job", task.ConnectionJob, game.MENU, game.LOBBY, metrics.NoTransition},
+	}
+	for _, test := range tests {
+		if transition := jobTransition(test.jobType, test.oldPhase, test.phase); transition != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, transition)
+		}
+	}
+}
+
+func TestReceivedNotifications(t *testing.T) {
+	notifications := make(chan time.Time, 3)
+	if received := receivedNotifications(notifications); len(received) != 0 {
+		t.Errorf("Expected no notifications without waiting, got %v", received)
+	}
+
+	first, second := time.Unix(1, 0), time.Unix(2, 0)
+	notifications <- first
+	notifications <- second
+	received := receivedNotifications(notifications)
+	if len(received) != 2 || !received[0].Equal(first) || !received[1].Equal(second) {
+		t.Errorf("Expected the notifications in the order they were received, got %v", received)
+	}
+
+	close(notifications)
+	if received := receivedNotifications(notifications); len(received) != 0 {
+		t.Errorf("Expected no notifications once the subscription is closed, got %v", received)
+	}
+}
@@ -6,38 +6,81 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/automuteus/automuteus/logging"
 	"github.com/automuteus/automuteus/metrics"
 	"github.com/automuteus/utils/pkg/task"
-	"github.com/bsm/redislock"
 	"github.com/go-redis/redis/v8"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"sync"
 	"time"
 )
 
+const (
+	// GalactusRetries is how many times a failed mute request is retried
+	GalactusRetries = 3
+	// GalactusRetryBackoff is how long to wait before the first retry; it doubles for every retry after that
+	GalactusRetryBackoff = 250 * time.Millisecond
+	// GalactusAttemptTimeout is how long a single mute request may take
+	GalactusAttemptTimeout = 2 * time.Second
+	// GalactusRetryDeadline bounds all the attempts of a mute request, so retrying never holds up the game's
+	// subscription for long (or past its lease). With a voice lock, the attempts also stop once the lock expires
+	GalactusRetryDeadline = 3 * time.Second
+)
+
 type GalactusClient struct {
 	Address string
 	client  *http.Client
+	logger  *logging.Logger
+	breaker *circuitBreaker
+
+	requestsLock sync.Mutex
+	requestSeq   uint64
+	// latestRequests is the sequence number of the latest mute request of every game with one in flight
+	latestRequests map[string]uint64
 }
 
-func NewGalactusClient(address string) (*GalactusClient, error) {
+func NewGalactusClient(address string, logger *logging.Logger) (*GalactusClient, error) {
 	gc := GalactusClient{
 		Address: address,
+		logger:  logger,
 		client: &http.Client{
 			Timeout: time.Second * 10,
 		},
+		breaker:        newCircuitBreaker(GalactusFailureThreshold, GalactusBreakerCooldown),
+		latestRequests: make(map[string]uint64),
 	}
-	r, err := gc.client.Get(gc.Address + "/")
+	return &gc, gc.Ping(context.Background())
+}
+
+// Degraded reports whether Galactus is failing, so players can't be muted/unmuted
+func (gc *GalactusClient) Degraded() bool {
+	return gc.breaker.isOpen()
+}
+
+// OnDegradedChange calls fn whenever Galactus starts failing, or recovers
+func (gc *GalactusClient) OnDegradedChange(fn func(degraded bool)) {
+	gc.breaker.lock.Lock()
+	defer gc.breaker.lock.Unlock()
+	gc.breaker.onChange = fn
+}
+
+// Ping checks that Galactus is reachable
+func (gc *GalactusClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gc.Address+"/", nil)
 	if err != nil {
-		return &gc, err
+		return err
+	}
+	r, err := gc.client.Do(req)
+	if err != nil {
+		return err
 	}
 	defer r.Body.Close()
 
 	if r.StatusCode != http.StatusOK {
-		return &gc, errors.New("galactus returned a non-200 status code; ensure it is reachable")
+		return errors.New("galactus returned a non-200 status code; ensure it is reachable")
 	}
-	return &gc, nil
+	return nil
 }
 
 func (gc *GalactusClient) AddToken(token string) error {
@@ -62,7 +105,11 @@ func RecordDiscordRequestsByCounts(client *redis.Client, counts *task.MuteDeafen
 	metrics.RecordDiscordRequests(client, metrics.InvalidRequest, counts.RateLimit)
 }
 
-func (gc *GalactusClient) ModifyUsers(guildID, connectCode string, request task.UserModifyRequest, lock *redislock.Lock) *task.MuteDeafenSuccessCounts {
+// ModifyUsers asks Galactus to mute/deafen the users. The transition that caused the request labels its latency.
+// Failed requests are retried with backoff, unless the circuit breaker is open. A retry is only idempotent while the
+// request is still current though, so retrying stops at the deadline, once the voice lock is lost, or as soon as a
+// newer request is issued for the game; the reconcile worker fixes up whatever was left behind
+func (gc *GalactusClient) ModifyUsers(guildID, connectCode string, request task.UserModifyRequest, lock Lock, transition string) *task.MuteDeafenSuccessCounts {
 	if lock != nil {
 		defer lock.Release(context.Background())
 	}
@@ -73,28 +120,118 @@ func (gc *GalactusClient) ModifyUsers(guildID, connectCode string, request task.
 		return nil
 	}
 
-	log.Println(request)
+	gc.logger.Debug("modifying users", "guildID", guildID, "connectCode", connectCode, "request", request)
+
+	seq := gc.startRequest(connectCode)
+	defer gc.finishRequest(connectCode, seq)
+
+	deadline := GalactusRetryDeadline
+	if lock != nil {
+		ttl, err := lock.TTL(context.Background())
+		if err == nil && ttl < deadline {
+			deadline = ttl
+		}
+	}
+	reqCtx, cancel := context.WithTimeout(context.Background(), deadline)
+	defer cancel()
+
+	backoff := GalactusRetryBackoff
+	for attempt := 0; ; attempt++ {
+		if !gc.breaker.allow() {
+			gc.logger.Warn("not modifying users; Galactus is unreachable", "guildID", guildID, "connectCode", connectCode)
+			return nil
+		}
+		mds, retry, err := gc.modifyUsers(reqCtx, fullURL, jBytes, transition)
+		if err == nil || !retry {
+			// Galactus responded, even if it didn't like the request
+			gc.breaker.success()
+			if err != nil {
+				gc.logger.Error("failed to modify users", "guildID", guildID, "connectCode", connectCode, "err", err)
+			}
+			return mds
+		}
+		gc.breaker.failure()
+		if attempt == GalactusRetries {
+			gc.logger.Error("failed to modify users, giving up", "guildID", guildID, "connectCode", connectCode, "attempts", attempt+1, "err", err)
+			return nil
+		}
+		gc.logger.Warn("failed to modify users, retrying", "guildID", guildID, "connectCode", connectCode, "backoff", backoff, "err", err)
+		select {
+		case <-time.After(backoff):
+		case <-reqCtx.Done():
+			gc.logger.Error("failed to modify users before the deadline, giving up", "guildID", guildID, "connectCode", connectCode, "attempts", attempt+1, "err", err)
+			return nil
+		}
+		backoff *= 2
+
+		if lock != nil {
+			if ttl, err := lock.TTL(context.Background()); err != nil || ttl <= 0 {
+				gc.logger.Warn("lost the voice lock, not retrying", "guildID", guildID, "connectCode", connectCode)
+				return nil
+			}
+		}
+		if !gc.isLatestRequest(connectCode, seq) {
+			gc.logger.Warn("a newer request was issued, not retrying", "guildID", guildID, "connectCode", connectCode)
+			return nil
+		}
+	}
+}
+
+// startRequest marks a new mute request as the latest one of the game, and returns its sequence number
+func (gc *GalactusClient) startRequest(connectCode string) uint64 {
+	gc.requestsLock.Lock()
+	defer gc.requestsLock.Unlock()
+	gc.requestSeq++
+	gc.latestRequests[connectCode] = gc.requestSeq
+	return gc.requestSeq
+}
+
+func (gc *GalactusClient) isLatestRequest(connectCode string, seq uint64) bool {
+	gc.requestsLock.Lock()
+	defer gc.requestsLock.Unlock()
+	return gc.latestRequests[connectCode] == seq
+}
 
-	resp, err := gc.client.Post(fullURL, "application/json", bytes.NewBuffer(jBytes))
+func (gc *GalactusClient) finishRequest(connectCode string, seq uint64) {
+	gc.requestsLock.Lock()
+	defer gc.requestsLock.Unlock()
+	if gc.latestRequests[connectCode] == seq {
+		delete(gc.latestRequests, connectCode)
+	}
+}
+
+// modifyUsers sends a single modify request to Galactus, and reports whether it's worth retrying if it failed
+func (gc *GalactusClient) modifyUsers(ctx context.Context, fullURL string, jBytes []byte, transition string) (*task.MuteDeafenSuccessCounts, bool, error) {
+	attemptCtx, cancel := context.WithTimeout(ctx, GalactusAttemptTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(attemptCtx, http.MethodPost, fullURL, bytes.NewBuffer(jBytes))
 	if err != nil {
-		return nil
+		return nil, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := gc.client.Do(req)
+	metrics.ObserveSince(metrics.GalactusModifyDuration, transition, start)
+	if err != nil {
+		return nil, true, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil
+		retry := resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+		return nil, retry, fmt.Errorf("%d response from modifying users", resp.StatusCode)
 	}
 
 	mds := task.MuteDeafenSuccessCounts{}
 	jBytes, err = ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.Println(err)
-		return &mds
+		gc.logger.Error("failed to read the modify response", "err", err)
+		return &mds, false, nil
 	}
 	err = json.Unmarshal(jBytes, &mds)
 	if err != nil {
-		log.Println(err)
-		return &mds
+		gc.logger.Error("invalid modify response", "err", err)
 	}
-	return &mds
+	return &mds, false, nil
 }
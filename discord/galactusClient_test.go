@@ -0,0 +1,140 @@
+package discord
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/automuteus/automuteus/logging"
+	"github.com/automuteus/automuteus/metrics"
+	"github.com/automuteus/utils/pkg/task"
+)
+
+func testGalactusClient(handler http.HandlerFunc) (*GalactusClient, *httptest.Server) {
+	server := httptest.NewServer(handler)
+	return &GalactusClient{
+		Address: server.URL,
+		client:  server.Client(),
+		logger:  logging.New(ioutil.Discard, logging.LevelError, logging.FormatLogfmt),
+		breaker: newCircuitBreaker(GalactusFailureThreshold, GalactusBreakerCooldown),
+
+		latestRequests: make(map[string]uint64),
+	}, server
+}
+
+func TestModifyUsersRetries(t *testing.T) {
+	var requests int32
+	gc, server := testGalactusClient(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"official":1}`))
+	})
+	defer server.Close()
+
+	mds := gc.ModifyUsers("1", "ABCDEFGH", task.UserModifyRequest{}, nil, metrics.NoTransition)
+	if mds == nil || mds.Official != 1 {
+		t.Error("the request should succeed after retrying", mds)
+	}
+	if requests != 3 {
+		t.Error("expected 3 requests, got", requests)
+	}
+	if gc.Degraded() {
+		t.Error("the breaker shouldn't open after a success")
+	}
+}
+
+func TestModifyUsersBadRequest(t *testing.T) {
+	var requests int32
+	gc, server := testGalactusClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	defer server.Close()
+
+	if gc.ModifyUsers("1", "ABCDEFGH", task.UserModifyRequest{}, nil, metrics.NoTransition) != nil {
+		t.Error("a bad request should fail")
+	}
+	if requests != 1 {
+		t.Error("bad requests shouldn't be retried")
+	}
+}
+
+func TestModifyUsersStopsWithTheLock(t *testing.T) {
+	var requests int32
+	gc, server := testGalactusClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	defer server.Close()
+
+	lock := NewMemoryGameStateStore().LockVoiceChanges("ABCDEFGH", 300*time.Millisecond)
+	start := time.Now()
+	if gc.ModifyUsers("1", "ABCDEFGH", task.UserModifyRequest{}, lock, metrics.NoTransition) != nil {
+		t.Error("the request should fail")
+	}
+	if time.Since(start) > time.Second {
+		t.Error("retrying shouldn't outlast the voice lock", time.Since(start))
+	}
+	if requests > GalactusRetries {
+		t.Error("retrying should stop once the lock expires, got", requests, "requests")
+	}
+}
+
+func TestModifyUsersNewerRequest(t *testing.T) {
+	var requests int32
+	var gc *GalactusClient
+	gc, server := testGalactusClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		// another request for the same game comes in while this one fails
+		gc.startRequest("ABCDEFGH")
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	defer server.Close()
+
+	if gc.ModifyUsers("1", "ABCDEFGH", task.UserModifyRequest{}, nil, metrics.NoTransition) != nil {
+		t.Error("the request should fail")
+	}
+	if requests != 1 {
+		t.Error("an outdated request shouldn't be retried, got", requests, "requests")
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	changes := make(chan bool, 2)
+	cb := newCircuitBreaker(2, 50*time.Millisecond)
+	cb.onChange = func(open bool) {
+		changes <- open
+	}
+
+	cb.failure()
+	if !cb.allow() || cb.isOpen() {
+		t.Error("the breaker shouldn't open before the threshold")
+	}
+	cb.failure()
+	if cb.allow() || !cb.isOpen() {
+		t.Error("the breaker should open at the threshold")
+	}
+	if open := <-changes; !open {
+		t.Error("opening the breaker should be reported")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !cb.allow() {
+		t.Error("a request should be let through after the cooldown")
+	}
+	if cb.allow() {
+		t.Error("only one request should be let through while trying again")
+	}
+	cb.success()
+	if !cb.allow() || cb.isOpen() {
+		t.Error("the breaker should close after a success")
+	}
+	if open := <-changes; open {
+		t.Error("closing the breaker should be reported")
+	}
+}
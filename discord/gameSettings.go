@@ -0,0 +1,139 @@
+package discord
+
+import (
+	"encoding/json"
+
+	"github.com/automuteus/automuteus/discord/setting"
+	"github.com/automuteus/automuteus/logging"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/game"
+)
+
+// GameSettingTypes are the settings that can be changed for a single game, with `game set`
+var GameSettingTypes = []setting.SettingType{
+	setting.UnmuteDead,
+	setting.Delays,
+	setting.VoiceRules,
+	setting.MuteSpectators,
+	setting.MatchSummary,
+	setting.MatchSummaryChannel,
+}
+
+// GameSettings are the settings of a single game, over the ones of the guild. Only the settings that were changed for
+// the game are set; the others keep following the guild settings, even if they change during the game
+type GameSettings struct {
+	// Mode is the name of the voice preset used for this game instead of the guild's voice settings, if any
+	Mode string `json:"mode,omitempty"`
+
+	VoiceRules               *game.VoiceRules `json:"voiceRules,omitempty"`
+	Delays                   *game.GameDelays `json:"delays,omitempty"`
+	UnmuteDeadDuringTasks    *bool            `json:"unmuteDeadDuringTasks,omitempty"`
+	MuteSpectator            *bool            `json:"muteSpectator,omitempty"`
+	DeleteGameSummaryMinutes *int             `json:"deleteGameSummaryMinutes,omitempty"`
+	MatchSummaryChannelID    *string          `json:"matchSummaryChannelID,omitempty"`
+}
+
+func isGameSetting(settType setting.SettingType) bool {
+	for _, t := range GameSettingTypes {
+		if t == settType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEmpty is true when the game follows the guild settings entirely
+func (gs *GameSettings) IsEmpty() bool {
+	return gs.Mode == "" && gs.VoiceRules == nil && gs.Delays == nil && gs.UnmuteDeadDuringTasks == nil &&
+		gs.MuteSpectator == nil && gs.DeleteGameSummaryMinutes == nil && gs.MatchSummaryChannelID == nil
+}
+
+// Set copies the value of the setting from sett (the settings of the game, once changed) into the game settings
+func (gs *GameSettings) Set(settType setting.SettingType, sett *storage.GuildSettings) {
+	// the rules and delays are maps; don't share them with sett
+	cpy := copyGuildSettings(sett)
+	switch settType {
+	case setting.UnmuteDead:
+		gs.UnmuteDeadDuringTasks = &cpy.UnmuteDeadDuringTasks
+	case setting.Delays:
+		gs.Delays = &cpy.Delays
+	case setting.VoiceRules:
+		gs.VoiceRules = &cpy.VoiceRules
+	case setting.MuteSpectators:
+		gs.MuteSpectator = &cpy.MuteSpectator
+	case setting.MatchSummary:
+		gs.DeleteGameSummaryMinutes = &cpy.DeleteGameSummaryMinutes
+	case setting.MatchSummaryChannel:
+		gs.MatchSummaryChannelID = &cpy.MatchSummaryChannelID
+	}
+}
+
+// Clear makes the setting follow the guild settings again
+func (gs *GameSettings) Clear(settType setting.SettingType) {
+	switch settType {
+	case setting.UnmuteDead:
+		gs.UnmuteDeadDuringTasks = nil
+	case setting.Delays:
+		gs.Delays = nil
+	case setting.VoiceRules:
+		gs.VoiceRules = nil
+	case setting.MuteSpectators:
+		gs.MuteSpectator = nil
+	case setting.MatchSummary:
+		gs.DeleteGameSummaryMinutes = nil
+	case setting.MatchSummaryChannel:
+		gs.MatchSummaryChannelID = nil
+	}
+}
+
+// apply changes sett to the settings of the game: the voice preset of the mode first (if it still exists), then the
+// settings changed for the game. Only the preset is applied if the game's settings can't be copied
+func (gs *GameSettings) apply(sett *storage.GuildSettings) error {
+	if gs.Mode != "" {
+		if preset, ok := sett.GetVoicePreset(gs.Mode); ok {
+			sett.ApplyVoicePreset(preset)
+		}
+	}
+
+	// deep-copy the rules and delays, so the game's own can't be changed through sett
+	var cpy GameSettings
+	jBytes, err := json.Marshal(gs)
+	if err == nil {
+		err = json.Unmarshal(jBytes, &cpy)
+	}
+	if err != nil {
+		return err
+	}
+	if cpy.VoiceRules != nil {
+		sett.VoiceRules = *cpy.VoiceRules
+	}
+	if cpy.Delays != nil {
+		sett.Delays = *cpy.Delays
+	}
+	if cpy.UnmuteDeadDuringTasks != nil {
+		sett.UnmuteDeadDuringTasks = *cpy.UnmuteDeadDuringTasks
+	}
+	if cpy.MuteSpectator != nil {
+		sett.MuteSpectator = *cpy.MuteSpectator
+	}
+	if cpy.DeleteGameSummaryMinutes != nil {
+		sett.DeleteGameSummaryMinutes = *cpy.DeleteGameSummaryMinutes
+	}
+	if cpy.MatchSummaryChannelID != nil {
+		sett.MatchSummaryChannelID = *cpy.MatchSummaryChannelID
+	}
+	return nil
+}
+
+// gameSettings are the guild settings with the settings of the game merged over them, so changing the settings of a
+// game doesn't change the guild's own settings
+func (dgs *GameState) gameSettings(logger *logging.Logger, sett *storage.GuildSettings) *storage.GuildSettings {
+	if dgs == nil || dgs.Settings.IsEmpty() {
+		return sett
+	}
+	gameSett := copyGuildSettings(sett)
+	if err := dgs.Settings.apply(gameSett); err != nil {
+		logger.Error("failed to apply the settings of the game", "err", err)
+	}
+	return gameSett
+}
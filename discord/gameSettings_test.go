@@ -0,0 +1,58 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/automuteus/automuteus/discord/setting"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/game"
+)
+
+func TestGameSettings(t *testing.T) {
+	sett := storage.MakeGuildSettings("")
+	dgs := NewDiscordGameState("1")
+	if dgs.gameSettings(nil, sett) != sett {
+		t.Error("A game without settings should use the guild settings")
+	}
+
+	// the mode's preset is applied first, then the settings changed for the game
+	dgs.Settings.Mode = "ghosts-talk"
+	gameSett := copyGuildSettings(dgs.gameSettings(nil, sett))
+	if !gameSett.UnmuteDeadDuringTasks || !gameSett.MuteSpectator {
+		t.Fatal("The preset of the mode should be applied")
+	}
+	gameSett.MuteSpectator = false
+	gameSett.Delays.Delays[game.PhaseNames[game.LOBBY]][game.PhaseNames[game.TASKS]] = 9
+	dgs.Settings.Set(setting.MuteSpectators, gameSett)
+	dgs.Settings.Set(setting.Delays, gameSett)
+
+	// changing the settings the overlay was taken from doesn't change the game's
+	gameSett.Delays.Delays[game.PhaseNames[game.LOBBY]][game.PhaseNames[game.TASKS]] = 1
+
+	merged := dgs.gameSettings(nil, sett)
+	if !merged.UnmuteDeadDuringTasks || merged.MuteSpectator {
+		t.Error("The settings of the game should be merged over the preset", merged.UnmuteDeadDuringTasks, merged.MuteSpectator)
+	}
+	if delay := merged.Delays.GetDelay(game.LOBBY, game.TASKS); delay != 9 {
+		t.Error("Expected the delay of the game, got", delay)
+	}
+	if sett.MuteSpectator || sett.UnmuteDeadDuringTasks || sett.Delays.GetDelay(game.LOBBY, game.TASKS) == 9 {
+		t.Error("The guild settings shouldn't change")
+	}
+
+	// changing the merged settings doesn't change the game's either
+	merged.Delays.Delays[game.PhaseNames[game.LOBBY]][game.PhaseNames[game.TASKS]] = 2
+	if delay := dgs.gameSettings(nil, sett).Delays.GetDelay(game.LOBBY, game.TASKS); delay != 9 {
+		t.Error("Expected the delay of the game, got", delay)
+	}
+
+	dgs.Settings.Clear(setting.MuteSpectators)
+	if !dgs.gameSettings(nil, sett).MuteSpectator {
+		t.Error("A cleared setting should follow the preset again")
+	}
+
+	dgs.Reset()
+	if !dgs.Settings.IsEmpty() {
+		t.Error("The settings of the game should be discarded with it")
+	}
+}
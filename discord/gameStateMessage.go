@@ -41,13 +41,6 @@ func (dgs *GameState) RemoveAllReactions(s *discordgo.Session) {
 	}
 }
 
-func (dgs *GameState) AddAllReactions(s *discordgo.Session, emojis []Emoji) {
-	for _, e := range emojis {
-		dgs.AddReaction(s, e.FormatForReaction())
-	}
-	dgs.AddReaction(s, "❌")
-}
-
 func (dgs *GameState) DeleteGameStateMsg(s *discordgo.Session) {
 	if dgs.GameStateMsg.MessageID != "" {
 		deleteMessage(s, dgs.GameStateMsg.MessageChannelID, dgs.GameStateMsg.MessageID)
@@ -55,11 +48,11 @@ func (dgs *GameState) DeleteGameStateMsg(s *discordgo.Session) {
 	}
 }
 
-var DeferredEdits = make(map[string]*discordgo.MessageEmbed)
+var DeferredEdits = make(map[string]*discordgo.MessageEdit)
 var DeferredEditsLock = sync.Mutex{}
 
 // Note this is not a pointer; we never expect the underlying DGS to change on an edit
-func (dgs GameState) Edit(s *discordgo.Session, me *discordgo.MessageEmbed) bool {
+func (dgs GameState) Edit(s *discordgo.Session, me *discordgo.MessageEmbed, components []discordgo.MessageComponent) bool {
 	newEdit := false
 
 	if !ValidFields(me) {
@@ -74,7 +67,11 @@ func (dgs GameState) Edit(s *discordgo.Session, me *discordgo.MessageEmbed) bool
 		newEdit = true
 	}
 	// whether or not it's found, replace the contents with the new message
-	DeferredEdits[dgs.GameStateMsg.MessageID] = me
+	// (components are always resent; an edit without them would remove them from the message)
+	DeferredEdits[dgs.GameStateMsg.MessageID] = &discordgo.MessageEdit{
+		Embeds:     []*discordgo.MessageEmbed{me},
+		Components: components,
+	}
 	DeferredEditsLock.Unlock()
 	return newEdit
 }
@@ -101,18 +98,23 @@ func deferredEditWorker(s *discordgo.Session, channelID, messageID string) {
 	time.Sleep(time.Second * time.Duration(DeferredEditSeconds))
 
 	DeferredEditsLock.Lock()
-	me := DeferredEdits[messageID]
+	edit := DeferredEdits[messageID]
 	delete(DeferredEdits, messageID)
 	DeferredEditsLock.Unlock()
 
-	if me != nil {
-		editMessageEmbed(s, channelID, messageID, me)
+	if edit != nil {
+		edit.Channel = channelID
+		edit.ID = messageID
+		editMessageComplex(s, edit)
 	}
 }
 
-func (dgs *GameState) CreateMessage(s *discordgo.Session, me *discordgo.MessageEmbed, channelID string, authorID string) {
+func (dgs *GameState) CreateMessage(s *discordgo.Session, me *discordgo.MessageEmbed, components []discordgo.MessageComponent, channelID string, authorID string) {
 	dgs.GameStateMsg.LeaderID = authorID
-	msg := sendMessageEmbed(s, channelID, me)
+	msg := sendMessageComplex(s, channelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{me},
+		Components: components,
+	})
 	if msg != nil {
 		dgs.GameStateMsg.MessageAuthorID = msg.Author.ID
 		dgs.GameStateMsg.MessageChannelID = msg.ChannelID
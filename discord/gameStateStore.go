@@ -0,0 +1,55 @@
+package discord
+
+import (
+	"context"
+	"time"
+)
+
+// Lock is obtained before modifying a game state, and is released by SetDiscordGameState
+type Lock interface {
+	Release(ctx context.Context) error
+	// TTL returns how long the lock is still held for, or 0 if it expired (or was released)
+	TTL(ctx context.Context) (time.Duration, error)
+}
+
+// GameStateStore holds the state of every game, the pointers used to find a game by its connect code, text
+// channel or voice channel, the cache of username<->userID links, and the set of active games per guild.
+// RedisInterface is the implementation used by the bot, as the capture events reach it through Redis anyway;
+// MemoryGameStateStore keeps everything in-process, for tests and replays
+type GameStateStore interface {
+	// GetReadOnlyDiscordGameState fetches a game state without locking it; changes to it must not be saved
+	GetReadOnlyDiscordGameState(gsr GameStateRequest) *GameState
+	// GetDiscordGameStateAndLock returns a nil Lock if the game state is already locked
+	GetDiscordGameStateAndLock(gsr GameStateRequest) (Lock, *GameState)
+	// SetDiscordGameState saves the game state (and its pointers), and releases the lock if provided
+	SetDiscordGameState(data *GameState, lock Lock)
+	DeleteDiscordGameState(dgs *GameState)
+	CheckPointer(pointer string) string
+
+	LockVoiceChanges(connectCode string, dur time.Duration) Lock
+	LockSnowflake(snowflake string) Lock
+
+	GetUsernameOrUserIDMappings(guildID, key string) map[string]interface{}
+	AddUsernameLink(guildID, userID, userName string) error
+	DeleteLinksByUserID(guildID, userID string) error
+
+	RefreshActiveGame(guildID, connectCode string)
+	RemoveOldGame(guildID, connectCode string)
+	LoadAllActiveGames(guildID string) []string
+
+	// AddOrphanedGame marks a game as orphaned: its instance shut down, and it's waiting for another one to resume it
+	AddOrphanedGame(guildID, connectCode string)
+	// RemoveOrphanedGame returns true if the game was orphaned, which claims it for the caller
+	RemoveOrphanedGame(guildID, connectCode string) bool
+	LoadOrphanedGames() []GameStateRequest
+
+	// AcquireGameLease makes ownerID the only instance allowed to subscribe to the game, until the lease expires. It
+	// fails if another owner holds an unexpired lease, and extends the lease if ownerID already holds it
+	AcquireGameLease(gsr GameStateRequest, ownerID string, ttl time.Duration) bool
+	// RenewGameLease extends the lease, as long as ownerID still holds it
+	RenewGameLease(connectCode, ownerID string, ttl time.Duration) bool
+	// ReleaseGameLease releases the lease if ownerID holds it (or if it expired), and forgets about the game
+	ReleaseGameLease(connectCode, ownerID string)
+	// LoadExpiredGameLeases returns the games whose owner stopped renewing their lease without releasing it
+	LoadExpiredGameLeases() []GameStateRequest
+}
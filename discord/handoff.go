@@ -0,0 +1,162 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	// OrphanedGamesInterval is how often every instance checks for orphaned games (or expired leases) to resume
+	OrphanedGamesInterval = 5 * time.Second
+	// GameLeaseTTL is how long an instance owns the subscription to a game without renewing its lease. If the
+	// instance dies, another one takes over the game once the lease expires
+	GameLeaseTTL = 30 * time.Second
+	// GracefulCloseTimeout is how long closing waits for the game subscriptions to be handed off
+	GracefulCloseTimeout = 10 * time.Second
+)
+
+const (
+	// EndGame stops the subscription to a game, and ends the game entirely
+	EndGame EndGameMessage = true
+	// OrphanGame stops the subscription to a game, but leaves it running for another instance to resume
+	OrphanGame EndGameMessage = false
+)
+
+// instanceID identifies this instance as the owner of game leases. Hostnames are unique per pod/container, and the
+// PID tells apart instances sharing a host
+func instanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Println(err)
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+func (bot *Bot) isClosing() bool {
+	bot.ChannelsMapLock.RLock()
+	defer bot.ChannelsMapLock.RUnlock()
+	return bot.closing
+}
+
+// GracefulClose stops accepting new games, and hands off the games this instance is subscribed to. They're marked as
+// orphaned, so another instance (or this one, once it restarts) resumes them right where they left off, instead of
+// leaving the players muted until the game times out
+func (bot *Bot) GracefulClose() {
+	bot.ChannelsMapLock.Lock()
+	bot.closing = true
+	channels := bot.EndGameChannels
+	bot.EndGameChannels = make(map[string]chan EndGameMessage)
+	bot.ChannelsMapLock.Unlock()
+
+	bot.logger.Info("handing off games to other instances", "games", len(channels))
+	// unlike a timer channel, the context stays done, so every send (and the final wait) sees the deadline
+	closeCtx, cancel := context.WithTimeout(ctx, GracefulCloseTimeout)
+	defer cancel()
+	for connectCode, killChan := range channels {
+		select {
+		case killChan <- OrphanGame:
+		case <-closeCtx.Done():
+			bot.logger.Warn("timed out handing off the game", "connectCode", connectCode)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		bot.subscriptions.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		bot.logger.Info("handed off all games")
+	case <-closeCtx.Done():
+		bot.logger.Warn("timed out waiting for the games to be handed off")
+	}
+}
+
+// orphanGame marks a game as no longer subscribed to, and waiting for an instance to resume it
+func (bot *Bot) orphanGame(gsr GameStateRequest) {
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
+	for lock == nil {
+		lock, dgs = bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
+	}
+	dgs.Subscribed = false
+	bot.GameStateStore.SetDiscordGameState(dgs, lock)
+
+	bot.GameStateStore.AddOrphanedGame(gsr.GuildID, gsr.ConnectCode)
+	bot.logger.Info("orphaned the game", "guildID", gsr.GuildID, "connectCode", gsr.ConnectCode)
+}
+
+// resumeGame resubscribes to the events of a game that another instance (or this one, before restarting) was
+// subscribed to. If onlyOrphaned, the game is only resumed if it was orphaned, and this instance claimed it
+func (bot *Bot) resumeGame(gsr GameStateRequest, onlyOrphaned bool) {
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
+	for lock == nil {
+		lock, dgs = bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
+	}
+	claimed := bot.GameStateStore.RemoveOrphanedGame(gsr.GuildID, gsr.ConnectCode)
+
+	bot.ChannelsMapLock.Lock()
+	_, subscribed := bot.EndGameChannels[gsr.ConnectCode]
+	if dgs == nil || dgs.ConnectCode == "" || subscribed || bot.closing || (onlyOrphaned && !claimed) {
+		bot.ChannelsMapLock.Unlock()
+		if claimed && bot.closing {
+			// leave it for another instance
+			bot.GameStateStore.AddOrphanedGame(gsr.GuildID, gsr.ConnectCode)
+		}
+		if dgs == nil || dgs.ConnectCode == "" {
+			// the game is gone, so its lease doesn't need taking over anymore
+			bot.GameStateStore.ReleaseGameLease(gsr.ConnectCode, bot.nodeID)
+		}
+		lock.Release(ctx)
+		return
+	}
+	killChan := make(chan EndGameMessage)
+	bot.EndGameChannels[dgs.ConnectCode] = killChan
+	// added while we know the bot isn't closing, so GracefulClose can't be waiting already
+	bot.subscriptions.Add(1)
+	bot.ChannelsMapLock.Unlock()
+
+	bot.logger.Info("resubscribing to Redis events for an old game", "guildID", gsr.GuildID, "connectCode", gsr.ConnectCode)
+	go bot.SubscribeToGameByConnectCode(gsr.GuildID, dgs.ConnectCode, killChan)
+	dgs.Subscribed = true
+
+	bot.GameStateStore.SetDiscordGameState(dgs, lock)
+}
+
+// stopSubscription forgets about the subscription to a game, unless the game was resubscribed to since
+func (bot *Bot) stopSubscription(connectCode string, endGameChannel chan EndGameMessage) {
+	bot.ChannelsMapLock.Lock()
+	if bot.EndGameChannels[connectCode] == endGameChannel {
+		delete(bot.EndGameChannels, connectCode)
+	}
+	bot.ChannelsMapLock.Unlock()
+}
+
+// orphanedGamesWorker resumes the orphaned games of the guilds this instance is connected to, as well as the games
+// whose owner died without handing them off. Leases make sure only one instance takes over each game
+func (bot *Bot) orphanedGamesWorker(dur time.Duration) {
+	for {
+		time.Sleep(dur)
+		if bot.isClosing() {
+			return
+		}
+		for _, gsr := range bot.GameStateStore.LoadOrphanedGames() {
+			if _, err := bot.PrimarySession.State.Guild(gsr.GuildID); err != nil {
+				// another shard's guild
+				continue
+			}
+			bot.resumeGame(gsr, true)
+		}
+		for _, gsr := range bot.GameStateStore.LoadExpiredGameLeases() {
+			if _, err := bot.PrimarySession.State.Guild(gsr.GuildID); err != nil {
+				continue
+			}
+			bot.logger.Info("taking over the game after its lease expired", "guildID", gsr.GuildID, "connectCode", gsr.ConnectCode)
+			bot.resumeGame(gsr, false)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/automuteus/automuteus/metrics"
+)
+
+// DiscordHeartbeatTimeout is how long the gateway can go without acknowledging a heartbeat before the bot is considered
+// dead. discordgo reconnects on its own well before this, so it only happens if reconnecting is stuck
+const DiscordHeartbeatTimeout = 3 * time.Minute
+
+// healthChecks are the dependencies the bot needs to run games
+func (bot *Bot) healthChecks() []metrics.HealthCheck {
+	checks := []metrics.HealthCheck{
+		{
+			Name: "redis",
+			Check: func(ctx context.Context) error {
+				return bot.RedisInterface.client.Ping(ctx).Err()
+			},
+		},
+		{
+			Name: "postgres",
+			Check: func(ctx context.Context) error {
+				return bot.PostgresInterface.Pool.Ping(ctx)
+			},
+		},
+		{
+			Name:  "discordSession",
+			Check: bot.checkDiscordSession,
+		},
+		{
+			Name:  "discordHeartbeat",
+			Live:  true,
+			Check: bot.checkDiscordHeartbeat,
+		},
+	}
+	if gc, ok := bot.VoiceModifier.(*GalactusClient); ok {
+		checks = append(checks, metrics.HealthCheck{
+			Name:  "galactus",
+			Check: gc.Ping,
+		})
+	}
+	return checks
+}
+
+func (bot *Bot) checkDiscordSession(_ context.Context) error {
+	bot.PrimarySession.RLock()
+	defer bot.PrimarySession.RUnlock()
+	if !bot.PrimarySession.DataReady {
+		return errors.New("not connected to the Discord gateway")
+	}
+	return nil
+}
+
+func (bot *Bot) checkDiscordHeartbeat(_ context.Context) error {
+	bot.PrimarySession.RLock()
+	defer bot.PrimarySession.RUnlock()
+	if since := time.Since(bot.PrimarySession.LastHeartbeatAck); since > DiscordHeartbeatTimeout {
+		return fmt.Errorf("no heartbeat acknowledged by the Discord gateway in %s", since.Round(time.Second))
+	}
+	return nil
+}
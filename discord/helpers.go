@@ -88,6 +88,22 @@ func editMessageEmbed(s *discordgo.Session, channelID string, messageID string,
 	return msg
 }
 
+func sendMessageComplex(s *discordgo.Session, channelID string, message *discordgo.MessageSend) *discordgo.Message {
+	msg, err := s.ChannelMessageSendComplex(channelID, message)
+	if err != nil {
+		log.Println(err)
+	}
+	return msg
+}
+
+func editMessageComplex(s *discordgo.Session, message *discordgo.MessageEdit) *discordgo.Message {
+	msg, err := s.ChannelMessageEditComplex(message)
+	if err != nil {
+		log.Println(err)
+	}
+	return msg
+}
+
 func deleteMessage(s *discordgo.Session, channelID string, messageID string) {
 	err := s.ChannelMessageDelete(channelID, messageID)
 	if err != nil {
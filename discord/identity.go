@@ -0,0 +1,195 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/discord"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// linkIdentity records that the user is the player with the in-game name, unless they opted out of data collection. If
+// other users claim the same name, it's logged; whois shows them as well
+func (bot *Bot) linkIdentity(guildID, userID, inGameName string, confidence storage.LinkConfidence) {
+	if bot.IdentityInterface == nil || userID == "" || inGameName == "" || inGameName == amongus.UnlinkedPlayerName {
+		return
+	}
+	conflicts, err := bot.IdentityInterface.AddLink(storage.IdentityLink{
+		GuildID:    guildID,
+		UserID:     userID,
+		InGameName: inGameName,
+		Confidence: confidence,
+	})
+	if err != nil {
+		bot.logger.Error("failed to store the identity link", "guildID", guildID, "userID", userID, "err", err)
+		return
+	}
+	for _, conflict := range conflicts {
+		bot.logger.Warn("in-game name claimed by multiple users", "guildID", guildID, "name", inGameName,
+			"userID", userID, "confidence", confidence.String(), "otherUserID", conflict.UserID, "otherConfidence", conflict.Confidence.String())
+	}
+}
+
+// bestIdentityLink returns the user of the game the links are most confident about. When several users of the game
+// are linked to the name as confidently, it's ambiguous and nobody is returned
+func bestIdentityLink(links []storage.IdentityLink, userData UserDataSet) string {
+	best := ""
+	var bestConfidence storage.LinkConfidence
+	ambiguous := false
+	for _, link := range links {
+		if _, ok := userData[link.UserID]; !ok || link.UserID == best {
+			continue
+		}
+		switch {
+		case link.Confidence > bestConfidence:
+			best, bestConfidence, ambiguous = link.UserID, link.Confidence, false
+		case link.Confidence == bestConfidence:
+			ambiguous = true
+		}
+	}
+	if ambiguous {
+		return ""
+	}
+	return best
+}
+
+// attemptPairingByLinks pairs the player using the stored identity links, and the cached usernames if none of the
+// users in the game are linked to them
+func (bot *Bot) attemptPairingByLinks(dgs *GameState, data amongus.PlayerData) string {
+	if bot.IdentityInterface != nil {
+		links, err := bot.IdentityInterface.GetLinksByName(dgs.GuildID, data.Name)
+		if err != nil {
+			bot.logger.Error("failed to get the identity links", "guildID", dgs.GuildID, "name", data.Name, "err", err)
+		} else if userID := bestIdentityLink(links, dgs.UserData); userID != "" {
+			return dgs.AttemptPairingByUserIDs(data, map[string]interface{}{userID: ""})
+		}
+	}
+	uids := bot.GameStateStore.GetUsernameOrUserIDMappings(dgs.GuildID, data.Name)
+	return dgs.AttemptPairingByUserIDs(data, uids)
+}
+
+func (bot *Bot) deleteIdentityLinks(guildID, userID string) error {
+	if bot.IdentityInterface == nil {
+		return nil
+	}
+	return bot.IdentityInterface.DeleteLinksByUserID(guildID, userID)
+}
+
+// deleteAllIdentityLinks deletes the links of the user in every guild; opting out isn't specific to a guild
+func (bot *Bot) deleteAllIdentityLinks(userID string) error {
+	if bot.IdentityInterface == nil {
+		return nil
+	}
+	return bot.IdentityInterface.DeleteAllLinksByUserID(userID)
+}
+
+func commandFnWhois(
+	bot *Bot,
+	_ bool,
+	_ bool,
+	sett *storage.GuildSettings,
+	_ *discordgo.Guild,
+	message *discordgo.MessageCreate,
+	args []string,
+	cmd *Command,
+) (string, interface{}) {
+	if len(args[1:]) == 0 {
+		return message.ChannelID, ConstructEmbedForCommand(*cmd, sett)
+	}
+	if bot.IdentityInterface == nil {
+		return message.ChannelID, sett.LocalizeMessage(&i18n.Message{
+			ID:    "identity.commandFnWhois.unavailable",
+			Other: "Sorry, linked identities aren't available right now",
+		})
+	}
+
+	userID, err := discord.ExtractUserIDFromMention(args[1])
+	if err == nil && userID != "" {
+		links, err := bot.IdentityInterface.GetLinksByUserID(message.GuildID, userID)
+		if err != nil {
+			bot.logger.Error("failed to get the identity links", "guildID", message.GuildID, "userID", userID, "err", err)
+			return message.ChannelID, err.Error()
+		}
+		if len(links) == 0 {
+			return message.ChannelID, sett.LocalizeMessage(&i18n.Message{
+				ID:    "identity.commandFnWhois.noNames",
+				Other: "{{.User}} isn't linked to any in-game name",
+			},
+				map[string]interface{}{
+					"User": discord.MentionByUserID(userID),
+				})
+		}
+		buf := bytes.NewBuffer([]byte(sett.LocalizeMessage(&i18n.Message{
+			ID:    "identity.commandFnWhois.names",
+			Other: "In-game names linked to {{.User}}:",
+		},
+			map[string]interface{}{
+				"User": discord.MentionByUserID(userID),
+			})))
+		buf.WriteString("\n")
+		for _, link := range links {
+			claims, err := bot.IdentityInterface.GetLinksByName(message.GuildID, link.InGameName)
+			if err != nil {
+				bot.logger.Error("failed to get the identity links", "guildID", message.GuildID, "name", link.InGameName, "err", err)
+			}
+			buf.WriteString(identityLinkLine(sett, fmt.Sprintf("`%s`", link.InGameName), link, len(claims) > 1))
+		}
+		return message.ChannelID, buf.String()
+	}
+
+	// in-game names can have spaces
+	name := strings.Join(args[1:], " ")
+	links, err := bot.IdentityInterface.GetLinksByName(message.GuildID, name)
+	if err != nil {
+		bot.logger.Error("failed to get the identity links", "guildID", message.GuildID, "name", name, "err", err)
+		return message.ChannelID, err.Error()
+	}
+	if len(links) == 0 {
+		return message.ChannelID, sett.LocalizeMessage(&i18n.Message{
+			ID:    "identity.commandFnWhois.noUsers",
+			Other: "Nobody is linked to `{{.Name}}`",
+		},
+			map[string]interface{}{
+				"Name": name,
+			})
+	}
+	buf := bytes.NewBuffer([]byte(sett.LocalizeMessage(&i18n.Message{
+		ID:    "identity.commandFnWhois.users",
+		Other: "Users linked to `{{.Name}}`:",
+	},
+		map[string]interface{}{
+			"Name": name,
+		})))
+	buf.WriteString("\n")
+	for _, link := range links {
+		buf.WriteString(identityLinkLine(sett, discord.MentionByUserID(link.UserID), link, len(links) > 1))
+	}
+	return message.ChannelID, buf.String()
+}
+
+// identityLinkLine describes one link, flagging it if the name is claimed by several users
+func identityLinkLine(sett *storage.GuildSettings, subject string, link storage.IdentityLink, conflict bool) string {
+	line := sett.LocalizeMessage(&i18n.Message{
+		ID:    "identity.identityLinkLine.link",
+		Other: "{{.Subject}}: {{.Confidence}}, last seen <t:{{.LastSeen}}:R>",
+	},
+		map[string]interface{}{
+			"Subject":    subject,
+			"Confidence": link.Confidence.String(),
+			"LastSeen":   link.LastSeen,
+		})
+	if link.FriendCode != "" {
+		line += fmt.Sprintf(" (%s)", link.FriendCode)
+	}
+	if conflict {
+		line += " " + sett.LocalizeMessage(&i18n.Message{
+			ID:    "identity.identityLinkLine.conflict",
+			Other: "⚠️ claimed by several users",
+		})
+	}
+	return line + "\n"
+}
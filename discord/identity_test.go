@@ -0,0 +1,34 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/automuteus/automuteus/storage"
+)
+
+func TestBestIdentityLink(t *testing.T) {
+	userData := UserDataSet{"1": UserData{}, "2": UserData{}, "3": UserData{}}
+	link := func(userID string, confidence storage.LinkConfidence) storage.IdentityLink {
+		return storage.IdentityLink{UserID: userID, InGameName: "Soup", Confidence: confidence}
+	}
+	tests := []struct {
+		links []storage.IdentityLink
+		want  string
+	}{
+		{nil, ""},
+		{[]storage.IdentityLink{link("1", storage.LinkByNameMatch)}, "1"},
+		// a manual link is trusted over a reaction, which is trusted over a name match
+		{[]storage.IdentityLink{link("1", storage.LinkManual), link("2", storage.LinkByReaction)}, "1"},
+		{[]storage.IdentityLink{link("2", storage.LinkByReaction), link("3", storage.LinkByNameMatch)}, "2"},
+		// as confident about two users of the game is ambiguous
+		{[]storage.IdentityLink{link("1", storage.LinkByReaction), link("2", storage.LinkByReaction)}, ""},
+		// users not in the game don't count
+		{[]storage.IdentityLink{link("4", storage.LinkManual), link("2", storage.LinkByReaction)}, "2"},
+		{[]storage.IdentityLink{link("4", storage.LinkManual)}, ""},
+	}
+	for _, test := range tests {
+		if got := bestIdentityLink(test.links, userData); got != test.want {
+			t.Error("Expected", test.want, "for", test.links, "got", got)
+		}
+	}
+}
@@ -0,0 +1,446 @@
+package discord
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	redis_common "github.com/automuteus/automuteus/common"
+	"github.com/automuteus/automuteus/metrics"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/discord"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	colorSelectID  = "gamestate-color"
+	unlinkButtonID = "gamestate-unlink"
+)
+
+// registerApplicationCommands registers every command as a global application (slash) command.
+// Bulk overwriting means commands that were removed from allCommands are also removed from Discord
+func (bot *Bot) registerApplicationCommands(s *discordgo.Session) {
+	cmds := applicationCommands(allCommands)
+	_, err := s.ApplicationCommandBulkOverwrite(s.State.User.ID, "", cmds)
+	if err != nil {
+		log.Println("error registering application commands:", err)
+		return
+	}
+	log.Printf("Registered %d application commands\n", len(cmds))
+}
+
+// applicationCommands builds the application commands to register for the commands
+func applicationCommands(commands []Command) []*discordgo.ApplicationCommand {
+	cmds := make([]*discordgo.ApplicationCommand, 0, len(commands))
+	for _, cmd := range commands {
+		options := cmd.Options
+		if options == nil {
+			// Discord expects an empty list, not null
+			options = []*discordgo.ApplicationCommandOption{}
+		}
+		cmds = append(cmds, &discordgo.ApplicationCommand{
+			Name:        cmd.Command,
+			Description: cmd.ShortDesc.Other,
+			Options:     options,
+		})
+	}
+	return cmds
+}
+
+func (bot *Bot) handleInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		bot.handleApplicationCommand(s, i)
+	case discordgo.InteractionMessageComponent:
+		customID := i.MessageComponentData().CustomID
+		if strings.HasPrefix(customID, settingsImportApplyID) || strings.HasPrefix(customID, settingsImportCancelID) {
+			bot.handleSettingsImportComponent(s, i)
+			return
+		}
+		if strings.HasPrefix(customID, settingsRollbackApplyID) || strings.HasPrefix(customID, settingsRollbackCancelID) {
+			bot.handleSettingsRollbackComponent(s, i)
+			return
+		}
+		if strings.HasPrefix(customID, scheduleRSVPID) {
+			bot.handleScheduleRSVPComponent(s, i)
+			return
+		}
+		bot.handleGameStateComponent(s, i)
+	}
+}
+
+func (bot *Bot) handleApplicationCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// commands are only supported within guilds, where Member is populated
+	if i.GuildID == "" || i.Member == nil || i.Member.User == nil {
+		return
+	}
+	user := i.Member.User
+
+	if redis_common.IsUserBanned(bot.RedisInterface.client, user.ID) {
+		return
+	}
+
+	g, err := s.State.Guild(i.GuildID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	sett := bot.StorageInterface.GetGuildSettings(i.GuildID)
+
+	data := i.ApplicationCommandData()
+	command, exists := getCommand(data.Name)
+	if !exists {
+		log.Printf("Received unknown application command \"%s\" from User %s\n", data.Name, user.ID)
+		return
+	}
+
+	if redis_common.IsUserRateLimitedGeneral(bot.RedisInterface.client, user.ID) {
+		banned := redis_common.IncrementRateLimitExceed(bot.RedisInterface.client, user.ID)
+		if banned {
+			respondEphemeral(s, i.Interaction, sett.LocalizeMessage(&i18n.Message{
+				ID:    "message_handlers.softban",
+				Other: "I'm ignoring {{.User}} for the next 5 minutes, stop spamming",
+			},
+				map[string]interface{}{
+					"User": discord.MentionByUserID(user.ID),
+				}))
+		} else {
+			respondEphemeral(s, i.Interaction, sett.LocalizeMessage(&i18n.Message{
+				ID:    "message_handlers.generalRatelimit",
+				Other: "{{.User}}, you're issuing commands too fast! Please slow down!",
+			},
+				map[string]interface{}{
+					"User": discord.MentionByUserID(user.ID),
+				}))
+		}
+		return
+	}
+	redis_common.MarkUserRateLimit(bot.RedisInterface.client, user.ID, "", 0)
+
+	isAdmin, isPermissioned := getPermissions(g, sett, user, i.Member)
+	if !command.isPermitted(isAdmin, isPermissioned) {
+		respondEphemeral(s, i.Interaction, noPermsResponse(sett))
+		return
+	}
+
+	flags := interactionResponseFlags(&command)
+
+	// defer the response; commands like new can take longer than the 3 seconds Discord allows
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: flags,
+		},
+	})
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// the command functions are shared with the prefix parser, so hand them the message they would have received
+	message := &discordgo.MessageCreate{
+		Message: &discordgo.Message{
+			ID:        i.ID,
+			ChannelID: i.ChannelID,
+			GuildID:   i.GuildID,
+			Author:    user,
+			Member:    i.Member,
+		},
+	}
+	// attachments aren't args; hand them over just like they'd be attached to a message
+	for _, opt := range data.Options {
+		if opt.Type == discordgo.ApplicationCommandOptionAttachment && data.Resolved != nil {
+			if attachment, ok := data.Resolved.Attachments[opt.Value.(string)]; ok {
+				message.Attachments = append(message.Attachments, attachment)
+			}
+		}
+	}
+	args := applicationCommandArgs(&command, data.Options)
+
+	_, msgToSend := command.fn(bot, isAdmin, isPermissioned, sett, g, message, args, &command)
+	bot.editInteractionResponse(s, i.Interaction, msgToSend, flags)
+}
+
+// interactionResponseFlags are the flags of the replies to a command; replies to ephemeral commands are only shown to
+// the User invoking them
+func interactionResponseFlags(cmd *Command) uint64 {
+	if cmd.IsEphemeral {
+		return uint64(discordgo.MessageFlagsEphemeral)
+	}
+	return 0
+}
+
+// applicationCommandArgs converts the typed options of an interaction back into the args the prefix parser
+// would produce, in the order the command declares them
+func applicationCommandArgs(cmd *Command, options []*discordgo.ApplicationCommandInteractionDataOption) []string {
+	return append([]string{cmd.Command}, optionArgs(cmd.Options, options)...)
+}
+
+// optionArgs converts the options in the order they're declared. A subcommand (or subcommand group) is an arg itself,
+// followed by its own options
+func optionArgs(declaredOptions []*discordgo.ApplicationCommandOption, options []*discordgo.ApplicationCommandInteractionDataOption) []string {
+	var args []string
+	for _, declared := range declaredOptions {
+		for _, opt := range options {
+			if opt.Name != declared.Name {
+				continue
+			}
+			switch opt.Type {
+			case discordgo.ApplicationCommandOptionSubCommand, discordgo.ApplicationCommandOptionSubCommandGroup:
+				args = append(args, opt.Name)
+				args = append(args, optionArgs(declared.Options, opt.Options)...)
+			case discordgo.ApplicationCommandOptionUser:
+				args = append(args, discord.MentionByUserID(opt.UserValue(nil).ID))
+			case discordgo.ApplicationCommandOptionChannel:
+				args = append(args, discord.MentionByChannelID(opt.ChannelValue(nil).ID))
+			case discordgo.ApplicationCommandOptionString:
+				args = append(args, strings.Fields(strings.ToLower(opt.StringValue()))...)
+			case discordgo.ApplicationCommandOptionInteger:
+				args = append(args, strconv.FormatInt(opt.IntValue(), 10))
+			case discordgo.ApplicationCommandOptionBoolean:
+				// booleans are flags, like "clear" or "reset"; they're only passed when set
+				if opt.BoolValue() {
+					args = append(args, opt.Name)
+				}
+			}
+		}
+	}
+	return args
+}
+
+func (bot *Bot) editInteractionResponse(s *discordgo.Session, interaction *discordgo.Interaction, msgToSend interface{}, flags uint64) {
+	appID := s.State.User.ID
+	msgsSent := int64(1)
+	var err error
+
+	switch msg := msgToSend.(type) {
+	case string:
+		_, err = s.InteractionResponseEdit(appID, interaction, &discordgo.WebhookEdit{
+			Content: msg,
+		})
+	case []string:
+		if len(msg) == 0 {
+			err = s.InteractionResponseDelete(appID, interaction)
+			break
+		}
+		_, err = s.InteractionResponseEdit(appID, interaction, &discordgo.WebhookEdit{
+			Content: msg[0],
+		})
+		for _, v := range msg[1:] {
+			if err != nil {
+				break
+			}
+			_, err = s.FollowupMessageCreate(appID, interaction, true, &discordgo.WebhookParams{
+				Content: v,
+				Flags:   flags,
+			})
+			msgsSent++
+		}
+	case discordgo.MessageEmbed:
+		_, err = s.InteractionResponseEdit(appID, interaction, &discordgo.WebhookEdit{
+			Embeds: []*discordgo.MessageEmbed{&msg},
+		})
+	case *discordgo.MessageEmbed:
+		_, err = s.InteractionResponseEdit(appID, interaction, &discordgo.WebhookEdit{
+			Embeds: []*discordgo.MessageEmbed{msg},
+		})
+	case *discordgo.MessageSend:
+		edit := &discordgo.WebhookEdit{
+			Content:    msg.Content,
+			Components: msg.Components,
+			Files:      msg.Files,
+		}
+		if msg.Embed != nil {
+			edit.Embeds = []*discordgo.MessageEmbed{msg.Embed}
+		}
+		_, err = s.InteractionResponseEdit(appID, interaction, edit)
+	case nil:
+		// the command already sent its own messages (like the game state message); remove the deferred reply
+		err = s.InteractionResponseDelete(appID, interaction)
+	default:
+		log.Printf("Incapable of processing interaction response of type: %T", msgToSend)
+		err = s.InteractionResponseDelete(appID, interaction)
+	}
+	if err != nil {
+		log.Println(err)
+	}
+	metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageCreateDelete, msgsSent)
+}
+
+func respondEphemeral(s *discordgo.Session, interaction *discordgo.Interaction, content string) {
+	err := s.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   uint64(discordgo.MessageFlagsEphemeral),
+		},
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// deferMessageUpdate acknowledges a component interaction without replying, so Discord doesn't show it as failed
+func deferMessageUpdate(s *discordgo.Session, interaction *discordgo.Interaction) {
+	err := s.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// handleGameStateComponent links or unlinks the User who used the color select menu or unlink button
+// on the game state message. Every interaction is answered, even if it's ignored, or Discord reports it as failed
+func (bot *Bot) handleGameStateComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.GuildID == "" || i.Member == nil || i.Member.User == nil || i.Message == nil {
+		deferMessageUpdate(s, i.Interaction)
+		return
+	}
+	userID := i.Member.User.ID
+
+	if redis_common.IsUserBanned(bot.RedisInterface.client, userID) {
+		// ignored, just like their commands
+		deferMessageUpdate(s, i.Interaction)
+		return
+	}
+
+	sett := bot.StorageInterface.GetGuildSettings(i.GuildID)
+
+	g, err := s.State.Guild(i.GuildID)
+	if err != nil {
+		log.Println(err)
+		respondEphemeral(s, i.Interaction, sett.LocalizeMessage(&i18n.Message{
+			ID:    "interaction_handlers.handleGameStateComponent.noGuild",
+			Other: "Sorry, I couldn't load this server; please try again in a bit",
+		}))
+		return
+	}
+
+	if redis_common.IsUserRateLimitedGeneral(bot.RedisInterface.client, userID) {
+		banned := redis_common.IncrementRateLimitExceed(bot.RedisInterface.client, userID)
+		if banned {
+			respondEphemeral(s, i.Interaction, sett.LocalizeMessage(&i18n.Message{
+				ID:    "message_handlers.softban",
+				Other: "I'm ignoring {{.User}} for the next 5 minutes, stop spamming",
+			},
+				map[string]interface{}{
+					"User": discord.MentionByUserID(userID),
+				}))
+		} else {
+			respondEphemeral(s, i.Interaction, sett.LocalizeMessage(&i18n.Message{
+				ID:    "interaction_handlers.handleGameStateComponent.generalRatelimit",
+				Other: "{{.User}}, you're selecting too fast! Please slow down!",
+			},
+				map[string]interface{}{
+					"User": discord.MentionByUserID(userID),
+				}))
+		}
+		return
+	}
+	redis_common.MarkUserRateLimit(bot.RedisInterface.client, userID, "Reaction", redis_common.ReactionRateLimitDuration)
+
+	gsr := GameStateRequest{
+		GuildID:     i.GuildID,
+		TextChannel: i.ChannelID,
+	}
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
+	if lock == nil {
+		respondEphemeral(s, i.Interaction, NoLock)
+		return
+	}
+	if dgs == nil || !dgs.Exists() || dgs.GameStateMsg.MessageID != i.Message.ID {
+		lock.Release(ctx)
+		respondEphemeral(s, i.Interaction, sett.LocalizeMessage(&i18n.Message{
+			ID:    "interaction_handlers.handleGameStateComponent.inactive",
+			Other: "This game is no longer active!",
+		}))
+		return
+	}
+
+	outcome := dgs.applyGameStateComponent(g, s, sett, userID, i.MessageComponentData())
+	if !outcome.changed {
+		lock.Release(ctx)
+		if outcome.reply != "" {
+			respondEphemeral(s, i.Interaction, outcome.reply)
+		} else {
+			deferMessageUpdate(s, i.Interaction)
+		}
+		return
+	}
+	bot.GameStateStore.SetDiscordGameState(dgs, lock)
+	if outcome.linkedName != "" {
+		go bot.GameStateStore.AddUsernameLink(i.GuildID, userID, outcome.linkedName)
+		go bot.linkIdentity(i.GuildID, userID, outcome.linkedName, storage.LinkByReaction)
+	}
+
+	// acknowledge without a visible reply; the edit below reflects the (un)link
+	deferMessageUpdate(s, i.Interaction)
+
+	// make sure to update any voice changes if they occurred
+	bot.handleTrackedMembers(bot.gameLogger(dgs), bot.PrimarySession, sett, 0, NoPriority, gsr, metrics.NoTransition)
+	edited := dgs.Edit(s, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
+	if edited {
+		metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageEdit, 1)
+	}
+}
+
+// gameStateComponentOutcome is how a component of the game state message changed the game
+type gameStateComponentOutcome struct {
+	changed bool
+	// linkedName is the in-game name the User linked themselves to, if they did
+	linkedName string
+	// reply is shown only to the User, when the game couldn't be changed
+	reply string
+}
+
+// applyGameStateComponent applies the color select menu, unlink button or waitlist button the User used to the game
+func (dgs *GameState) applyGameStateComponent(g *discordgo.Guild, s *discordgo.Session, sett *storage.GuildSettings, userID string, data discordgo.MessageComponentInteractionData) gameStateComponentOutcome {
+	switch data.CustomID {
+	case colorSelectID:
+		if len(data.Values) == 0 {
+			return gameStateComponentOutcome{}
+		}
+		color := data.Values[0]
+		log.Printf("Player %s selected color %s\n", userID, color)
+
+		// the User doesn't exist in our userdata cache; add them
+		user, added := dgs.checkCacheAndAddUser(g, s, userID)
+		if !added {
+			log.Println("No users found in Discord for UserID " + userID)
+			return gameStateComponentOutcome{
+				reply: sett.LocalizeMessage(&i18n.Message{
+					ID:    "interaction_handlers.handleGameStateComponent.memberNotFound",
+					Other: "Sorry, I couldn't find you in this server; please try again",
+				}),
+			}
+		}
+		auData, found := dgs.AmongUsData.GetByColor(color)
+		if !found {
+			return gameStateComponentOutcome{
+				reply: sett.LocalizeMessage(&i18n.Message{
+					ID:    "interaction_handlers.handleGameStateComponent.colorNotFound",
+					Other: "I couldn't find any player with that color; is your capture linked?",
+				}),
+			}
+		}
+		user.Link(auData)
+		dgs.UpdateUserData(userID, user)
+		return gameStateComponentOutcome{changed: true, linkedName: auData.Name}
+	case unlinkButtonID:
+		log.Println("Removing player " + userID)
+		dgs.ClearPlayerData(userID)
+		return gameStateComponentOutcome{changed: true}
+	case waitlistButtonID:
+		if dgs.Waitlist.Toggle(userID) {
+			log.Printf("Player %s joined the waitlist\n", userID)
+		} else {
+			log.Printf("Player %s left the waitlist\n", userID)
+		}
+		return gameStateComponentOutcome{changed: true}
+	default:
+		return gameStateComponentOutcome{}
+	}
+}
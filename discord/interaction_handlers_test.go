@@ -0,0 +1,270 @@
+package discord
+
+import (
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/discord"
+	"github.com/automuteus/utils/pkg/game"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// notFoundTransport answers every Discord API request with a 404, like for members that left the guild
+type notFoundTransport struct{}
+
+func (notFoundTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusNotFound,
+		Status:     "404 Not Found",
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(`{"message": "Unknown Member", "code": 10007}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestApplyGameStateComponent(t *testing.T) {
+	sett := storage.MakeGuildSettings("")
+	sess, _ := discordgo.New("Bot token")
+	sess.Client = &http.Client{Transport: notFoundTransport{}}
+	g := &discordgo.Guild{
+		ID:      "10",
+		Members: []*discordgo.Member{{User: &discordgo.User{ID: "100", Username: "alice"}}},
+	}
+
+	dgs := NewDiscordGameState("10")
+	dgs.AmongUsData.UpdatePlayer(game.Player{Name: "Alice", Color: 0, IsDead: false, Action: game.JOINED})
+	red := game.GetColorStringForInt(0)
+
+	outcome := dgs.applyGameStateComponent(g, sess, sett, "100", discordgo.MessageComponentInteractionData{CustomID: colorSelectID, Values: []string{red}})
+	if !outcome.changed || outcome.linkedName != "Alice" || dgs.UserData["100"].InGameName != "Alice" {
+		t.Errorf("Selecting a color should link the player, got %+v", outcome)
+	}
+
+	outcome = dgs.applyGameStateComponent(g, sess, sett, "100", discordgo.MessageComponentInteractionData{CustomID: unlinkButtonID})
+	if !outcome.changed || dgs.UserData["100"].InGameName != amongus.UnlinkedPlayerName {
+		t.Errorf("Unlinking should clear the player, got %+v", outcome)
+	}
+
+	outcome = dgs.applyGameStateComponent(g, sess, sett, "100", discordgo.MessageComponentInteractionData{CustomID: waitlistButtonID})
+	if !outcome.changed || len(dgs.Waitlist.Queue) != 1 {
+		t.Errorf("The waitlist button should add the user to the waitlist, got %+v", outcome)
+	}
+
+	tests := []struct {
+		name   string
+		userID string
+		data   discordgo.MessageComponentInteractionData
+		reply  bool
+	}{
+		{"no color selected", "100", discordgo.MessageComponentInteractionData{CustomID: colorSelectID}, false},
+		{"unknown color", "100", discordgo.MessageComponentInteractionData{CustomID: colorSelectID, Values: []string{game.GetColorStringForInt(1)}}, true},
+		{"unknown member", "200", discordgo.MessageComponentInteractionData{CustomID: colorSelectID, Values: []string{red}}, true},
+		{"unknown component", "100", discordgo.MessageComponentInteractionData{CustomID: "something-else"}, false},
+	}
+	for _, test := range tests {
+		outcome := dgs.applyGameStateComponent(g, sess, sett, test.userID, test.data)
+		if outcome.changed {
+			t.Errorf("%s: the game shouldn't change", test.name)
+		}
+		if (outcome.reply != "") != test.reply {
+			t.Errorf("%s: expected a reply: %t, got \"%s\"", test.name, test.reply, outcome.reply)
+		}
+	}
+}
+
+func TestApplicationCommandArgs(t *testing.T) {
+	// a command with a subcommand, to cover the nested options
+	withSubcommand := Command{
+		Command: "event",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type: discordgo.ApplicationCommandOptionSubCommand,
+				Name: "invite",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionUser, Name: "user"},
+					{Type: discordgo.ApplicationCommandOptionChannel, Name: "voice"},
+				},
+			},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "cancel"},
+		},
+	}
+	link, _ := getCommand("link")
+	track, _ := getCommand("track")
+	cache, _ := getCommand("cache")
+	schedule, _ := getCommand("schedule")
+	gameCmd, _ := getCommand("game")
+
+	tests := []struct {
+		name     string
+		cmd      *Command
+		options  []*discordgo.ApplicationCommandInteractionDataOption
+		expected []string
+	}{
+		{
+			name:     "no options",
+			cmd:      &link,
+			expected: []string{"link"},
+		},
+		{
+			name: "user and string, in the declared order",
+			cmd:  &link,
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Type: discordgo.ApplicationCommandOptionString, Name: "player", Value: "Red Player"},
+				{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Value: "140581066283941888"},
+			},
+			expected: []string{"link", discord.MentionByUserID("140581066283941888"), "red", "player"},
+		},
+		{
+			name: "channels with roles",
+			cmd:  &track,
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Type: discordgo.ApplicationCommandOptionChannel, Name: "channel1", Value: "754465589958803548"},
+				{Type: discordgo.ApplicationCommandOptionString, Name: "role1", Value: "ghosts"},
+			},
+			expected: []string{"track", discord.MentionByChannelID("754465589958803548"), "ghosts"},
+		},
+		{
+			name: "boolean flag set",
+			cmd:  &cache,
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Value: "140581066283941888"},
+				{Type: discordgo.ApplicationCommandOptionBoolean, Name: clearArgumentString, Value: true},
+			},
+			expected: []string{"cache", discord.MentionByUserID("140581066283941888"), clearArgumentString},
+		},
+		{
+			name: "boolean flag unset",
+			cmd:  &cache,
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Value: "140581066283941888"},
+				{Type: discordgo.ApplicationCommandOptionBoolean, Name: clearArgumentString, Value: false},
+			},
+			expected: []string{"cache", discord.MentionByUserID("140581066283941888")},
+		},
+		{
+			name: "integer",
+			cmd:  &schedule,
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Type: discordgo.ApplicationCommandOptionString, Name: "when", Value: "2021-06-01T20:00"},
+				{Type: discordgo.ApplicationCommandOptionInteger, Name: "reminder", Value: float64(15)},
+			},
+			expected: []string{"schedule", "2021-06-01t20:00", "15"},
+		},
+		{
+			name: "action choice",
+			cmd:  &gameCmd,
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Type: discordgo.ApplicationCommandOptionString, Name: "value", Value: "true"},
+				{Type: discordgo.ApplicationCommandOptionString, Name: "setting", Value: "unmuteDead"},
+				{Type: discordgo.ApplicationCommandOptionString, Name: "action", Value: "set"},
+			},
+			expected: []string{"game", "set", "unmutedead", "true"},
+		},
+		{
+			name: "subcommand with options",
+			cmd:  &withSubcommand,
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{
+					Type: discordgo.ApplicationCommandOptionSubCommand,
+					Name: "invite",
+					Options: []*discordgo.ApplicationCommandInteractionDataOption{
+						{Type: discordgo.ApplicationCommandOptionChannel, Name: "voice", Value: "754465589958803548"},
+						{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Value: "140581066283941888"},
+					},
+				},
+			},
+			expected: []string{"event", "invite", discord.MentionByUserID("140581066283941888"), discord.MentionByChannelID("754465589958803548")},
+		},
+		{
+			name: "subcommand without options",
+			cmd:  &withSubcommand,
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "cancel"},
+			},
+			expected: []string{"event", "cancel"},
+		},
+		{
+			name: "undeclared option",
+			cmd:  &link,
+			options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Type: discordgo.ApplicationCommandOptionString, Name: "unknown", Value: "ignored"},
+			},
+			expected: []string{"link"},
+		},
+	}
+	for _, test := range tests {
+		if args := applicationCommandArgs(test.cmd, test.options); !reflect.DeepEqual(args, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, args)
+		}
+	}
+}
+
+func TestInteractionResponseFlags(t *testing.T) {
+	tests := []struct {
+		command   string
+		ephemeral bool
+	}{
+		{"privacy", true},
+		{"cache", true},
+		{"whois", true},
+		{"new", false},
+		{"link", false},
+	}
+	for _, test := range tests {
+		cmd, exists := getCommand(test.command)
+		if !exists {
+			t.Fatalf("%s: command doesn't exist", test.command)
+		}
+		if ephemeral := interactionResponseFlags(&cmd) == uint64(discordgo.MessageFlagsEphemeral); ephemeral != test.ephemeral {
+			t.Errorf("%s: expected ephemeral %v, got %v", test.command, test.ephemeral, ephemeral)
+		}
+	}
+	for _, cmd := range allCommands {
+		cmd := cmd
+		if flags := interactionResponseFlags(&cmd); (flags != 0) != cmd.IsEphemeral {
+			t.Errorf("%s: expected the flags to match IsEphemeral (%v), got %d", cmd.Command, cmd.IsEphemeral, flags)
+		}
+	}
+}
+
+func TestApplicationCommands(t *testing.T) {
+	commands := []Command{
+		{Command: "help", ShortDesc: &i18n.Message{Other: "Display help"}},
+		{
+			Command:   "link",
+			ShortDesc: &i18n.Message{Other: "Link a Discord User"},
+			Options: []*discordgo.ApplicationCommandOption{
+				{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Required: true},
+			},
+		},
+	}
+	cmds := applicationCommands(commands)
+	if len(cmds) != 2 {
+		t.Fatalf("Expected an application command per command, got %d", len(cmds))
+	}
+	if cmds[0].Name != "help" || cmds[0].Description != "Display help" {
+		t.Errorf("Expected the command's name and short description, got %s: %s", cmds[0].Name, cmds[0].Description)
+	}
+	if cmds[0].Options == nil || len(cmds[0].Options) != 0 {
+		t.Errorf("Commands without options should have an empty list of options, got %v", cmds[0].Options)
+	}
+	if len(cmds[1].Options) != 1 || cmds[1].Options[0].Name != "user" {
+		t.Errorf("Expected the command's options, got %v", cmds[1].Options)
+	}
+
+	// every command needs a description, and Discord limits the names to 32 lowercase characters
+	for _, cmd := range applicationCommands(allCommands) {
+		if cmd.Description == "" {
+			t.Errorf("%s: application commands need a description", cmd.Name)
+		}
+		if len(cmd.Name) > 32 || strings.ToLower(cmd.Name) != cmd.Name {
+			t.Errorf("%s: invalid application command name", cmd.Name)
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/automuteus/utils/pkg/rediskey"
+)
+
+// MemoryGameStateStore is a GameStateStore that keeps everything in-process. It mirrors the key layout and
+// expiry of the Redis implementation, so it's only suitable for a single bot instance (or for tests)
+type MemoryGameStateStore struct {
+	lock sync.Mutex
+
+	// game state data and the pointers to it, keyed the same way as in Redis
+	values map[string]memoryValue
+	locks  map[string]memoryValue
+
+	// guildID -> username or userID -> set of linked userIDs or usernames
+	usernameCache map[string]map[string]map[string]interface{}
+
+	// guildID -> connect code -> last refresh (unix)
+	activeGames map[string]map[string]int64
+
+	orphanedGames map[GameStateRequest]struct{}
+
+	// connect code -> lease on the game subscription
+	gameLeases map[string]memoryLease
+
+	lockTokens int64
+}
+
+type memoryValue struct {
+	value   string
+	expires time.Time
+}
+
+func (v memoryValue) expired() bool {
+	return !v.expires.IsZero() && time.Now().After(v.expires)
+}
+
+type memoryLease struct {
+	guildID string
+	owner   string
+	expires time.Time
+}
+
+type memoryLock struct {
+	store *MemoryGameStateStore
+	key   string
+	token string
+}
+
+func (l *memoryLock) Release(_ context.Context) error {
+	l.store.lock.Lock()
+	defer l.store.lock.Unlock()
+
+	if v, ok := l.store.locks[l.key]; ok && v.value == l.token && !v.expired() {
+		delete(l.store.locks, l.key)
+		return nil
+	}
+	return errors.New("lock not held")
+}
+
+func (l *memoryLock) TTL(_ context.Context) (time.Duration, error) {
+	l.store.lock.Lock()
+	defer l.store.lock.Unlock()
+
+	if v, ok := l.store.locks[l.key]; ok && v.value == l.token && !v.expired() {
+		return time.Until(v.expires), nil
+	}
+	return 0, nil
+}
+
+var _ GameStateStore = &MemoryGameStateStore{}
+
+func NewMemoryGameStateStore() *MemoryGameStateStore {
+	return &MemoryGameStateStore{
+		lock:          sync.Mutex{},
+		values:        make(map[string]memoryValue),
+		locks:         make(map[string]memoryValue),
+		usernameCache: make(map[string]map[string]map[string]interface{}),
+		activeGames:   make(map[string]map[string]int64),
+		orphanedGames: make(map[GameStateRequest]struct{}),
+		gameLeases:    make(map[string]memoryLease),
+	}
+}
+
+func (store *MemoryGameStateStore) get(key string) string {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	v, ok := store.values[key]
+	if !ok {
+		return ""
+	}
+	if v.expired() {
+		delete(store.values, key)
+		return ""
+	}
+	return v.value
+}
+
+func (store *MemoryGameStateStore) set(key, value string, ttl time.Duration) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	store.values[key] = memoryValue{
+		value:   value,
+		expires: time.Now().Add(ttl),
+	}
+}
+
+func (store *MemoryGameStateStore) del(key string) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	delete(store.values, key)
+}
+
+// obtain behaves like redislock's Obtain with a linear backoff; it returns nil if the lock couldn't be obtained
+func (store *MemoryGameStateStore) obtain(key string, ttl time.Duration, retries int) Lock {
+	for i := 0; ; i++ {
+		store.lock.Lock()
+		if v, held := store.locks[key]; !held || v.expired() {
+			store.lockTokens++
+			token := strconv.FormatInt(store.lockTokens, 10)
+			store.locks[key] = memoryValue{
+				value:   token,
+				expires: time.Now().Add(ttl),
+			}
+			store.lock.Unlock()
+			return &memoryLock{
+				store: store,
+				key:   key,
+				token: token,
+			}
+		}
+		store.lock.Unlock()
+
+		if i >= retries {
+			return nil
+		}
+		time.Sleep(time.Millisecond * LinearBackoffMs)
+	}
+}
+
+func (store *MemoryGameStateStore) getDiscordGameStateKey(gsr GameStateRequest) string {
+	key := store.CheckPointer(rediskey.ConnectCodePtr(gsr.GuildID, gsr.ConnectCode))
+	if key == "" {
+		key = store.CheckPointer(rediskey.TextChannelPtr(gsr.GuildID, gsr.TextChannel))
+		if key == "" {
+			key = store.CheckPointer(rediskey.VoiceChannelPtr(gsr.GuildID, gsr.VoiceChannel))
+		}
+	}
+	return key
+}
+
+func (store *MemoryGameStateStore) GetReadOnlyDiscordGameState(gsr GameStateRequest) *GameState {
+	return store.getDiscordGameState(gsr)
+}
+
+func (store *MemoryGameStateStore) GetDiscordGameStateAndLock(gsr GameStateRequest) (Lock, *GameState) {
+	key := store.getDiscordGameStateKey(gsr)
+	lock := store.obtain(key+":lock", time.Millisecond*LockTimeoutMs, MaxRetries)
+	if lock == nil {
+		return nil, nil
+	}
+	return lock, store.getDiscordGameState(gsr)
+}
+
+func (store *MemoryGameStateStore) getDiscordGameState(gsr GameStateRequest) *GameState {
+	key := store.getDiscordGameStateKey(gsr)
+
+	jsonStr := store.get(key)
+	if jsonStr == "" {
+		dgs := NewDiscordGameState(gsr.GuildID)
+		dgs.ConnectCode = gsr.ConnectCode
+		dgs.GameStateMsg.MessageChannelID = gsr.TextChannel
+		dgs.Tracking.ChannelID = gsr.VoiceChannel
+		store.SetDiscordGameState(dgs, nil)
+		return dgs
+	}
+
+	// always hand out a copy, just like a fetch from Redis would
+	dgs := GameState{}
+	err := json.Unmarshal([]byte(jsonStr), &dgs)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	return &dgs
+}
+
+func (store *MemoryGameStateStore) CheckPointer(pointer string) string {
+	return store.get(pointer)
+}
+
+func (store *MemoryGameStateStore) SetDiscordGameState(data *GameState, lock Lock) {
+	if lock != nil {
+		defer lock.Release(ctx)
+	}
+	if data == nil {
+		return
+	}
+	key := store.getDiscordGameStateKey(GameStateRequest{
+		GuildID:      data.GuildID,
+		TextChannel:  data.GameStateMsg.MessageChannelID,
+		VoiceChannel: data.Tracking.ChannelID,
+		ConnectCode:  data.ConnectCode,
+	})
+	// connectCode is the only key we rely on for tracking games; see RedisInterface.SetDiscordGameState
+	if key == "" && data.ConnectCode == "" {
+		return
+	}
+	key = rediskey.ConnectCodeData(data.GuildID, data.ConnectCode)
+
+	jBytes, err := json.Marshal(data)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	ttl := GameTimeoutSeconds * time.Second
+	store.set(key, string(jBytes), ttl)
+	if data.ConnectCode != "" {
+		store.set(rediskey.ConnectCodePtr(data.GuildID, data.ConnectCode), key, ttl)
+	}
+	for _, c := range data.TrackedChannels() {
+		store.set(rediskey.VoiceChannelPtr(data.GuildID, c.ChannelID), key, ttl)
+	}
+	if data.GameStateMsg.MessageChannelID != "" {
+		store.set(rediskey.TextChannelPtr(data.GuildID, data.GameStateMsg.MessageChannelID), key, ttl)
+	}
+}
+
+func (store *MemoryGameStateStore) DeleteDiscordGameState(dgs *GameState) {
+	if dgs.GuildID == "" || dgs.ConnectCode == "" {
+		log.Println("Can't delete DGS with null guildID or null ConnCode")
+		return
+	}
+	data := store.getDiscordGameState(GameStateRequest{
+		GuildID:     dgs.GuildID,
+		ConnectCode: dgs.ConnectCode,
+	})
+	if data == nil {
+		return
+	}
+	store.del(rediskey.TextChannelPtr(dgs.GuildID, data.GameStateMsg.MessageChannelID))
+	for _, c := range data.TrackedChannels() {
+		store.del(rediskey.VoiceChannelPtr(dgs.GuildID, c.ChannelID))
+	}
+	store.del(rediskey.ConnectCodePtr(dgs.GuildID, data.ConnectCode))
+	store.del(rediskey.ConnectCodeData(dgs.GuildID, dgs.ConnectCode))
+}
+
+func (store *MemoryGameStateStore) LockVoiceChanges(connectCode string, dur time.Duration) Lock {
+	return store.obtain(rediskey.VoiceChangesForGameCodeLock(connectCode), dur, MaxRetries)
+}
+
+func (store *MemoryGameStateStore) LockSnowflake(snowflake string) Lock {
+	return store.obtain(rediskey.SnowflakeLockID(snowflake), time.Millisecond*SnowflakeLockMs, 0)
+}
+
+func (store *MemoryGameStateStore) GetUsernameOrUserIDMappings(guildID, key string) map[string]interface{} {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	ret := map[string]interface{}{}
+	for k, v := range store.usernameCache[guildID][key] {
+		ret[k] = v
+	}
+	return ret
+}
+
+func (store *MemoryGameStateStore) AddUsernameLink(guildID, userID, userName string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	store.appendToEntry(guildID, userID, userName)
+	store.appendToEntry(guildID, userName, userID)
+	return nil
+}
+
+func (store *MemoryGameStateStore) appendToEntry(guildID, key, value string) {
+	if _, ok := store.usernameCache[guildID]; !ok {
+		store.usernameCache[guildID] = make(map[string]map[string]interface{})
+	}
+	if _, ok := store.usernameCache[guildID][key]; !ok {
+		store.usernameCache[guildID][key] = make(map[string]interface{})
+	}
+	store.usernameCache[guildID][key][value] = struct{}{}
+}
+
+func (store *MemoryGameStateStore) DeleteLinksByUserID(guildID, userID string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	cache := store.usernameCache[guildID]
+	for username := range cache[userID] {
+		delete(cache[username], userID)
+	}
+	delete(cache, userID)
+	return nil
+}
+
+func (store *MemoryGameStateStore) RefreshActiveGame(guildID, connectCode string) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if _, ok := store.activeGames[guildID]; !ok {
+		store.activeGames[guildID] = make(map[string]int64)
+	}
+	store.activeGames[guildID][connectCode] = time.Now().Unix()
+}
+
+func (store *MemoryGameStateStore) RemoveOldGame(guildID, connectCode string) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	delete(store.activeGames[guildID], connectCode)
+}
+
+func (store *MemoryGameStateStore) LoadAllActiveGames(guildID string) []string {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	before := time.Now().Add(-time.Second * GameTimeoutSeconds).Unix()
+	games := []string{}
+	for code, refreshed := range store.activeGames[guildID] {
+		if refreshed < before {
+			delete(store.activeGames[guildID], code)
+		} else {
+			games = append(games, code)
+		}
+	}
+	return games
+}
+
+func (store *MemoryGameStateStore) AddOrphanedGame(guildID, connectCode string) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	store.orphanedGames[GameStateRequest{GuildID: guildID, ConnectCode: connectCode}] = struct{}{}
+}
+
+func (store *MemoryGameStateStore) RemoveOrphanedGame(guildID, connectCode string) bool {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	gsr := GameStateRequest{GuildID: guildID, ConnectCode: connectCode}
+	if _, ok := store.orphanedGames[gsr]; !ok {
+		return false
+	}
+	delete(store.orphanedGames, gsr)
+	return true
+}
+
+func (store *MemoryGameStateStore) LoadOrphanedGames() []GameStateRequest {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	games := make([]GameStateRequest, 0, len(store.orphanedGames))
+	for gsr := range store.orphanedGames {
+		games = append(games, gsr)
+	}
+	return games
+}
+
+func (store *MemoryGameStateStore) AcquireGameLease(gsr GameStateRequest, ownerID string, ttl time.Duration) bool {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	lease, ok := store.gameLeases[gsr.ConnectCode]
+	if ok && lease.owner != ownerID && time.Now().Before(lease.expires) {
+		return false
+	}
+	store.gameLeases[gsr.ConnectCode] = memoryLease{
+		guildID: gsr.GuildID,
+		owner:   ownerID,
+		expires: time.Now().Add(ttl),
+	}
+	return true
+}
+
+func (store *MemoryGameStateStore) RenewGameLease(connectCode, ownerID string, ttl time.Duration) bool {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	lease, ok := store.gameLeases[connectCode]
+	if !ok || lease.owner != ownerID || time.Now().After(lease.expires) {
+		return false
+	}
+	lease.expires = time.Now().Add(ttl)
+	store.gameLeases[connectCode] = lease
+	return true
+}
+
+func (store *MemoryGameStateStore) ReleaseGameLease(connectCode, ownerID string) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	lease, ok := store.gameLeases[connectCode]
+	if ok && (lease.owner == ownerID || time.Now().After(lease.expires)) {
+		delete(store.gameLeases, connectCode)
+	}
+}
+
+func (store *MemoryGameStateStore) LoadExpiredGameLeases() []GameStateRequest {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	games := []GameStateRequest{}
+	for connectCode, lease := range store.gameLeases {
+		if time.Now().After(lease.expires) {
+			games = append(games, GameStateRequest{GuildID: lease.guildID, ConnectCode: connectCode})
+		}
+	}
+	return games
+}
@@ -0,0 +1,171 @@
+package discord
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryGameStateStore_Pointers(t *testing.T) {
+	store := NewMemoryGameStateStore()
+
+	lock, dgs := store.GetDiscordGameStateAndLock(GameStateRequest{
+		GuildID:     "1",
+		TextChannel: "2",
+	})
+	if lock == nil || dgs == nil {
+		t.Fatal("Obtaining an uncontested game state should always succeed")
+	}
+	dgs.ConnectCode = "ABCDEFGH"
+	dgs.Tracking.ChannelID = "3"
+	dgs.Running = true
+	store.SetDiscordGameState(dgs, lock)
+
+	for _, gsr := range []GameStateRequest{
+		{GuildID: "1", ConnectCode: "ABCDEFGH"},
+		{GuildID: "1", TextChannel: "2"},
+		{GuildID: "1", VoiceChannel: "3"},
+	} {
+		fetched := store.GetReadOnlyDiscordGameState(gsr)
+		if fetched == nil || fetched.ConnectCode != "ABCDEFGH" || !fetched.Running {
+			t.Errorf("Game state wasn't found using request %v", gsr)
+		}
+	}
+
+	store.DeleteDiscordGameState(dgs)
+	fetched := store.GetReadOnlyDiscordGameState(GameStateRequest{GuildID: "1", VoiceChannel: "3"})
+	if fetched.ConnectCode != "" {
+		t.Error("Deleted game state should not be found by its pointers")
+	}
+}
+
+func TestMemoryGameStateStore_Lock(t *testing.T) {
+	store := NewMemoryGameStateStore()
+	dgs := NewDiscordGameState("1")
+	dgs.ConnectCode = "ABCDEFGH"
+	store.SetDiscordGameState(dgs, nil)
+
+	gsr := GameStateRequest{
+		GuildID:     "1",
+		ConnectCode: "ABCDEFGH",
+	}
+	lock, dgs := store.GetDiscordGameStateAndLock(gsr)
+	if lock == nil {
+		t.Fatal("Obtaining an uncontested game state should always succeed")
+	}
+
+	// held locks expire after LockTimeoutMs, which is shorter than the retries take
+	second, _ := store.GetDiscordGameStateAndLock(gsr)
+	if second == nil {
+		t.Error("Lock should be obtained once the previous one expires")
+	}
+	store.SetDiscordGameState(dgs, second)
+	if err := second.Release(ctx); err == nil {
+		t.Error("Setting the game state should have released the lock")
+	}
+
+	if store.LockVoiceChanges("ABCDEFGH", time.Minute) == nil {
+		t.Error("Uncontested voice lock should be obtained")
+	}
+	if store.LockVoiceChanges("ABCDEFGH", time.Minute) != nil {
+		t.Error("Voice lock should not be obtained while it's held")
+	}
+}
+
+func TestMemoryGameStateStore_UsernameLinks(t *testing.T) {
+	store := NewMemoryGameStateStore()
+
+	if err := store.AddUsernameLink("1", "100", "soup"); err != nil {
+		t.Error(err)
+	}
+	if _, ok := store.GetUsernameOrUserIDMappings("1", "100")["soup"]; !ok {
+		t.Error("UserID should map to the linked username")
+	}
+	if _, ok := store.GetUsernameOrUserIDMappings("1", "soup")["100"]; !ok {
+		t.Error("Username should map to the linked userID")
+	}
+	if len(store.GetUsernameOrUserIDMappings("2", "100")) != 0 {
+		t.Error("Links should not be shared between guilds")
+	}
+
+	if err := store.DeleteLinksByUserID("1", "100"); err != nil {
+		t.Error(err)
+	}
+	if len(store.GetUsernameOrUserIDMappings("1", "100")) != 0 || len(store.GetUsernameOrUserIDMappings("1", "soup")) != 0 {
+		t.Error("Deleting links by userID should remove both directions")
+	}
+}
+
+func TestMemoryGameStateStore_ActiveGames(t *testing.T) {
+	store := NewMemoryGameStateStore()
+	store.RefreshActiveGame("1", "ABCDEFGH")
+	store.RefreshActiveGame("1", "HGFEDCBA")
+
+	if len(store.LoadAllActiveGames("1")) != 2 {
+		t.Error("Both refreshed games should be active")
+	}
+	store.RemoveOldGame("1", "ABCDEFGH")
+	games := store.LoadAllActiveGames("1")
+	if len(games) != 1 || games[0] != "HGFEDCBA" {
+		t.Error("Removed game should no longer be active")
+	}
+}
+
+func TestMemoryGameStateStore_OrphanedGames(t *testing.T) {
+	store := NewMemoryGameStateStore()
+
+	store.AddOrphanedGame("1", "ABCDEFGH")
+	games := store.LoadOrphanedGames()
+	if len(games) != 1 || games[0].GuildID != "1" || games[0].ConnectCode != "ABCDEFGH" {
+		t.Errorf("Expected the orphaned game to be loaded, got %v", games)
+	}
+
+	if !store.RemoveOrphanedGame("1", "ABCDEFGH") {
+		t.Error("Removing an orphaned game should claim it")
+	}
+	if store.RemoveOrphanedGame("1", "ABCDEFGH") {
+		t.Error("An orphaned game should only be claimed once")
+	}
+	if len(store.LoadOrphanedGames()) != 0 {
+		t.Error("Claimed games shouldn't be orphaned anymore")
+	}
+}
+
+func TestMemoryGameStateStore_GameLeases(t *testing.T) {
+	store := NewMemoryGameStateStore()
+	gsr := GameStateRequest{GuildID: "1", ConnectCode: "ABCDEFGH"}
+
+	if !store.AcquireGameLease(gsr, "node1", time.Minute) {
+		t.Fatal("Acquiring an unleased game should always succeed")
+	}
+	if store.AcquireGameLease(gsr, "node2", time.Minute) {
+		t.Error("Only one owner should hold the lease for a game")
+	}
+	if !store.AcquireGameLease(gsr, "node1", time.Minute) || !store.RenewGameLease("ABCDEFGH", "node1", time.Minute) {
+		t.Error("The owner should be able to extend their lease")
+	}
+	if store.RenewGameLease("ABCDEFGH", "node2", time.Minute) {
+		t.Error("Only the owner should be able to renew the lease")
+	}
+	if len(store.LoadExpiredGameLeases()) != 0 {
+		t.Error("Renewed leases shouldn't be expired")
+	}
+
+	store.ReleaseGameLease("ABCDEFGH", "node2")
+	if store.AcquireGameLease(gsr, "node2", time.Minute) {
+		t.Error("Only the owner should be able to release the lease")
+	}
+
+	store.AcquireGameLease(gsr, "node1", -time.Second)
+	expired := store.LoadExpiredGameLeases()
+	if len(expired) != 1 || expired[0] != gsr {
+		t.Errorf("Expected the lease to be expired, got %v", expired)
+	}
+	if !store.AcquireGameLease(gsr, "node2", time.Minute) {
+		t.Error("Another owner should be able to take over an expired lease")
+	}
+
+	store.ReleaseGameLease("ABCDEFGH", "node2")
+	if len(store.LoadExpiredGameLeases()) != 0 || !store.AcquireGameLease(gsr, "node1", time.Minute) {
+		t.Error("Released leases should be forgotten")
+	}
+}
@@ -3,10 +3,9 @@ package discord
 import (
 	"context"
 	"fmt"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/automuteus/utils/pkg/discord"
-	"github.com/automuteus/utils/pkg/settings"
 	"log"
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -17,14 +16,11 @@ import (
 	"github.com/automuteus/utils/pkg/game"
 	"github.com/automuteus/utils/pkg/premium"
 	"github.com/automuteus/utils/pkg/task"
-	"github.com/bsm/redislock"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 )
 
-const DefaultMaxActiveGames = 150
-
 const downloadURL = "https://capture.automute.us"
 
 func (bot *Bot) handleMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
@@ -37,7 +33,7 @@ func (bot *Bot) handleMessageCreate(s *discordgo.Session, m *discordgo.MessageCr
 		return
 	}
 
-	lock := bot.RedisInterface.LockSnowflake(m.ID)
+	lock := bot.GameStateStore.LockSnowflake(m.ID)
 	// couldn't obtain lock; bail bail bail!
 	if lock == nil {
 		return
@@ -56,7 +52,7 @@ func (bot *Bot) handleMessageCreate(s *discordgo.Session, m *discordgo.MessageCr
 	// can be a guild's old prefix setting, or @AutoMuteUs
 	prefix := sett.GetCommandPrefix()
 
-	globalPrefix := os.Getenv("AUTOMUTEUS_GLOBAL_PREFIX")
+	globalPrefix := bot.config.GlobalPrefix
 	if globalPrefix != "" && strings.HasPrefix(contents, globalPrefix) {
 		// if the global matches, then use that for future processing/control flow using the prefix
 		prefix = globalPrefix
@@ -99,25 +95,7 @@ func (bot *Bot) handleMessageCreate(s *discordgo.Session, m *discordgo.MessageCr
 
 		contents = removePrefixOrMention(contents, prefix, mention, altMention)
 
-		isAdmin, isPermissioned := false, false
-
-		if g.OwnerID == m.Author.ID || (len(sett.AdminUserIDs) == 0 && len(sett.PermissionRoleIDs) == 0) {
-			// the guild owner should always have both permissions
-			// or if both permissions are still empty everyone get both
-			isAdmin = true
-			isPermissioned = true
-		} else {
-			// if we have no admins, then we MUST have mods as per the check above.
-			if len(sett.AdminUserIDs) == 0 {
-				// we have no admins, but we have mods, so make sure users fulfill that check
-				isAdmin = sett.HasRolePerms(m.Member)
-			} else {
-				// we have admins; make sure user is one
-				isAdmin = sett.HasAdminPerms(m.Author)
-			}
-			// even if we have admins, we can grant mod if the moderators role is empty; it is lesser permissions
-			isPermissioned = len(sett.PermissionRoleIDs) == 0 || sett.HasRolePerms(m.Member)
-		}
+		isAdmin, isPermissioned := getPermissions(g, sett, m.Author, m.Member)
 
 		deleteUserMessage := false
 		if len(contents) == 0 {
@@ -145,6 +123,29 @@ func (bot *Bot) handleMessageCreate(s *discordgo.Session, m *discordgo.MessageCr
 	}
 }
 
+func getPermissions(g *discordgo.Guild, sett *storage.GuildSettings, user *discordgo.User, member *discordgo.Member) (bool, bool) {
+	isAdmin, isPermissioned := false, false
+
+	if g.OwnerID == user.ID || (len(sett.AdminUserIDs) == 0 && len(sett.PermissionRoleIDs) == 0) {
+		// the guild owner should always have both permissions
+		// or if both permissions are still empty everyone get both
+		isAdmin = true
+		isPermissioned = true
+	} else {
+		// if we have no admins, then we MUST have mods as per the check above.
+		if len(sett.AdminUserIDs) == 0 {
+			// we have no admins, but we have mods, so make sure users fulfill that check
+			isAdmin = sett.HasRolePerms(member)
+		} else {
+			// we have admins; make sure user is one
+			isAdmin = sett.HasAdminPerms(user)
+		}
+		// even if we have admins, we can grant mod if the moderators role is empty; it is lesser permissions
+		isPermissioned = len(sett.PermissionRoleIDs) == 0 || sett.HasRolePerms(member)
+	}
+	return isAdmin, isPermissioned
+}
+
 // TODO refactor to use regex, could do the matching + removal easier
 func removePrefixOrMention(contents, prefix, mention, altMention string) string {
 	oldLen := len(contents)
@@ -159,6 +160,8 @@ func removePrefixOrMention(contents, prefix, mention, altMention string) string
 	return contents
 }
 
+// handleReactionGameStartAdd links players that react to game state messages posted before linking moved to
+// message components (see handleGameStateComponent)
 func (bot *Bot) handleReactionGameStartAdd(s *discordgo.Session, m *discordgo.MessageReactionAdd) {
 	// IgnoreSpectator all reactions created by the bot itself
 	if m.UserID == s.State.User.ID {
@@ -169,7 +172,7 @@ func (bot *Bot) handleReactionGameStartAdd(s *discordgo.Session, m *discordgo.Me
 		return
 	}
 
-	lock := bot.RedisInterface.LockSnowflake(m.MessageID + m.UserID + m.Emoji.ID)
+	lock := bot.GameStateStore.LockSnowflake(m.MessageID + m.UserID + m.Emoji.ID)
 	// couldn't obtain lock; bail bail bail!
 	if lock == nil {
 		return
@@ -190,7 +193,7 @@ func (bot *Bot) handleReactionGameStartAdd(s *discordgo.Session, m *discordgo.Me
 		GuildID:     m.GuildID,
 		TextChannel: m.ChannelID,
 	}
-	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
 	if lock != nil && dgs != nil && dgs.Exists() {
 		// verify that the User is reacting to the state/status message
 		if dgs.IsReactionTo(m) {
@@ -222,58 +225,52 @@ func (bot *Bot) handleReactionGameStartAdd(s *discordgo.Session, m *discordgo.Me
 			}
 			redis_common.MarkUserRateLimit(bot.RedisInterface.client, m.UserID, "Reaction", redis_common.ReactionRateLimitDuration)
 			idMatched := false
-			if m.Emoji.Name == "▶️" {
-				metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.ReactionAdd, 14)
-				go removeReaction(bot.PrimarySession, m.ChannelID, m.MessageID, m.Emoji.Name, m.UserID)
-				go removeReaction(bot.PrimarySession, m.ChannelID, m.MessageID, m.Emoji.Name, "@me")
-				go dgs.AddAllReactions(bot.PrimarySession, bot.StatusEmojis[true])
-			} else {
-				for color, e := range bot.StatusEmojis[true] {
-					if e.ID == m.Emoji.ID {
-						idMatched = true
-						log.Print(fmt.Sprintf("Player %s reacted with color %s\n", m.UserID, game.GetColorStringForInt(color)))
-						// the User doesn't exist in our userdata cache; add them
-						user, added := dgs.checkCacheAndAddUser(g, s, m.UserID)
-						if !added {
-							log.Println("No users found in Discord for UserID " + m.UserID)
-							idMatched = false
+			for color, e := range bot.StatusEmojis[true] {
+				if e.ID == m.Emoji.ID {
+					idMatched = true
+					log.Print(fmt.Sprintf("Player %s reacted with color %s\n", m.UserID, game.GetColorStringForInt(color)))
+					// the User doesn't exist in our userdata cache; add them
+					user, added := dgs.checkCacheAndAddUser(g, s, m.UserID)
+					if !added {
+						log.Println("No users found in Discord for UserID " + m.UserID)
+						idMatched = false
+					} else {
+						auData, found := dgs.AmongUsData.GetByColor(game.GetColorStringForInt(color))
+						if found {
+							user.Link(auData)
+							dgs.UpdateUserData(m.UserID, user)
+							go bot.GameStateStore.AddUsernameLink(m.GuildID, m.UserID, auData.Name)
+							go bot.linkIdentity(m.GuildID, m.UserID, auData.Name, storage.LinkByReaction)
 						} else {
-							auData, found := dgs.AmongUsData.GetByColor(game.GetColorStringForInt(color))
-							if found {
-								user.Link(auData)
-								dgs.UpdateUserData(m.UserID, user)
-								go bot.RedisInterface.AddUsernameLink(m.GuildID, m.UserID, auData.Name)
-							} else {
-								log.Println("I couldn't find any player data for that color; is your capture linked?")
-								idMatched = false
-							}
+							log.Println("I couldn't find any player data for that color; is your capture linked?")
+							idMatched = false
 						}
-
-						// then remove the player's reaction if we matched, or if we didn't
-						go s.MessageReactionRemove(m.ChannelID, m.MessageID, e.FormatForReaction(), m.UserID)
-						break
 					}
+
+					// then remove the player's reaction if we matched, or if we didn't
+					go s.MessageReactionRemove(m.ChannelID, m.MessageID, e.FormatForReaction(), m.UserID)
+					break
 				}
-				if !idMatched {
-					// log.Println(m.Emoji.Name)
-					if m.Emoji.Name == "❌" {
-						log.Println("Removing player " + m.UserID)
-						dgs.ClearPlayerData(m.UserID)
-						go s.MessageReactionRemove(m.ChannelID, m.MessageID, "❌", m.UserID)
-						idMatched = true
-					}
+			}
+			if !idMatched {
+				// log.Println(m.Emoji.Name)
+				if m.Emoji.Name == "❌" {
+					log.Println("Removing player " + m.UserID)
+					dgs.ClearPlayerData(m.UserID)
+					go s.MessageReactionRemove(m.ChannelID, m.MessageID, "❌", m.UserID)
+					idMatched = true
 				}
-				// make sure to update any voice changes if they occurred
-				if idMatched {
-					bot.handleTrackedMembers(bot.PrimarySession, sett, 0, NoPriority, gsr)
-					edited := dgs.Edit(s, bot.gameStateResponse(dgs, sett))
-					if edited {
-						metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageEdit, 1)
-					}
+			}
+			// make sure to update any voice changes if they occurred
+			if idMatched {
+				bot.handleTrackedMembers(bot.gameLogger(dgs), bot.PrimarySession, sett, 0, NoPriority, gsr, metrics.NoTransition)
+				edited := dgs.Edit(s, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
+				if edited {
+					metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageEdit, 1)
 				}
 			}
 		}
-		bot.RedisInterface.SetDiscordGameState(dgs, lock)
+		bot.GameStateStore.SetDiscordGameState(dgs, lock)
 	}
 }
 
@@ -281,7 +278,7 @@ func (bot *Bot) handleReactionGameStartAdd(s *discordgo.Session, m *discordgo.Me
 // relevant discord api requests are fully applied successfully. Otherwise, we can issue multiple requests for
 // the same mute/unmute, erroneously
 func (bot *Bot) handleVoiceStateChange(s *discordgo.Session, m *discordgo.VoiceStateUpdate) {
-	snowFlakeLock := bot.RedisInterface.LockSnowflake(m.ChannelID + m.UserID + m.SessionID)
+	snowFlakeLock := bot.GameStateStore.LockSnowflake(m.ChannelID + m.UserID + m.SessionID)
 	// couldn't obtain lock; bail bail bail!
 	if snowFlakeLock == nil {
 		return
@@ -300,15 +297,15 @@ func (bot *Bot) handleVoiceStateChange(s *discordgo.Session, m *discordgo.VoiceS
 		VoiceChannel: m.ChannelID,
 	}
 
-	stateLock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	stateLock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
 	if stateLock == nil {
 		return
 	}
 	defer stateLock.Release(ctx)
 
-	var voiceLock *redislock.Lock
+	var voiceLock Lock
 	if dgs.ConnectCode != "" {
-		voiceLock = bot.RedisInterface.LockVoiceChanges(dgs.ConnectCode, time.Second)
+		voiceLock = bot.GameStateStore.LockVoiceChanges(dgs.ConnectCode, time.Second)
 		if voiceLock == nil {
 			return
 		}
@@ -327,27 +324,17 @@ func (bot *Bot) handleVoiceStateChange(s *discordgo.Session, m *discordgo.VoiceS
 		userData, _ = dgs.checkCacheAndAddUser(g, s, m.UserID)
 	}
 
-	tracked := m.ChannelID != "" && dgs.Tracking.ChannelID == m.ChannelID
+	role, tracked := dgs.TrackedChannelRole(m.ChannelID)
 
 	auData, found := dgs.AmongUsData.GetByName(userData.InGameName)
+	sett = dgs.gameSettings(bot.gameLogger(dgs), sett)
+	mute, deaf, handled := getVoiceState(sett, m.UserID, tracked, role, auData, found, dgs.AmongUsData.GetPhase())
 
-	var isAlive bool
+	// unlinked users are only handled here if they're explicitly marked as spectators, or sit in a spectators channel
+	override, _ := sett.GetVoiceOverride(m.UserID)
+	handled = handled && (found || override == storage.OverrideSpectator || (tracked && role == RoleSpectators))
 
-	// only actually tracked if we're in a tracked channel AND linked to a player
-	if !sett.GetMuteSpectator() {
-		tracked = tracked && found
-		isAlive = auData.IsAlive
-	} else {
-		if !found {
-			// we just assume the spectator is dead
-			isAlive = false
-		} else {
-			isAlive = auData.IsAlive
-		}
-	}
-	mute, deaf := sett.GetVoiceState(isAlive, tracked, dgs.AmongUsData.GetPhase())
-	// check the userdata is linked here to not accidentally undeafen music bots, for example
-	if found && (userData.ShouldBeDeaf != deaf || userData.ShouldBeMute != mute) && (mute != m.Mute || deaf != m.Deaf) {
+	if handled && (userData.ShouldBeDeaf != deaf || userData.ShouldBeMute != mute) && (mute != m.Mute || deaf != m.Deaf) {
 		userData.SetShouldBeMuteDeaf(mute, deaf)
 
 		dgs.UpdateUserData(m.UserID, userData)
@@ -364,7 +351,7 @@ func (bot *Bot) handleVoiceStateChange(s *discordgo.Session, m *discordgo.VoiceS
 					},
 				},
 			}
-			mdsc := bot.GalactusClient.ModifyUsers(m.GuildID, dgs.ConnectCode, req, voiceLock)
+			mdsc := bot.VoiceModifier.ModifyUsers(m.GuildID, dgs.ConnectCode, req, voiceLock, metrics.NoTransition)
 			if mdsc == nil {
 				log.Println("Nil response from modifyUsers, probably not good...")
 			} else {
@@ -372,27 +359,23 @@ func (bot *Bot) handleVoiceStateChange(s *discordgo.Session, m *discordgo.VoiceS
 			}
 		}
 	}
-	bot.RedisInterface.SetDiscordGameState(dgs, stateLock)
+	bot.GameStateStore.SetDiscordGameState(dgs, stateLock)
 }
 
-func (bot *Bot) handleNewGameMessage(m *discordgo.MessageCreate, g *discordgo.Guild, sett *settings.GuildSettings) (string, interface{}) {
-	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(GameStateRequest{
-		GuildID:     m.GuildID,
-		TextChannel: m.ChannelID,
-	})
-	retries := 0
-	for lock == nil {
-		if retries > 10 {
-			log.Println("DEADLOCK in obtaining game state lock, upon calling new")
-			return m.ChannelID, "I wasn't able to make a new game, maybe try in a different text channel?"
-		}
-		retries++
-		lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(GameStateRequest{
-			GuildID:     m.GuildID,
-			TextChannel: m.ChannelID,
+// handleNewGameMessage starts a new game in the channel of the message, with the voice preset of the mode (if any)
+func (bot *Bot) handleNewGameMessage(m *discordgo.MessageCreate, g *discordgo.Guild, sett *storage.GuildSettings, mode string) (string, interface{}) {
+	if bot.isClosing() {
+		return m.ChannelID, sett.LocalizeMessage(&i18n.Message{
+			ID:    "message_handlers.handleNewGameMessage.closing",
+			Other: "I'm restarting right now! Please try again in a few seconds",
 		})
 	}
 
+	lock, dgs := bot.newGameLock(m.GuildID, m.ChannelID)
+	if lock == nil {
+		return m.ChannelID, "I wasn't able to make a new game, maybe try in a different text channel?"
+	}
+
 	if redis_common.IsUserRateLimitedSpecific(bot.RedisInterface.client, m.Author.ID, "NewGame") {
 		defer lock.Release(context.Background())
 		banned := redis_common.IncrementRateLimitExceed(bot.RedisInterface.client, m.Author.ID)
@@ -450,10 +433,37 @@ func (bot *Bot) handleNewGameMessage(m *discordgo.MessageCreate, g *discordgo.Gu
 		})
 	}
 
+	return bot.startNewGame(m, g, sett, mode, tracking, lock, dgs)
+}
+
+// newGameLock locks the game state of the text channel to start a new game in it, retrying a few times. Returns a
+// nil lock if it couldn't be obtained
+func (bot *Bot) newGameLock(guildID, channelID string) (Lock, *GameState) {
+	gsr := GameStateRequest{
+		GuildID:     guildID,
+		TextChannel: channelID,
+	}
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
+	retries := 0
+	for lock == nil {
+		if retries > 10 {
+			log.Println("DEADLOCK in obtaining game state lock, upon calling new")
+			return nil, nil
+		}
+		retries++
+		lock, dgs = bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
+	}
+	return lock, dgs
+}
+
+// startNewGame starts a game in the channel of the message, tracking the voice channel, and DMs the capture link to
+// the author. Any previous game in the channel is ended. The game state must be locked already; the lock is released
+func (bot *Bot) startNewGame(m *discordgo.MessageCreate, g *discordgo.Guild, sett *storage.GuildSettings, mode string, tracking TrackingChannel, lock Lock, dgs *GameState) (string, interface{}) {
+
 	// allow people with a previous game going to be able to make new games
 	if dgs.GameStateMsg.MessageID != "" {
 		if v, ok := bot.EndGameChannels[dgs.ConnectCode]; ok {
-			v <- true
+			v <- EndGame
 		}
 		delete(bot.EndGameChannels, dgs.ConnectCode)
 
@@ -468,11 +478,7 @@ func (bot *Bot) handleNewGameMessage(m *discordgo.MessageCreate, g *discordgo.Gu
 		// Premium users should always be allowed to start new games; only check the free guilds
 		if premTier == premium.FreeTier {
 			activeGames := broker.GetActiveGames(bot.RedisInterface.client, GameTimeoutSeconds)
-			act := os.Getenv("MAX_ACTIVE_GAMES")
-			num, err := strconv.ParseInt(act, 10, 64)
-			if err != nil {
-				num = DefaultMaxActiveGames
-			}
+			num := int64(bot.config.MaxActiveGames)
 			if activeGames > num {
 				defer lock.Release(context.Background())
 				return m.ChannelID, sett.LocalizeMessage(&i18n.Message{
@@ -491,16 +497,19 @@ func (bot *Bot) handleNewGameMessage(m *discordgo.MessageCreate, g *discordgo.Gu
 	connectCode := generateConnectCode(m.GuildID)
 
 	dgs.ConnectCode = connectCode
+	// the settings of the previous game don't carry over
+	dgs.Settings = GameSettings{Mode: mode}
 
-	bot.RedisInterface.RefreshActiveGame(m.GuildID, connectCode)
+	bot.GameStateStore.RefreshActiveGame(m.GuildID, connectCode)
 
 	killChan := make(chan EndGameMessage)
 
+	bot.subscriptions.Add(1)
 	go bot.SubscribeToGameByConnectCode(m.GuildID, connectCode, killChan)
 
 	dgs.Subscribed = true
 
-	bot.RedisInterface.SetDiscordGameState(dgs, lock)
+	bot.GameStateStore.SetDiscordGameState(dgs, lock)
 
 	bot.ChannelsMapLock.Lock()
 	bot.EndGameChannels[connectCode] = killChan
@@ -561,8 +570,8 @@ func (bot *Bot) handleNewGameMessage(m *discordgo.MessageCreate, g *discordgo.Gu
 	return "", nil
 }
 
-func (bot *Bot) handleGameStartMessage(m *discordgo.MessageCreate, sett *settings.GuildSettings, channel TrackingChannel, g *discordgo.Guild, connCode string) {
-	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(GameStateRequest{
+func (bot *Bot) handleGameStartMessage(m *discordgo.MessageCreate, sett *storage.GuildSettings, channel TrackingChannel, g *discordgo.Guild, connCode string) {
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(GameStateRequest{
 		GuildID:     m.GuildID,
 		TextChannel: m.ChannelID,
 		ConnectCode: connCode,
@@ -589,13 +598,7 @@ func (bot *Bot) handleGameStartMessage(m *discordgo.MessageCreate, sett *setting
 		}
 	}
 
-	dgs.CreateMessage(bot.PrimarySession, bot.gameStateResponse(dgs, sett), m.ChannelID, m.Author.ID)
-
-	bot.RedisInterface.SetDiscordGameState(dgs, lock)
-
-	// log.Println("Added self game state message")
-	// +18 emojis, 1 for X
-	metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.ReactionAdd, 19)
+	dgs.CreateMessage(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett), m.ChannelID, m.Author.ID)
 
-	go dgs.AddAllReactions(bot.PrimarySession, bot.StatusEmojis[true])
+	bot.GameStateStore.SetDiscordGameState(dgs, lock)
 }
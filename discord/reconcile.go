@@ -0,0 +1,148 @@
+package discord
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/automuteus/automuteus/metrics"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/task"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// ReconcileInterval is how often the voice states of every running game are reconciled
+	ReconcileInterval = 30 * time.Second
+	// ReconcileLockTimeout is how long reconciling a game keeps other voice changes to it waiting, at most
+	ReconcileLockTimeout = 5 * time.Second
+)
+
+// subscribedGameRequests are the games this instance is currently running
+func (bot *Bot) subscribedGameRequests() []GameStateRequest {
+	bot.ChannelsMapLock.RLock()
+	defer bot.ChannelsMapLock.RUnlock()
+	gsrs := make([]GameStateRequest, 0, len(bot.subscribedGames))
+	for _, gsr := range bot.subscribedGames {
+		gsrs = append(gsrs, gsr)
+	}
+	return gsrs
+}
+
+// galactusDegradedChanged shows (or hides) the warning on the game messages when Galactus starts failing (or
+// recovers). Once it recovers, the mutes/deafens that failed meanwhile are re-applied
+func (bot *Bot) galactusDegradedChanged(degraded bool) {
+	if degraded {
+		bot.logger.Warn("Galactus is failing; muting is degraded")
+	} else {
+		bot.logger.Info("Galactus recovered; reconciling the voice states of the running games")
+	}
+	for _, gsr := range bot.subscribedGameRequests() {
+		if !degraded {
+			bot.reconcileVoiceStates(gsr, metrics.ReconcileRecovery)
+		}
+		dgs := bot.GameStateStore.GetReadOnlyDiscordGameState(gsr)
+		if dgs == nil {
+			continue
+		}
+		sett := bot.StorageInterface.GetGuildSettings(gsr.GuildID)
+		edited := dgs.Edit(bot.PrimarySession, bot.gameStateResponse(dgs, sett), bot.gameStateComponents(sett))
+		if edited {
+			metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageEdit, 1)
+		}
+	}
+}
+
+// reconcileVoiceStates fixes the mute/deafen state of the players of a game whose actual voice state (in the discordgo
+// state cache) drifted from the one they should be in, like when a request was dropped, or failed while Galactus was
+// down. Returns how many players had drifted
+func (bot *Bot) reconcileVoiceStates(gsr GameStateRequest, source string) int {
+	metrics.VoiceReconciliations.WithLabelValues(source).Inc()
+
+	// if the voice changes are locked, a transition is applying the mutes already (or waiting on its delay)
+	voiceLock := bot.GameStateStore.LockVoiceChanges(gsr.ConnectCode, ReconcileLockTimeout)
+	if voiceLock == nil {
+		return 0
+	}
+	dgs, users := bot.voiceStateDrift(gsr)
+	if len(users) == 0 {
+		voiceLock.Release(ctx)
+		return 0
+	}
+	metrics.VoiceDrift.WithLabelValues(source).Add(float64(len(users)))
+	logger := bot.gameLogger(dgs)
+	logger.Info("reconciling drifted voice states", "users", len(users), "source", source)
+	bot.issueMutesAndRecord(logger, dgs.GuildID, dgs.ConnectCode, task.UserModifyRequest{
+		Premium: bot.getPremiumTier(dgs.GuildID),
+		Users:   users,
+	}, voiceLock, metrics.NoTransition)
+	return len(users)
+}
+
+// voiceStateDrift lists the users of a game whose actual voice state isn't the one they should be in, along with
+// the mute/deafen state to apply to them
+func (bot *Bot) voiceStateDrift(gsr GameStateRequest) (*GameState, []task.UserModify) {
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
+	for lock == nil {
+		lock, dgs = bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
+	}
+
+	g, err := bot.PrimarySession.State.Guild(dgs.GuildID)
+	if !dgs.Running || err != nil || g == nil {
+		lock.Release(ctx)
+		return dgs, nil
+	}
+	sett := dgs.gameSettings(bot.gameLogger(dgs), bot.StorageInterface.GetGuildSettings(dgs.GuildID))
+
+	users := driftedUsers(dgs, g.VoiceStates, sett)
+	bot.GameStateStore.SetDiscordGameState(dgs, lock)
+	return dgs, users
+}
+
+// driftedUsers compares the actual voice state of every tracked member handled by the bot against the one they should be
+// in, and updates the state they should be in for the ones that drifted. Members in other voice channels are left alone,
+// so a moderator's server mute elsewhere in the guild isn't undone; leaving the tracked channels is handled on transitions
+func driftedUsers(dgs *GameState, voiceStates []*discordgo.VoiceState, sett *storage.GuildSettings) []task.UserModify {
+	var users []task.UserModify
+	for _, voiceState := range voiceStates {
+		userData, err := dgs.GetUser(voiceState.UserID)
+		if err != nil {
+			continue
+		}
+		role, tracked := dgs.TrackedChannelRole(voiceState.ChannelID)
+		if !tracked {
+			continue
+		}
+		auData, found := dgs.AmongUsData.GetByName(userData.InGameName)
+		mute, deaf, handled := getVoiceState(sett, voiceState.UserID, tracked, role, auData, found, dgs.AmongUsData.GetPhase())
+		// only the users the bot handles; never touch exempt users
+		if !handled || (voiceState.Mute == mute && voiceState.Deaf == deaf) {
+			continue
+		}
+		uid, _ := strconv.ParseUint(voiceState.UserID, 10, 64)
+		users = append(users, task.UserModify{
+			UserID: uid,
+			Mute:   mute,
+			Deaf:   deaf,
+		})
+		userData.SetShouldBeMuteDeaf(mute, deaf)
+		dgs.UpdateUserData(userData.User.UserID, userData)
+	}
+	return users
+}
+
+// voiceReconcileWorker periodically reconciles the voice states of a game, until done is closed
+func (bot *Bot) voiceReconcileWorker(gsr GameStateRequest, dur time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(dur)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			// Galactus failing would just report the same drift over and over; it's reconciled once it recovers
+			if !bot.VoiceModifier.Degraded() {
+				bot.reconcileVoiceStates(gsr, metrics.ReconcilePeriodic)
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/game"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestDriftedUsers(t *testing.T) {
+	sett := storage.MakeGuildSettings("")
+	dgs := NewDiscordGameState("1")
+	dgs.Tracking = TrackingChannel{ChannelID: "754465589958803100", ChannelName: "Among Us"}
+	dgs.AmongUsData.UpdatePhase(game.TASKS)
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		dgs.AmongUsData.PlayerData[name] = amongus.PlayerData{Name: name, IsAlive: true}
+	}
+	dgs.AmongUsData.PlayerData["Dave"] = amongus.PlayerData{Name: "Dave", IsAlive: true}
+	for id, name := range map[string]string{"1": "Alice", "2": "Bob", "3": "Carol", "5": "Dave"} {
+		user := MakeUserDataFromDiscordUser(&discordgo.User{ID: id}, "")
+		user.Link(amongus.PlayerData{Name: name})
+		dgs.UpdateUserData(id, user)
+	}
+	sett.SetVoiceOverride("3", storage.OverrideExempt)
+
+	voiceStates := []*discordgo.VoiceState{
+		{UserID: "1", ChannelID: "754465589958803100"},
+		{UserID: "2", ChannelID: "754465589958803100", Mute: true, Deaf: true},
+		{UserID: "3", ChannelID: "754465589958803100"},
+		{UserID: "4", ChannelID: "754465589958803100"},
+		// muted by a moderator in another channel of the guild
+		{UserID: "5", ChannelID: "754465589958803999", Mute: true},
+	}
+	users := driftedUsers(dgs, voiceStates, sett)
+	if len(users) != 1 {
+		t.Fatal("Expected only the undeafened alive player to have drifted, got", users)
+	}
+	if users[0].UserID != 1 || !users[0].Mute || !users[0].Deaf {
+		t.Error("The drifted player should be muted and deafened", users[0])
+	}
+	if user, _ := dgs.GetUser("1"); !user.ShouldBeMute || !user.ShouldBeDeaf {
+		t.Error("The state the drifted player should be in should be updated")
+	}
+}
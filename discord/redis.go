@@ -12,6 +12,7 @@ import (
 	"github.com/bwmarrin/discordgo"
 	"github.com/go-redis/redis/v8"
 	"log"
+	"strings"
 	"time"
 )
 
@@ -29,6 +30,8 @@ type RedisInterface struct {
 	client *redis.Client
 }
 
+var _ GameStateStore = &RedisInterface{}
+
 func (redisInterface *RedisInterface) Init(params interface{}) error {
 	redisParams := params.(storage.RedisParameters)
 	rdb := redis.NewClient(&redis.Options{
@@ -89,7 +92,7 @@ type GameStateRequest struct {
 	ConnectCode  string
 }
 
-func (redisInterface *RedisInterface) LockVoiceChanges(connectCode string, dur time.Duration) *redislock.Lock {
+func (redisInterface *RedisInterface) LockVoiceChanges(connectCode string, dur time.Duration) Lock {
 	locker := redislock.New(redisInterface.client)
 	lock, err := locker.Obtain(ctx, rediskey.VoiceChangesForGameCodeLock(connectCode), dur, &redislock.Options{
 		RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(time.Millisecond*LinearBackoffMs), MaxRetries),
@@ -120,7 +123,7 @@ func (redisInterface *RedisInterface) GetReadOnlyDiscordGameState(gsr GameStateR
 	return dgs
 }
 
-func (redisInterface *RedisInterface) GetDiscordGameStateAndLock(gsr GameStateRequest) (*redislock.Lock, *GameState) {
+func (redisInterface *RedisInterface) GetDiscordGameStateAndLock(gsr GameStateRequest) (Lock, *GameState) {
 	key := redisInterface.getDiscordGameStateKey(gsr)
 	locker := redislock.New(redisInterface.client)
 	lock, err := locker.Obtain(ctx, key+":lock", time.Millisecond*LockTimeoutMs, &redislock.Options{
@@ -171,7 +174,7 @@ func (redisInterface *RedisInterface) CheckPointer(pointer string) string {
 	return key
 }
 
-func (redisInterface *RedisInterface) SetDiscordGameState(data *GameState, lock *redislock.Lock) {
+func (redisInterface *RedisInterface) SetDiscordGameState(data *GameState, lock Lock) {
 	if data == nil {
 		if lock != nil {
 			lock.Release(ctx)
@@ -221,8 +224,8 @@ func (redisInterface *RedisInterface) SetDiscordGameState(data *GameState, lock
 		}
 	}
 
-	if data.Tracking.ChannelID != "" {
-		err = redisInterface.client.Set(ctx, rediskey.VoiceChannelPtr(data.GuildID, data.Tracking.ChannelID), key, GameTimeoutSeconds*time.Second).Err()
+	for _, c := range data.TrackedChannels() {
+		err = redisInterface.client.Set(ctx, rediskey.VoiceChannelPtr(data.GuildID, c.ChannelID), key, GameTimeoutSeconds*time.Second).Err()
 		if err != nil {
 			log.Println(err)
 		}
@@ -282,6 +285,131 @@ func (redisInterface *RedisInterface) LoadAllActiveGames(guildID string) []strin
 	return games
 }
 
+// OrphanedGamesSet holds the games (as guildID:connectCode) waiting to be resumed by another instance
+const OrphanedGamesSet = "automuteus:games:orphaned"
+
+func (redisInterface *RedisInterface) AddOrphanedGame(guildID, connectCode string) {
+	err := redisInterface.client.SAdd(ctx, OrphanedGamesSet, guildID+":"+connectCode).Err()
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (redisInterface *RedisInterface) RemoveOrphanedGame(guildID, connectCode string) bool {
+	removed, err := redisInterface.client.SRem(ctx, OrphanedGamesSet, guildID+":"+connectCode).Result()
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	return removed > 0
+}
+
+func (redisInterface *RedisInterface) LoadOrphanedGames() []GameStateRequest {
+	members, err := redisInterface.client.SMembers(ctx, OrphanedGamesSet).Result()
+	if err != nil {
+		log.Println(err)
+		return []GameStateRequest{}
+	}
+	games := make([]GameStateRequest, 0, len(members))
+	for _, member := range members {
+		parts := strings.SplitN(member, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		games = append(games, GameStateRequest{GuildID: parts[0], ConnectCode: parts[1]})
+	}
+	return games
+}
+
+// GameLeasesHash maps the connect code of every leased game to its guild, so expired leases can be taken over
+const GameLeasesHash = "automuteus:games:leases"
+
+func gameLeaseKey(connectCode string) string {
+	return "automuteus:games:lease:" + connectCode
+}
+
+var acquireGameLeaseScript = redis.NewScript(`
+local owner = redis.call("GET", KEYS[1])
+if owner == false or owner == ARGV[1] then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	redis.call("HSET", KEYS[2], ARGV[3], ARGV[4])
+	return 1
+end
+return 0`)
+
+var renewGameLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+return 0`)
+
+var releaseGameLeaseScript = redis.NewScript(`
+local owner = redis.call("GET", KEYS[1])
+if owner == false or owner == ARGV[1] then
+	redis.call("DEL", KEYS[1])
+	redis.call("HDEL", KEYS[2], ARGV[2])
+	return 1
+end
+return 0`)
+
+func (redisInterface *RedisInterface) AcquireGameLease(gsr GameStateRequest, ownerID string, ttl time.Duration) bool {
+	acquired, err := acquireGameLeaseScript.Run(ctx, redisInterface.client,
+		[]string{gameLeaseKey(gsr.ConnectCode), GameLeasesHash},
+		ownerID, ttl.Milliseconds(), gsr.ConnectCode, gsr.GuildID).Int()
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	return acquired == 1
+}
+
+func (redisInterface *RedisInterface) RenewGameLease(connectCode, ownerID string, ttl time.Duration) bool {
+	renewed, err := renewGameLeaseScript.Run(ctx, redisInterface.client,
+		[]string{gameLeaseKey(connectCode)},
+		ownerID, ttl.Milliseconds()).Int()
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	return renewed == 1
+}
+
+func (redisInterface *RedisInterface) ReleaseGameLease(connectCode, ownerID string) {
+	err := releaseGameLeaseScript.Run(ctx, redisInterface.client,
+		[]string{gameLeaseKey(connectCode), GameLeasesHash},
+		ownerID, connectCode).Err()
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (redisInterface *RedisInterface) LoadExpiredGameLeases() []GameStateRequest {
+	leases, err := redisInterface.client.HGetAll(ctx, GameLeasesHash).Result()
+	if err != nil {
+		log.Println(err)
+		return []GameStateRequest{}
+	}
+	pipe := redisInterface.client.Pipeline()
+	exists := make(map[string]*redis.IntCmd, len(leases))
+	for connectCode := range leases {
+		exists[connectCode] = pipe.Exists(ctx, gameLeaseKey(connectCode))
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		log.Println(err)
+		return []GameStateRequest{}
+	}
+
+	games := []GameStateRequest{}
+	for connectCode, guildID := range leases {
+		if exists[connectCode].Val() == 0 {
+			games = append(games, GameStateRequest{GuildID: guildID, ConnectCode: connectCode})
+		}
+	}
+	return games
+}
+
 func (redisInterface *RedisInterface) DeleteDiscordGameState(dgs *GameState) {
 	guildID := dgs.GuildID
 	connCode := dgs.ConnectCode
@@ -313,9 +441,11 @@ func (redisInterface *RedisInterface) DeleteDiscordGameState(dgs *GameState) {
 	if err != nil {
 		log.Println(err)
 	}
-	err = redisInterface.client.Del(ctx, rediskey.VoiceChannelPtr(guildID, data.Tracking.ChannelID)).Err()
-	if err != nil {
-		log.Println(err)
+	for _, c := range data.TrackedChannels() {
+		err = redisInterface.client.Del(ctx, rediskey.VoiceChannelPtr(guildID, c.ChannelID)).Err()
+		if err != nil {
+			log.Println(err)
+		}
 	}
 	err = redisInterface.client.Del(ctx, rediskey.ConnectCodePtr(guildID, data.ConnectCode)).Err()
 	if err != nil {
@@ -404,7 +534,7 @@ func (redisInterface *RedisInterface) setUsernameOrUserIDMappings(guildID, key s
 	return err
 }
 
-func (redisInterface *RedisInterface) LockSnowflake(snowflake string) *redislock.Lock {
+func (redisInterface *RedisInterface) LockSnowflake(snowflake string) Lock {
 	locker := redislock.New(redisInterface.client)
 	lock, err := locker.Obtain(ctx, rediskey.SnowflakeLockID(snowflake), time.Millisecond*SnowflakeLockMs, nil)
 	if errors.Is(err, redislock.ErrNotObtained) {
@@ -0,0 +1,359 @@
+package discord
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/automuteus/automuteus/logging"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/game"
+	storageutils "github.com/automuteus/utils/pkg/storage"
+	"github.com/automuteus/utils/pkg/task"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	replayBotUserID = "1"
+	replayMessageID = "2"
+)
+
+// ReplayMember is a member of the guild that is in voice when the replay starts
+type ReplayMember struct {
+	UserID   string `json:"userID"`
+	Username string `json:"username"`
+	Nick     string `json:"nick"`
+	// ChannelID is the voice channel the member is in; defaults to the tracked channel
+	ChannelID string `json:"channelID"`
+	// PlayerName is the in-game name the member was previously linked to, if any. Linked members are paired
+	// automatically when the player joins, just like they are by the live bot
+	PlayerName string `json:"playerName"`
+}
+
+// ReplayConfig describes the guild a recorded game is replayed against
+type ReplayConfig struct {
+	GuildID        string         `json:"guildID"`
+	ConnectCode    string         `json:"connectCode"`
+	TextChannelID  string         `json:"textChannelID"`
+	VoiceChannelID string         `json:"voiceChannelID"`
+	Members        []ReplayMember `json:"members"`
+
+	// Settings defaults to the settings of a new guild
+	Settings *storage.GuildSettings `json:"settings"`
+}
+
+// ReplayDecision is a single mute/deafen request the bot sent to Galactus while replaying
+type ReplayDecision struct {
+	// Event is the index of the job that caused the request
+	Event   int          `json:"event"`
+	JobType task.JobType `json:"jobType"`
+	Phase   game.Phase   `json:"phase"`
+	// Delay is the number of seconds the bot would have waited before sending the request. Replays don't wait
+	Delay int               `json:"delay"`
+	Users []task.UserModify `json:"users"`
+}
+
+func (d ReplayDecision) String() string {
+	buf := bytes.NewBuffer([]byte{})
+	buf.WriteString(fmt.Sprintf("#%d %s (delay %ds):", d.Event, game.PhaseNames[d.Phase], d.Delay))
+	for _, v := range d.Users {
+		buf.WriteString(fmt.Sprintf(" %d[mute=%t deaf=%t]", v.UserID, v.Mute, v.Deaf))
+	}
+	return buf.String()
+}
+
+// ReadReplayJobs reads jobs from JSONL, one job per line in the same format the capture pushes to Redis:
+// {"type": 2, "payload": "1"}
+func ReadReplayJobs(r io.Reader) ([]task.Job, error) {
+	jobs := []task.Job{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		job := task.Job{}
+		err := json.Unmarshal([]byte(text), &job)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if _, ok := job.Payload.(string); !ok {
+			return nil, fmt.Errorf("line %d: payload must be a string", line)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, scanner.Err()
+}
+
+// ReplayJobsFromPostgres fetches the recorded events of a match. Note that events are only recorded once the
+// match has started, so players that joined in the lobby beforehand won't be linked unless they're listed as
+// ReplayMembers with a PlayerName
+func ReplayJobsFromPostgres(psql *storageutils.PsqlInterface, matchID string) ([]task.Job, error) {
+	events, err := psql.GetGameEvents(matchID)
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]task.Job, len(events))
+	for i, v := range events {
+		jobs[i] = task.Job{
+			JobType: task.JobType(v.EventType),
+			Payload: v.Payload,
+		}
+	}
+	return jobs, nil
+}
+
+// Replay feeds recorded jobs through the same processing as SubscribeToGameByConnectCode, against a stub
+// Discord session and a stub Galactus, and returns every mute/deafen request the bot made along the way
+func Replay(config ReplayConfig, jobs []task.Job) ([]ReplayDecision, error) {
+	if config.GuildID == "" || config.ConnectCode == "" || config.VoiceChannelID == "" {
+		return nil, errors.New("replaying requires a guildID, connectCode and voiceChannelID")
+	}
+	if config.TextChannelID == "" {
+		config.TextChannelID = config.VoiceChannelID
+	}
+
+	sett := config.Settings
+	if sett == nil {
+		sett = storage.MakeGuildSettings("")
+	}
+	// work on a copy; the delays are recorded on each decision instead of being waited out
+	replaySett := storage.GuildSettings{}
+	jBytes, err := json.Marshal(sett)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(jBytes, &replaySett)
+	if err != nil {
+		return nil, err
+	}
+	replaySett.Delays = game.GameDelays{}
+
+	galactus := newReplayGalactus()
+	defer galactus.Close()
+
+	sess, err := newReplaySession(config)
+	if err != nil {
+		return nil, err
+	}
+
+	store := NewMemoryGameStateStore()
+	// like the standard logger, so the replay's own output isn't mixed with the logs
+	logger := logging.New(os.Stderr, logging.LevelInfo, logging.FormatLogfmt)
+	bot := &Bot{
+		ConnsToGames:    make(map[string]string),
+		StatusEmojis:    emptyStatusEmojis(),
+		EndGameChannels: make(map[string]chan EndGameMessage),
+		ChannelsMapLock: sync.RWMutex{},
+		subscribedGames: make(map[string]GameStateRequest),
+		PrimarySession:  sess,
+		VoiceModifier: &GalactusClient{
+			Address: galactus.URL,
+			client:  galactus.Client(),
+			logger:  logger,
+			breaker: newCircuitBreaker(GalactusFailureThreshold, GalactusBreakerCooldown),
+
+			latestRequests: make(map[string]uint64),
+		},
+		// no Redis client; metrics aren't recorded
+		RedisInterface: &RedisInterface{},
+		GameStateStore: store,
+		logger:         logger,
+		captureTimeout: GameTimeoutSeconds,
+	}
+
+	dgs := NewDiscordGameState(config.GuildID)
+	dgs.ConnectCode = config.ConnectCode
+	dgs.Running = true
+	dgs.Subscribed = true
+	dgs.Tracking.ChannelID = config.VoiceChannelID
+	dgs.GameStateMsg.MessageID = replayMessageID
+	dgs.GameStateMsg.MessageChannelID = config.TextChannelID
+	dgs.GameStateMsg.MessageAuthorID = replayBotUserID
+	for _, v := range sess.State.Guilds[0].Members {
+		dgs.UserData[v.User.ID] = MakeUserDataFromDiscordUser(v.User, v.Nick)
+	}
+	store.SetDiscordGameState(dgs, nil)
+
+	for _, v := range config.Members {
+		if v.PlayerName != "" {
+			err := store.AddUsernameLink(config.GuildID, v.UserID, v.PlayerName)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	dgsRequest := GameStateRequest{
+		GuildID:     config.GuildID,
+		ConnectCode: config.ConnectCode,
+	}
+
+	decisions := []ReplayDecision{}
+	for i, job := range jobs {
+		oldPhase := store.GetReadOnlyDiscordGameState(dgsRequest).AmongUsData.GetPhase()
+
+		bot.processJob(bot.logger, &replaySett, job, dgsRequest)
+
+		phase := store.GetReadOnlyDiscordGameState(dgsRequest).AmongUsData.GetPhase()
+		delay := 0
+		if job.JobType == task.StateJob {
+			// gameover uses the same delay as returning to the lobby; see processTransition
+			dest := phase
+			if dest == game.GAMEOVER {
+				dest = game.LOBBY
+			}
+			delay = sett.Delays.GetDelay(oldPhase, dest)
+		}
+
+		for _, req := range galactus.take() {
+			decisions = append(decisions, ReplayDecision{
+				Event:   i,
+				JobType: job.JobType,
+				Phase:   phase,
+				Delay:   delay,
+				Users:   req.Users,
+			})
+		}
+	}
+	return decisions, nil
+}
+
+// replayGalactus records the modify requests it receives instead of muting anyone
+type replayGalactus struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	requests []task.UserModifyRequest
+}
+
+func newReplayGalactus() *replayGalactus {
+	galactus := &replayGalactus{
+		requests: []task.UserModifyRequest{},
+	}
+	galactus.Server = httptest.NewServer(http.HandlerFunc(galactus.handleModify))
+	return galactus
+}
+
+func (galactus *replayGalactus) handleModify(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, "/modify/") {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	req := task.UserModifyRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	galactus.lock.Lock()
+	galactus.requests = append(galactus.requests, req)
+	galactus.lock.Unlock()
+
+	jBytes, err := json.Marshal(task.MuteDeafenSuccessCounts{
+		Official: int64(len(req.Users)),
+	})
+	if err != nil {
+		log.Println(err)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(jBytes)
+}
+
+// take returns (and forgets) the requests received since the last call
+func (galactus *replayGalactus) take() []task.UserModifyRequest {
+	galactus.lock.Lock()
+	defer galactus.lock.Unlock()
+
+	reqs := galactus.requests
+	galactus.requests = []task.UserModifyRequest{}
+	return reqs
+}
+
+// replayTransport answers every Discord API request with the same canned message, so edits and refreshes of
+// the game state message succeed without ever reaching Discord
+type replayTransport struct {
+	body []byte
+}
+
+func (t replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     strconv.Itoa(http.StatusOK) + " " + http.StatusText(http.StatusOK),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(bytes.NewReader(t.body)),
+		Request:    req,
+	}, nil
+}
+
+func newReplaySession(config ReplayConfig) (*discordgo.Session, error) {
+	sess, err := discordgo.New("Bot replay")
+	if err != nil {
+		return nil, err
+	}
+	botUser := &discordgo.User{
+		ID:       replayBotUserID,
+		Username: "AutoMuteUs",
+		Bot:      true,
+	}
+	body, err := json.Marshal(discordgo.Message{
+		ID:        replayMessageID,
+		ChannelID: config.TextChannelID,
+		GuildID:   config.GuildID,
+		Author:    botUser,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sess.Client = &http.Client{
+		Transport: replayTransport{body: body},
+	}
+	sess.State.User = botUser
+
+	guild := &discordgo.Guild{
+		ID:          config.GuildID,
+		Members:     []*discordgo.Member{},
+		VoiceStates: []*discordgo.VoiceState{},
+	}
+	for _, v := range config.Members {
+		guild.Members = append(guild.Members, &discordgo.Member{
+			GuildID: config.GuildID,
+			Nick:    v.Nick,
+			User: &discordgo.User{
+				ID:       v.UserID,
+				Username: v.Username,
+			},
+		})
+		channelID := v.ChannelID
+		if channelID == "" {
+			channelID = config.VoiceChannelID
+		}
+		guild.VoiceStates = append(guild.VoiceStates, &discordgo.VoiceState{
+			GuildID:   config.GuildID,
+			ChannelID: channelID,
+			UserID:    v.UserID,
+		})
+	}
+	err = sess.State.GuildAdd(guild)
+	if err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
@@ -0,0 +1,88 @@
+package discord
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/automuteus/utils/pkg/game"
+	"github.com/automuteus/utils/pkg/task"
+)
+
+func TestReadReplayJobs(t *testing.T) {
+	jobs, err := ReadReplayJobs(strings.NewReader("{\"type\": 2, \"payload\": \"1\"}\n\n{\"type\": 0, \"payload\": \"true\"}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 || jobs[0].JobType != task.StateJob || jobs[0].Payload != "1" {
+		t.Error("Jobs weren't read correctly")
+	}
+
+	_, err = ReadReplayJobs(strings.NewReader("{\"type\": 2, \"payload\": 1}"))
+	if err == nil {
+		t.Error("Non-string payloads should be rejected")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	f, err := os.Open("testdata/replay.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	jobs, err := ReadReplayJobs(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decisions, err := Replay(ReplayConfig{
+		GuildID:        "10",
+		ConnectCode:    "ABCDEFGH",
+		VoiceChannelID: "20",
+		Members: []ReplayMember{
+			{UserID: "100", Username: "alice"},
+			// only linked through the username cache
+			{UserID: "200", Username: "robert", PlayerName: "Bob"},
+			// not in the tracked channel
+			{UserID: "300", Username: "carol", ChannelID: "21", PlayerName: "Carol"},
+		},
+	}, jobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		event int
+		phase game.Phase
+		users map[uint64][2]bool
+	}{
+		{5, game.TASKS, map[uint64][2]bool{100: {true, true}, 200: {true, true}}},
+		// Bob dying during tasks doesn't unmute him; the dead are muted first when discussion starts
+		{7, game.DISCUSS, map[uint64][2]bool{200: {true, false}}},
+		{7, game.DISCUSS, map[uint64][2]bool{100: {false, false}}},
+		// the alive are muted first when tasks resume
+		{8, game.TASKS, map[uint64][2]bool{100: {true, true}}},
+		{8, game.TASKS, map[uint64][2]bool{200: {false, false}}},
+		{9, game.LOBBY, map[uint64][2]bool{100: {false, false}}},
+	}
+	if len(decisions) != len(expected) {
+		t.Fatalf("Expected %d decisions, got %d: %v", len(expected), len(decisions), decisions)
+	}
+	for i, v := range expected {
+		d := decisions[i]
+		if d.Event != v.event || d.Phase != v.phase || len(d.Users) != len(v.users) {
+			t.Errorf("Decision %d was %s", i, d.String())
+			continue
+		}
+		for _, u := range d.Users {
+			if state, ok := v.users[u.UserID]; !ok || state[0] != u.Mute || state[1] != u.Deaf {
+				t.Errorf("Decision %d was %s", i, d.String())
+			}
+		}
+	}
+
+	// delays are recorded using the guild's settings instead of being waited out
+	if decisions[0].Delay != 7 {
+		t.Errorf("Expected the default lobby->tasks delay to be recorded, got %d", decisions[0].Delay)
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/automuteus/utils/pkg/discord"
-	"github.com/automuteus/utils/pkg/settings"
 	"log"
 	"strings"
 	"time"
@@ -27,7 +27,7 @@ const ISO8601 = "2006-01-02T15:04:05-0700"
 
 const BasePremiumURL = "https://automute.us/premium?guild="
 
-func helpResponse(isAdmin, isPermissioned bool, commands []Command, sett *settings.GuildSettings) discordgo.MessageEmbed {
+func helpResponse(isAdmin, isPermissioned bool, commands []Command, sett *storage.GuildSettings) discordgo.MessageEmbed {
 	embed := discordgo.MessageEmbed{
 		URL:  "",
 		Type: "",
@@ -81,7 +81,7 @@ func helpResponse(isAdmin, isPermissioned bool, commands []Command, sett *settin
 	return embed
 }
 
-func settingResponse(commandPrefix string, settings []setting.Setting, sett *settings.GuildSettings, prem bool) *discordgo.MessageEmbed {
+func settingResponse(commandPrefix string, settings []setting.Setting, sett *storage.GuildSettings, prem bool) *discordgo.MessageEmbed {
 	embed := discordgo.MessageEmbed{
 		URL:  "",
 		Type: "",
@@ -156,7 +156,7 @@ func settingResponse(commandPrefix string, settings []setting.Setting, sett *set
 	return &embed
 }
 
-func (bot *Bot) infoResponse(guildID string, sett *settings.GuildSettings) *discordgo.MessageEmbed {
+func (bot *Bot) infoResponse(guildID string, sett *storage.GuildSettings) *discordgo.MessageEmbed {
 	version, commit := rediskey.GetVersionAndCommit(context.Background(), bot.RedisInterface.client)
 	if strings.HasPrefix(version, "6.9") {
 		version = "😎 " + version + " 😎"
@@ -301,19 +301,107 @@ func (bot *Bot) infoResponse(guildID string, sett *settings.GuildSettings) *disc
 	return &embed
 }
 
-func (bot *Bot) gameStateResponse(dgs *GameState, sett *settings.GuildSettings) *discordgo.MessageEmbed {
+func (bot *Bot) gameStateResponse(dgs *GameState, sett *storage.GuildSettings) *discordgo.MessageEmbed {
 	// we need to generate the messages based on the state of the game
-	messages := map[game.Phase]func(dgs *GameState, emojis AlivenessEmojis, sett *settings.GuildSettings) *discordgo.MessageEmbed{
+	messages := map[game.Phase]func(dgs *GameState, emojis AlivenessEmojis, sett *storage.GuildSettings) *discordgo.MessageEmbed{
 		game.MENU:     menuMessage,
 		game.LOBBY:    lobbyMessage,
 		game.TASKS:    gamePlayMessage,
 		game.DISCUSS:  gamePlayMessage,
 		game.GAMEOVER: gamePlayMessage,
 	}
-	return messages[dgs.AmongUsData.Phase](dgs, bot.StatusEmojis, sett)
+	embed := messages[dgs.AmongUsData.Phase](dgs, bot.StatusEmojis, sett)
+	if embed != nil && dgs.Settings.Mode != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: sett.LocalizeMessage(&i18n.Message{
+				ID:    "responses.gameStateResponse.mode",
+				Other: "🎛 Mode",
+			}),
+			Value:  dgs.Settings.Mode,
+			Inline: false,
+		})
+	}
+	if embed != nil && len(dgs.Waitlist.Queue) > 0 {
+		embed.Fields = append(embed.Fields, waitlistEmbedField(dgs, sett))
+	}
+	if embed != nil && bot.VoiceModifier.Degraded() {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: sett.LocalizeMessage(&i18n.Message{
+				ID:    "responses.gameStateResponse.degraded.Name",
+				Other: "⚠️ Muting Degraded",
+			}),
+			Value: sett.LocalizeMessage(&i18n.Message{
+				ID:    "responses.gameStateResponse.degraded.Value",
+				Other: "I'm having trouble muting and unmuting players right now. I'll fix everyone's mutes as soon as I can!",
+			}),
+			Inline: false,
+		})
+	}
+	return embed
 }
 
-func lobbyMetaEmbedFields(room, region string, author, voiceChannelID string, playerCount int, linkedPlayers int, sett *settings.GuildSettings) []*discordgo.MessageEmbedField {
+// gameStateComponents are the color select menu and unlink button attached to the game state message.
+// Unlike reactions, they cost no extra API calls and don't rely on the guild hosting our custom emojis
+func (bot *Bot) gameStateComponents(sett *storage.GuildSettings) []discordgo.MessageComponent {
+	options := make([]discordgo.SelectMenuOption, 0, len(bot.StatusEmojis[true]))
+	for i, e := range bot.StatusEmojis[true] {
+		color := game.GetColorStringForInt(i)
+		option := discordgo.SelectMenuOption{
+			Label: strings.Title(color),
+			Value: color,
+		}
+		if e.ID != "" {
+			option.Emoji = discordgo.ComponentEmoji{
+				Name: e.Name,
+				ID:   e.ID,
+			}
+		}
+		options = append(options, option)
+	}
+
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					CustomID: colorSelectID,
+					Placeholder: sett.LocalizeMessage(&i18n.Message{
+						ID:    "responses.gameStateComponents.ColorSelect.Placeholder",
+						Other: "Select your in-game color",
+					}),
+					Options: options,
+				},
+			},
+		},
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label: sett.LocalizeMessage(&i18n.Message{
+						ID:    "responses.gameStateComponents.Unlink.Label",
+						Other: "Unlink me",
+					}),
+					Style:    discordgo.SecondaryButton,
+					CustomID: unlinkButtonID,
+					Emoji: discordgo.ComponentEmoji{
+						Name: "❌",
+					},
+				},
+				discordgo.Button{
+					Label: sett.LocalizeMessage(&i18n.Message{
+						ID:    "responses.gameStateComponents.Waitlist.Label",
+						Other: "Join/leave waitlist",
+					}),
+					Style:    discordgo.SecondaryButton,
+					CustomID: waitlistButtonID,
+					Emoji: discordgo.ComponentEmoji{
+						Name: "⏳",
+					},
+				},
+			},
+		},
+	}
+}
+
+func lobbyMetaEmbedFields(room, region string, author, voiceChannels string, playerCount int, linkedPlayers int, sett *storage.GuildSettings) []*discordgo.MessageEmbedField {
 	gameInfoFields := make([]*discordgo.MessageEmbedField, 0)
 	if author != "" {
 		gameInfoFields = append(gameInfoFields, &discordgo.MessageEmbedField{
@@ -325,13 +413,13 @@ func lobbyMetaEmbedFields(room, region string, author, voiceChannelID string, pl
 			Inline: true,
 		})
 	}
-	if voiceChannelID != "" {
+	if voiceChannels != "" {
 		gameInfoFields = append(gameInfoFields, &discordgo.MessageEmbedField{
 			Name: sett.LocalizeMessage(&i18n.Message{
 				ID:    "responses.lobbyMetaEmbedFields.VoiceChannel",
 				Other: "Voice Channel",
 			}),
-			Value:  discord.MentionByChannelID(voiceChannelID),
+			Value:  voiceChannels,
 			Inline: true,
 		})
 	}
@@ -376,7 +464,7 @@ func lobbyMetaEmbedFields(room, region string, author, voiceChannelID string, pl
 	return gameInfoFields
 }
 
-func menuMessage(dgs *GameState, _ AlivenessEmojis, sett *settings.GuildSettings) *discordgo.MessageEmbed {
+func menuMessage(dgs *GameState, _ AlivenessEmojis, sett *storage.GuildSettings) *discordgo.MessageEmbed {
 	color := 15158332 // red
 	desc := ""
 	var footer *discordgo.MessageEmbedFooter
@@ -417,7 +505,7 @@ func menuMessage(dgs *GameState, _ AlivenessEmojis, sett *settings.GuildSettings
 				ID:    "responses.lobbyMetaEmbedFields.VoiceChannel",
 				Other: "Voice Channel",
 			}),
-			Value:  discord.MentionByChannelID(dgs.Tracking.ChannelID),
+			Value:  dgs.trackedChannelsString(),
 			Inline: true,
 		})
 	}
@@ -450,9 +538,9 @@ func menuMessage(dgs *GameState, _ AlivenessEmojis, sett *settings.GuildSettings
 	return &msg
 }
 
-func lobbyMessage(dgs *GameState, emojis AlivenessEmojis, sett *settings.GuildSettings) *discordgo.MessageEmbed {
+func lobbyMessage(dgs *GameState, emojis AlivenessEmojis, sett *storage.GuildSettings) *discordgo.MessageEmbed {
 	room, region, playMap := dgs.AmongUsData.GetRoomRegionMap()
-	gameInfoFields := lobbyMetaEmbedFields(room, region, dgs.GameStateMsg.LeaderID, dgs.Tracking.ChannelID, dgs.AmongUsData.GetNumDetectedPlayers(), dgs.GetCountLinked(), sett)
+	gameInfoFields := lobbyMetaEmbedFields(room, region, dgs.GameStateMsg.LeaderID, dgs.trackedChannelsString(), dgs.AmongUsData.GetNumDetectedPlayers(), dgs.GetCountLinked(), sett)
 
 	listResp := dgs.ToEmojiEmbedFields(emojis, sett)
 	listResp = append(gameInfoFields, listResp...)
@@ -469,7 +557,6 @@ func lobbyMessage(dgs *GameState, emojis AlivenessEmojis, sett *settings.GuildSe
 		})
 	}
 
-	emojiLeave := "❌"
 	msg := discordgo.MessageEmbed{
 		URL:  "",
 		Type: "",
@@ -481,12 +568,9 @@ func lobbyMessage(dgs *GameState, emojis AlivenessEmojis, sett *settings.GuildSe
 		Timestamp:   time.Now().Format(ISO8601),
 		Footer: &discordgo.MessageEmbedFooter{
 			Text: sett.LocalizeMessage(&i18n.Message{
-				ID:    "responses.lobbyMessage.Footer.Text",
-				Other: "React to this message with your in-game color! (or {{.emojiLeave}} to leave)",
-			},
-				map[string]interface{}{
-					"emojiLeave": emojiLeave,
-				}),
+				ID:    "responses.lobbyMessage.Footer.SelectText",
+				Other: "Select your in-game color below! (or Unlink me to leave)",
+			}),
 			IconURL:      "",
 			ProxyIconURL: "",
 		},
@@ -501,7 +585,7 @@ func lobbyMessage(dgs *GameState, emojis AlivenessEmojis, sett *settings.GuildSe
 	return &msg
 }
 
-func gameOverMessage(dgs *GameState, emojis AlivenessEmojis, sett *settings.GuildSettings, winners string) *discordgo.MessageEmbed {
+func gameOverMessage(dgs *GameState, emojis AlivenessEmojis, sett *storage.GuildSettings, winners string) *discordgo.MessageEmbed {
 	_, _, playMap := dgs.AmongUsData.GetRoomRegionMap()
 
 	listResp := dgs.ToEmojiEmbedFields(emojis, sett)
@@ -549,10 +633,10 @@ func gameOverMessage(dgs *GameState, emojis AlivenessEmojis, sett *settings.Guil
 	return &msg
 }
 
-func getThumbnailFromMap(playMap game.PlayMap, sett *settings.GuildSettings) *discordgo.MessageEmbedThumbnail {
+func getThumbnailFromMap(playMap game.PlayMap, sett *storage.GuildSettings) *discordgo.MessageEmbedThumbnail {
 	var thumbNail *discordgo.MessageEmbedThumbnail = nil
 	if playMap != game.EMPTYMAP {
-		mapItem, err := amongus.NewMapItem(game.MapNames[playMap], sett)
+		mapItem, err := amongus.NewMapItem(game.MapNames[playMap], &sett.GuildSettings)
 		if err != nil {
 			log.Println(err)
 		} else {
@@ -570,7 +654,7 @@ func getThumbnailFromMap(playMap game.PlayMap, sett *settings.GuildSettings) *di
 	return thumbNail
 }
 
-func gamePlayMessage(dgs *GameState, emojis AlivenessEmojis, sett *settings.GuildSettings) *discordgo.MessageEmbed {
+func gamePlayMessage(dgs *GameState, emojis AlivenessEmojis, sett *storage.GuildSettings) *discordgo.MessageEmbed {
 	phase := dgs.AmongUsData.GetPhase()
 	playMap := dgs.AmongUsData.GetPlayMap()
 	// send empty fields because we don't need to display those fields during the game...
@@ -578,7 +662,7 @@ func gamePlayMessage(dgs *GameState, emojis AlivenessEmojis, sett *settings.Guil
 	desc := ""
 
 	desc = dgs.makeDescription(sett)
-	gameInfoFields := lobbyMetaEmbedFields("", "", dgs.GameStateMsg.LeaderID, dgs.Tracking.ChannelID, dgs.AmongUsData.GetNumDetectedPlayers(), dgs.GetCountLinked(), sett)
+	gameInfoFields := lobbyMetaEmbedFields("", "", dgs.GameStateMsg.LeaderID, dgs.trackedChannelsString(), dgs.AmongUsData.GetNumDetectedPlayers(), dgs.GetCountLinked(), sett)
 	listResp = append(gameInfoFields, listResp...)
 
 	var color int
@@ -611,7 +695,7 @@ func gamePlayMessage(dgs *GameState, emojis AlivenessEmojis, sett *settings.Guil
 	return &msg
 }
 
-func (dgs *GameState) makeDescription(sett *settings.GuildSettings) string {
+func (dgs *GameState) makeDescription(sett *storage.GuildSettings) string {
 	buf := bytes.NewBuffer([]byte{})
 	if !dgs.Running {
 		buf.WriteString(sett.LocalizeMessage(&i18n.Message{
@@ -625,7 +709,7 @@ func (dgs *GameState) makeDescription(sett *settings.GuildSettings) string {
 	return buf.String()
 }
 
-func premiumEmbedResponse(guildID string, tier premium.Tier, daysRem int, sett *settings.GuildSettings) *discordgo.MessageEmbed {
+func premiumEmbedResponse(guildID string, tier premium.Tier, daysRem int, sett *storage.GuildSettings) *discordgo.MessageEmbed {
 	desc := ""
 	var fields []*discordgo.MessageEmbedField
 
@@ -773,7 +857,7 @@ func premiumEmbedResponse(guildID string, tier premium.Tier, daysRem int, sett *
 	return &msg
 }
 
-func nonPremiumSettingResponse(sett *settings.GuildSettings) string {
+func nonPremiumSettingResponse(sett *storage.GuildSettings) string {
 	return sett.LocalizeMessage(&i18n.Message{
 		ID:    "responses.nonPremiumSetting.Desc",
 		Other: "Sorry, but that setting is reserved for AutoMuteUs Premium users! See `{{.CommandPrefix}} premium` for details",
@@ -789,7 +873,7 @@ var BotInvites = []string{
 	"https://discord.com/api/oauth2/authorize?client_id=780589033033302036&permissions=12582912&scope=bot", // amu4
 	"https://discord.com/api/oauth2/authorize?client_id=780323801173983262&permissions=12582912&scope=bot"} // amu3
 
-func premiumInvitesEmbed(tier premium.Tier, sett *settings.GuildSettings) *discordgo.MessageEmbed {
+func premiumInvitesEmbed(tier premium.Tier, sett *storage.GuildSettings) *discordgo.MessageEmbed {
 	desc := ""
 	fields := []*discordgo.MessageEmbedField{}
 
@@ -848,12 +932,12 @@ func premiumInvitesEmbed(tier premium.Tier, sett *settings.GuildSettings) *disco
 	return &msg
 }
 
-func (bot *Bot) privacyResponse(guildID, authorID, arg string, sett *settings.GuildSettings) *discordgo.MessageEmbed {
+func (bot *Bot) privacyResponse(guildID, authorID, arg string, sett *storage.GuildSettings) *discordgo.MessageEmbed {
 	desc := ""
 
 	switch arg {
 	case "showme":
-		cached := bot.RedisInterface.GetUsernameOrUserIDMappings(guildID, authorID)
+		cached := bot.GameStateStore.GetUsernameOrUserIDMappings(guildID, authorID)
 		if len(cached) == 0 {
 			desc = sett.LocalizeMessage(&i18n.Message{
 				ID:    "commands.HandleCommand.ShowMe.emptyCachedNames",
@@ -894,7 +978,10 @@ func (bot *Bot) privacyResponse(guildID, authorID, arg string, sett *settings.Gu
 			})
 		}
 	case "optout":
-		err := bot.RedisInterface.DeleteLinksByUserID(guildID, authorID)
+		err := bot.GameStateStore.DeleteLinksByUserID(guildID, authorID)
+		if err == nil {
+			err = bot.deleteAllIdentityLinks(authorID)
+		}
 		if err != nil {
 			log.Println(err)
 		} else {
@@ -955,7 +1042,7 @@ func (bot *Bot) privacyResponse(guildID, authorID, arg string, sett *settings.Gu
 	return &msg
 }
 
-func workerEmbedResponse(guildID string, sett *settings.GuildSettings) *discordgo.MessageEmbed {
+func workerEmbedResponse(guildID string, sett *storage.GuildSettings) *discordgo.MessageEmbed {
 	desc := ""
 	fields := []*discordgo.MessageEmbedField{}
 
@@ -0,0 +1,556 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/automuteus/automuteus/metrics"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/discord"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	scheduleRSVPID = "schedule-rsvp"
+
+	// ScheduleInterval is how often the scheduled games are checked for reminders to send, and games to start
+	ScheduleInterval = 30 * time.Second
+	// DefaultScheduleReminderMinutes is how long before a scheduled game the users that RSVP'd are pinged, by default
+	DefaultScheduleReminderMinutes = 15
+	MaxScheduleReminderMinutes     = 24 * 60
+	// MaxScheduleAhead is how far ahead games can be scheduled
+	MaxScheduleAhead = 30 * 24 * time.Hour
+	// MaxScheduledGameDelay is how late a scheduled game still starts, like when no instance was running at the time
+	MaxScheduledGameDelay = 30 * time.Minute
+)
+
+// parseScheduleTime reads the time a game is scheduled for from the start of the args, and returns how many args it
+// used. The time is a delay (like `2h30m`, `7d` or `in 1d2h`), a unix or Discord timestamp (like `<t:1760000000:f>`),
+// or a UTC date (`2026-10-20 20:00`, with an optional `utc` after it)
+func parseScheduleTime(args []string, now time.Time) (time.Time, int, bool) {
+	if len(args) == 0 {
+		return time.Time{}, 0, false
+	}
+	if args[0] == "in" {
+		if len(args) < 2 {
+			return time.Time{}, 0, false
+		}
+		d, ok := parseScheduleDuration(args[1])
+		return now.Add(d), 2, ok
+	}
+
+	arg := args[0]
+	if strings.HasPrefix(arg, "<t:") && strings.HasSuffix(arg, ">") {
+		arg = strings.Split(strings.TrimSuffix(strings.TrimPrefix(arg, "<t:"), ">"), ":")[0]
+	}
+	if unix, err := strconv.ParseInt(arg, 10, 64); err == nil && len(arg) >= 9 {
+		return time.Unix(unix, 0), 1, true
+	}
+	if d, ok := parseScheduleDuration(arg); ok {
+		return now.Add(d), 1, true
+	}
+
+	// args are lowercased, so the T between the date and the time is too
+	t, err := time.Parse("2006-01-02t15:04", arg)
+	used := 1
+	if err != nil && len(args) > 1 {
+		t, err = time.Parse("2006-01-02 15:04", arg+" "+args[1])
+		used = 2
+	}
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	if len(args) > used && args[used] == "utc" {
+		used++
+	}
+	return t, used, true
+}
+
+// parseScheduleDuration is time.ParseDuration, with days too
+func parseScheduleDuration(arg string) (time.Duration, bool) {
+	var days int
+	if i := strings.Index(arg, "d"); i > 0 {
+		var err error
+		days, err = strconv.Atoi(arg[:i])
+		if err != nil {
+			return 0, false
+		}
+		arg = arg[i+1:]
+	}
+	var d time.Duration
+	if arg != "" {
+		var err error
+		d, err = time.ParseDuration(arg)
+		if err != nil {
+			return 0, false
+		}
+	}
+	return time.Duration(days)*24*time.Hour + d, true
+}
+
+func commandFnSchedule(
+	bot *Bot,
+	_ bool,
+	_ bool,
+	sett *storage.GuildSettings,
+	guild *discordgo.Guild,
+	message *discordgo.MessageCreate,
+	args []string,
+	_ *Command,
+) (string, interface{}) {
+	if len(args[1:]) == 0 {
+		return message.ChannelID, bot.scheduledGamesResponse(message.GuildID, sett)
+	}
+	if args[1] == "cancel" {
+		return message.ChannelID, bot.cancelScheduledGame(message.GuildID, sett, args[2:])
+	}
+
+	now := time.Now()
+	startTime, used, ok := parseScheduleTime(args[1:], now)
+	if !ok {
+		return message.ChannelID, sett.LocalizeMessage(&i18n.Message{
+			ID:    "schedule.commandFnSchedule.invalidTime",
+			Other: "Sorry, `{{.Time}}` isn't a time I understand. Use a delay like `2h30m` or `7d`, a UTC date like `2026-10-20 20:00`, or a Discord timestamp",
+		},
+			map[string]interface{}{
+				"Time": args[1],
+			})
+	}
+	if !startTime.After(now) || startTime.Sub(now) > MaxScheduleAhead {
+		return message.ChannelID, sett.LocalizeMessage(&i18n.Message{
+			ID:    "schedule.commandFnSchedule.outOfRange",
+			Other: "Games can only be scheduled in the future, and up to {{.Days}} days ahead",
+		},
+			map[string]interface{}{
+				"Days": int(MaxScheduleAhead.Hours() / 24),
+			})
+	}
+
+	sg := storage.ScheduledGame{
+		GuildID:       message.GuildID,
+		TextChannelID: message.ChannelID,
+		HostID:        message.Author.ID,
+		StartTime:     startTime.Unix(),
+		RemindMinutes: DefaultScheduleReminderMinutes,
+	}
+	for _, arg := range args[1+used:] {
+		if minutes, err := strconv.Atoi(arg); err == nil {
+			if minutes < 0 || minutes > MaxScheduleReminderMinutes {
+				return message.ChannelID, sett.LocalizeMessage(&i18n.Message{
+					ID:    "schedule.commandFnSchedule.invalidReminder",
+					Other: "The reminder has to be between 0 (no reminder) and {{.Max}} minutes before the game",
+				},
+					map[string]interface{}{
+						"Max": MaxScheduleReminderMinutes,
+					})
+			}
+			sg.RemindMinutes = minutes
+			continue
+		}
+		channelID, err := discord.ExtractChannelIDFromMention(arg)
+		if err != nil {
+			channelID = arg
+		}
+		channel, err := bot.PrimarySession.State.Channel(channelID)
+		if err != nil || channel.GuildID != message.GuildID || channel.Type != discordgo.ChannelTypeGuildVoice {
+			return message.ChannelID, sett.LocalizeMessage(&i18n.Message{
+				ID:    "schedule.commandFnSchedule.invalidChannel",
+				Other: "Sorry, `{{.Arg}}` isn't a voice channel of this server",
+			},
+				map[string]interface{}{
+					"Arg": arg,
+				})
+		}
+		sg.VoiceChannelID = channel.ID
+	}
+	// like `new`, the voice channel of whoever scheduled the game, by default
+	if sg.VoiceChannelID == "" {
+		for _, v := range guild.VoiceStates {
+			if v.UserID == message.Author.ID {
+				sg.VoiceChannelID = v.ChannelID
+			}
+		}
+	}
+	if sg.VoiceChannelID == "" {
+		return message.ChannelID, sett.LocalizeMessage(&i18n.Message{
+			ID:    "schedule.commandFnSchedule.noChannel",
+			Other: "Please mention the voice channel of the game, or join it before scheduling the game",
+		})
+	}
+
+	games, err := bot.ScheduleInterface.GetScheduledGames(message.GuildID)
+	if err != nil {
+		bot.logger.Error("failed to get the scheduled games", "guildID", message.GuildID, "err", err)
+		return message.ChannelID, err.Error()
+	}
+	if len(games) >= storage.MaxScheduledGames {
+		return message.ChannelID, sett.LocalizeMessage(&i18n.Message{
+			ID:    "schedule.commandFnSchedule.tooMany",
+			Other: "Sorry, a server can't have more than {{.Max}} scheduled games. Please cancel one first",
+		},
+			map[string]interface{}{
+				"Max": storage.MaxScheduledGames,
+			})
+	}
+
+	sg.ScheduleID, err = bot.ScheduleInterface.AddScheduledGame(sg)
+	if err != nil {
+		bot.logger.Error("failed to schedule the game", "guildID", message.GuildID, "err", err)
+		return message.ChannelID, err.Error()
+	}
+	msg, err := bot.PrimarySession.ChannelMessageSendComplex(message.ChannelID, &discordgo.MessageSend{
+		Embed:      scheduledGameEmbed(sett, sg, nil),
+		Components: scheduledGameComponents(sett, sg.ScheduleID),
+	})
+	if err != nil {
+		bot.logger.Error("failed to send the RSVP message", "guildID", message.GuildID, "scheduleID", sg.ScheduleID, "err", err)
+		return "", nil
+	}
+	metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageCreateDelete, 1)
+	err = bot.ScheduleInterface.SetScheduledGameMessage(sg.ScheduleID, msg.ID)
+	if err != nil {
+		bot.logger.Error("failed to store the RSVP message", "guildID", message.GuildID, "scheduleID", sg.ScheduleID, "err", err)
+	}
+
+	// already sent the RSVP message
+	return "", nil
+}
+
+// scheduledGamesResponse lists the upcoming games of the guild
+func (bot *Bot) scheduledGamesResponse(guildID string, sett *storage.GuildSettings) string {
+	games, err := bot.ScheduleInterface.GetScheduledGames(guildID)
+	if err != nil {
+		bot.logger.Error("failed to get the scheduled games", "guildID", guildID, "err", err)
+		return err.Error()
+	}
+	if len(games) == 0 {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "schedule.scheduledGamesResponse.none",
+			Other: "There are no scheduled games. Schedule one with `{{.CommandPrefix}} schedule <time> [#voice channel]`",
+		},
+			map[string]interface{}{
+				"CommandPrefix": sett.GetCommandPrefix(),
+			})
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	for _, sg := range games {
+		rsvps, err := bot.ScheduleInterface.GetRSVPs(sg.ScheduleID)
+		if err != nil {
+			bot.logger.Error("failed to get the RSVPs", "guildID", guildID, "scheduleID", sg.ScheduleID, "err", err)
+		}
+		buf.WriteString(sett.LocalizeMessage(&i18n.Message{
+			ID:    "schedule.scheduledGamesResponse.game",
+			Other: "`{{.ID}}`: <t:{{.Time}}:F> in {{.Voice}}, {{.Going}} going",
+		},
+			map[string]interface{}{
+				"ID":    sg.ScheduleID,
+				"Time":  sg.StartTime,
+				"Voice": discord.MentionByChannelID(sg.VoiceChannelID),
+				"Going": len(rsvps),
+			}))
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// cancelScheduledGame deletes a scheduled game, and marks its RSVP message as cancelled
+func (bot *Bot) cancelScheduledGame(guildID string, sett *storage.GuildSettings, args []string) string {
+	if len(args) == 0 {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "schedule.cancelScheduledGame.noID",
+			Other: "Please provide the ID of the game to cancel; `{{.CommandPrefix}} schedule` lists them",
+		},
+			map[string]interface{}{
+				"CommandPrefix": sett.GetCommandPrefix(),
+			})
+	}
+	scheduleID, err := strconv.ParseInt(args[0], 10, 64)
+	var sg *storage.ScheduledGame
+	if err == nil {
+		sg, err = bot.ScheduleInterface.GetScheduledGame(guildID, scheduleID)
+	}
+	if err == nil {
+		err = bot.ScheduleInterface.DeleteScheduledGame(guildID, scheduleID)
+	}
+	if err != nil {
+		if err != storage.ErrScheduledGameNotFound {
+			bot.logger.Error("failed to cancel the scheduled game", "guildID", guildID, "scheduleID", args[0], "err", err)
+		}
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "schedule.cancelScheduledGame.notFound",
+			Other: "Sorry, there's no scheduled game `{{.ID}}`",
+		},
+			map[string]interface{}{
+				"ID": args[0],
+			})
+	}
+
+	if sg.MessageID != "" {
+		embed := scheduledGameEmbed(sett, *sg, nil)
+		embed.Title = sett.LocalizeMessage(&i18n.Message{
+			ID:    "schedule.cancelScheduledGame.cancelledTitle",
+			Other: "❌ Cancelled game",
+		})
+		embed.Fields = nil
+		embed.Color = 15158332 // RED
+		_, err := bot.PrimarySession.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			Components: []discordgo.MessageComponent{},
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			ID:         sg.MessageID,
+			Channel:    sg.TextChannelID,
+		})
+		if err != nil {
+			bot.logger.Error("failed to edit the RSVP message", "guildID", guildID, "scheduleID", sg.ScheduleID, "err", err)
+		}
+	}
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "schedule.cancelScheduledGame.cancelled",
+		Other: "Cancelled the game scheduled for <t:{{.Time}}:F>",
+	},
+		map[string]interface{}{
+			"Time": sg.StartTime,
+		})
+}
+
+func scheduledGameEmbed(sett *storage.GuildSettings, sg storage.ScheduledGame, rsvps []string) *discordgo.MessageEmbed {
+	going := sett.LocalizeMessage(&i18n.Message{
+		ID:    "schedule.scheduledGameEmbed.nobody",
+		Other: "Nobody yet",
+	})
+	if len(rsvps) > 0 {
+		mentions := make([]string, len(rsvps))
+		for i, userID := range rsvps {
+			mentions[i] = discord.MentionByUserID(userID)
+		}
+		going = strings.Join(mentions, " ")
+	}
+	return &discordgo.MessageEmbed{
+		Title: sett.LocalizeMessage(&i18n.Message{
+			ID:    "schedule.scheduledGameEmbed.title",
+			Other: "📅 Scheduled game",
+		}),
+		Description: sett.LocalizeMessage(&i18n.Message{
+			ID:    "schedule.scheduledGameEmbed.description",
+			Other: "Starts <t:{{.Time}}:F> (<t:{{.Time}}:R>) in {{.Voice}}, hosted by {{.Host}}",
+		},
+			map[string]interface{}{
+				"Time":  sg.StartTime,
+				"Voice": discord.MentionByChannelID(sg.VoiceChannelID),
+				"Host":  discord.MentionByUserID(sg.HostID),
+			}),
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name: sett.LocalizeMessage(&i18n.Message{
+					ID:    "schedule.scheduledGameEmbed.going",
+					Other: "Going ({{.Count}})",
+				},
+					map[string]interface{}{
+						"Count": len(rsvps),
+					}),
+				Value: going,
+			},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: sett.LocalizeMessage(&i18n.Message{
+				ID:    "schedule.scheduledGameEmbed.footer",
+				Other: "ID {{.ID}}",
+			},
+				map[string]interface{}{
+					"ID": sg.ScheduleID,
+				}),
+		},
+		Color: 3447003, // BLUE
+	}
+}
+
+func scheduledGameComponents(sett *storage.GuildSettings, scheduleID int64) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label: sett.LocalizeMessage(&i18n.Message{
+						ID:    "schedule.scheduledGameComponents.rsvp",
+						Other: "RSVP",
+					}),
+					Style:    discordgo.SuccessButton,
+					CustomID: fmt.Sprintf("%s:%d", scheduleRSVPID, scheduleID),
+				},
+			},
+		},
+	}
+}
+
+// handleScheduleRSVPComponent RSVPs the user to a scheduled game (or takes their RSVP back), and updates the list of
+// who's going
+func (bot *Bot) handleScheduleRSVPComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.GuildID == "" || i.Member == nil || i.Member.User == nil {
+		return
+	}
+	sett := bot.StorageInterface.GetGuildSettings(i.GuildID)
+
+	customID := i.MessageComponentData().CustomID
+	scheduleID, err := strconv.ParseInt(customID[strings.Index(customID, ":")+1:], 10, 64)
+	var sg *storage.ScheduledGame
+	if err == nil {
+		sg, err = bot.ScheduleInterface.GetScheduledGame(i.GuildID, scheduleID)
+	}
+	if err == nil {
+		_, err = bot.ScheduleInterface.ToggleRSVP(scheduleID, i.Member.User.ID)
+	}
+	if err != nil {
+		if err != storage.ErrScheduledGameNotFound {
+			bot.logger.Error("failed to toggle the RSVP", "guildID", i.GuildID, "userID", i.Member.User.ID, "err", err)
+		}
+		respondEphemeral(s, i.Interaction, sett.LocalizeMessage(&i18n.Message{
+			ID:    "schedule.handleScheduleRSVPComponent.gone",
+			Other: "This game was cancelled, or it already started",
+		}))
+		return
+	}
+	rsvps, err := bot.ScheduleInterface.GetRSVPs(scheduleID)
+	if err != nil {
+		bot.logger.Error("failed to get the RSVPs", "guildID", i.GuildID, "scheduleID", scheduleID, "err", err)
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{scheduledGameEmbed(sett, *sg, rsvps)},
+			Components: scheduledGameComponents(sett, scheduleID),
+		},
+	})
+	if err != nil {
+		bot.logger.Error("failed to respond to the RSVP", "guildID", i.GuildID, "scheduleID", scheduleID, "err", err)
+	}
+}
+
+// scheduledGamesWorker sends the reminders of the scheduled games, and starts them, once they're due
+func (bot *Bot) scheduledGamesWorker(dur time.Duration) {
+	shardCount, shardID := bot.config.NumShards, bot.config.ShardID
+	if shardCount < 1 {
+		shardCount, shardID = 1, 0
+	}
+	for {
+		time.Sleep(dur)
+		if bot.isClosing() {
+			return
+		}
+		now := time.Now().Unix()
+		reminders, err := bot.ScheduleInterface.ClaimDueReminders(now, shardCount, shardID)
+		if err != nil {
+			bot.logger.Error("failed to claim the due reminders", "err", err)
+		}
+		for _, sg := range reminders {
+			bot.remindScheduledGame(sg)
+		}
+		games, err := bot.ScheduleInterface.ClaimDueScheduledGames(now, shardCount, shardID)
+		if err != nil {
+			bot.logger.Error("failed to claim the due scheduled games", "err", err)
+		}
+		for _, sg := range games {
+			bot.startScheduledGame(sg)
+		}
+	}
+}
+
+// remindScheduledGame pings the users that RSVP'd to the game (and its host)
+func (bot *Bot) remindScheduledGame(sg storage.ScheduledGame) {
+	rsvps, err := bot.ScheduleInterface.GetRSVPs(sg.ScheduleID)
+	if err != nil {
+		bot.logger.Error("failed to get the RSVPs", "guildID", sg.GuildID, "scheduleID", sg.ScheduleID, "err", err)
+	}
+	mentions := []string{discord.MentionByUserID(sg.HostID)}
+	for _, userID := range rsvps {
+		if userID != sg.HostID {
+			mentions = append(mentions, discord.MentionByUserID(userID))
+		}
+	}
+	sett := bot.StorageInterface.GetGuildSettings(sg.GuildID)
+	_, err = bot.PrimarySession.ChannelMessageSend(sg.TextChannelID, sett.LocalizeMessage(&i18n.Message{
+		ID:    "schedule.remindScheduledGame.reminder",
+		Other: "⏰ The scheduled game starts <t:{{.Time}}:R> in {{.Voice}}! {{.Mentions}}",
+	},
+		map[string]interface{}{
+			"Time":     sg.StartTime,
+			"Voice":    discord.MentionByChannelID(sg.VoiceChannelID),
+			"Mentions": strings.Join(mentions, " "),
+		}))
+	if err != nil {
+		bot.logger.Error("failed to send the reminder", "guildID", sg.GuildID, "scheduleID", sg.ScheduleID, "err", err)
+		return
+	}
+	metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageCreateDelete, 1)
+}
+
+// startScheduledGame starts the game the same way `new` does, as if its host had typed it, but tracking the voice
+// channel the game was scheduled for
+func (bot *Bot) startScheduledGame(sg storage.ScheduledGame) {
+	logger := bot.logger.With("guildID", sg.GuildID, "scheduleID", sg.ScheduleID)
+	sett := bot.StorageInterface.GetGuildSettings(sg.GuildID)
+	var channelID string
+	var msg interface{}
+	g, err := bot.PrimarySession.State.Guild(sg.GuildID)
+	if err != nil {
+		logger.Error("guild of the scheduled game not found", "err", err)
+		return
+	}
+	voiceChannel, err := bot.PrimarySession.State.Channel(sg.VoiceChannelID)
+	switch {
+	case time.Since(time.Unix(sg.StartTime, 0)) > MaxScheduledGameDelay:
+		logger.Warn("scheduled game missed its start time")
+		channelID, msg = sg.TextChannelID, sett.LocalizeMessage(&i18n.Message{
+			ID:    "schedule.startScheduledGame.missed",
+			Other: "Sorry, I couldn't start the game scheduled for <t:{{.Time}}:F> in time",
+		},
+			map[string]interface{}{
+				"Time": sg.StartTime,
+			})
+	case err != nil:
+		channelID, msg = sg.TextChannelID, sett.LocalizeMessage(&i18n.Message{
+			ID:    "schedule.startScheduledGame.noChannel",
+			Other: "Sorry, I couldn't start the scheduled game; its voice channel doesn't exist anymore",
+		})
+	default:
+		lock, dgs := bot.newGameLock(sg.GuildID, sg.TextChannelID)
+		if lock == nil {
+			logger.Error("couldn't lock the game state to start the scheduled game")
+			return
+		}
+		logger.Info("starting scheduled game")
+		// the same message `new` would have received from the host
+		m := &discordgo.MessageCreate{
+			Message: &discordgo.Message{
+				ChannelID: sg.TextChannelID,
+				GuildID:   sg.GuildID,
+				Author:    &discordgo.User{ID: sg.HostID},
+			},
+		}
+		channelID, msg = bot.startNewGame(m, g, sett, "", TrackingChannel{
+			ChannelID:   voiceChannel.ID,
+			ChannelName: voiceChannel.Name,
+		}, lock, dgs)
+	}
+	if text, ok := msg.(string); ok && text != "" {
+		_, err = bot.PrimarySession.ChannelMessageSend(channelID, text)
+		if err != nil {
+			logger.Error("failed to send the scheduled game message", "err", err)
+		}
+	}
+
+	// the game started (or won't), so it can't be RSVP'd to anymore
+	if sg.MessageID != "" {
+		_, err = bot.PrimarySession.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			Components: []discordgo.MessageComponent{},
+			ID:         sg.MessageID,
+			Channel:    sg.TextChannelID,
+		})
+		if err != nil {
+			logger.Error("failed to edit the RSVP message", "err", err)
+		}
+	}
+}
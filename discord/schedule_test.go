@@ -0,0 +1,36 @@
+package discord
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleTime(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		args []string
+		want time.Time
+		used int
+	}{
+		{[]string{"2h30m", "#voice"}, now.Add(150 * time.Minute), 1},
+		{[]string{"in", "1d2h"}, now.Add(26 * time.Hour), 2},
+		{[]string{"7d"}, now.Add(7 * 24 * time.Hour), 1},
+		{[]string{"1760000000"}, time.Unix(1760000000, 0), 1},
+		{[]string{"<t:1760000000:f>", "30"}, time.Unix(1760000000, 0), 1},
+		{[]string{"2026-10-20t20:00"}, time.Date(2026, 10, 20, 20, 0, 0, 0, time.UTC), 1},
+		{[]string{"2026-10-20", "20:00", "30"}, time.Date(2026, 10, 20, 20, 0, 0, 0, time.UTC), 2},
+		{[]string{"2026-10-20", "20:00", "utc", "30"}, time.Date(2026, 10, 20, 20, 0, 0, 0, time.UTC), 3},
+	}
+	for _, test := range tests {
+		got, used, ok := parseScheduleTime(test.args, now)
+		if !ok || !got.Equal(test.want) || used != test.used {
+			t.Error("Wrong time for", test.args, got, used, ok)
+		}
+	}
+
+	for _, args := range [][]string{{}, {"in"}, {"tomorrow"}, {"30"}, {"2026-10-20"}, {"xd"}} {
+		if _, _, ok := parseScheduleTime(args, now); ok {
+			t.Error("Expected an invalid time for", args)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package setting
+
+import (
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/discord"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+func FnAuditChannel(sett *storage.GuildSettings, args []string) (interface{}, bool) {
+	if sett == nil || len(args) < 2 {
+		return nil, false
+	}
+	if len(args) == 2 {
+		return ConstructEmbedForSetting(sett.GetAuditChannelID(), AllSettings[AuditChannel], sett), false
+	}
+
+	if args[2] == "clear" || args[2] == "c" {
+		if sett.GetAuditChannelID() == "" {
+			return Unchanged(sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingAuditChannel.alreadyClear",
+				Other: "Settings changes aren't posted to any channel!",
+			})), false
+		}
+		sett.SetAuditChannelID("")
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingAuditChannel.cleared",
+			Other: "Settings changes will no longer be posted to a channel",
+		}), true
+	}
+
+	channelID, err := discord.ExtractChannelIDFromMention(args[2])
+	if err != nil {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingAuditChannel.invalidChannelID",
+			Other: "{{.channelID}} is not a valid text channel ID or mention!",
+		},
+			map[string]interface{}{
+				"channelID": args[2],
+			}), false
+	}
+
+	sett.SetAuditChannelID(channelID)
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "settings.SettingAuditChannel.withChannelID",
+		Other: "Settings changes will now be posted to {{.channelID}}!",
+	},
+		map[string]interface{}{
+			"channelID": discord.MentionByChannelID(channelID),
+		}), true
+}
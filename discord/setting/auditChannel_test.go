@@ -0,0 +1,36 @@
+package setting
+
+import "testing"
+
+func TestFnAuditChannel(t *testing.T) {
+	sett, err := testSettingsFn(FnAuditChannel)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, valid := FnAuditChannel(sett, []string{"sett", "audit", "clear"})
+	if valid {
+		t.Error("Clearing an audit channel that isn't set shouldn't result in a valid settings change")
+	}
+
+	_, valid = FnAuditChannel(sett, []string{"sett", "audit", "somegarbage"})
+	if valid {
+		t.Error("Garbage channel arg shouldn't result in a valid settings change")
+	}
+
+	_, valid = FnAuditChannel(sett, []string{"sett", "audit", "<#754465589958803548>"})
+	if !valid {
+		t.Error("Valid channel mention should result in a valid settings change")
+	}
+	if sett.GetAuditChannelID() != "754465589958803548" {
+		t.Error("Valid audit channel was not set correctly")
+	}
+
+	_, valid = FnAuditChannel(sett, []string{"sett", "audit", "clear"})
+	if !valid {
+		t.Error("Clearing the audit channel should result in a valid settings change")
+	}
+	if sett.GetAuditChannelID() != "" {
+		t.Error("Audit channel was not cleared")
+	}
+}
@@ -2,11 +2,11 @@ package setting
 
 import (
 	"fmt"
-	"github.com/automuteus/utils/pkg/settings"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 )
 
-func FnAutoRefresh(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+func FnAutoRefresh(sett *storage.GuildSettings, args []string) (interface{}, bool) {
 	if sett == nil || len(args) < 2 {
 		return nil, false
 	}
@@ -28,13 +28,13 @@ func FnAutoRefresh(sett *settings.GuildSettings, args []string) (interface{}, bo
 
 	newSet := val == "t" || val == "true"
 	if sett.GetAutoRefresh() == newSet {
-		return sett.LocalizeMessage(&i18n.Message{
+		return Unchanged(sett.LocalizeMessage(&i18n.Message{
 			ID:    "settings.SettingAutoRefresh.Noop",
 			Other: "AutoRefresh was already set to `{{.Value}}`; not doing anything",
 		},
 			map[string]interface{}{
 				"Value": newSet,
-			}), false
+			})), false
 	}
 	sett.SetAutoRefresh(newSet)
 	if newSet {
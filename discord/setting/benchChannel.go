@@ -0,0 +1,50 @@
+package setting
+
+import (
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/discord"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+func FnBenchChannel(sett *storage.GuildSettings, args []string) (interface{}, bool) {
+	if sett == nil || len(args) < 2 {
+		return nil, false
+	}
+	if len(args) == 2 {
+		return ConstructEmbedForSetting(sett.GetBenchChannelID(), AllSettings[BenchChannel], sett), false
+	}
+
+	if args[2] == "clear" || args[2] == "c" {
+		if sett.GetBenchChannelID() == "" {
+			return Unchanged(sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingBenchChannel.alreadyClear",
+				Other: "Benched players aren't moved to any channel!",
+			})), false
+		}
+		sett.SetBenchChannelID("")
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingBenchChannel.cleared",
+			Other: "Players will no longer be moved when the waitlist rotates",
+		}), true
+	}
+
+	channelID, err := discord.ExtractChannelIDFromMention(args[2])
+	if err != nil {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingBenchChannel.invalidChannelID",
+			Other: "{{.channelID}} is not a valid voice channel ID or mention!",
+		},
+			map[string]interface{}{
+				"channelID": args[2],
+			}), false
+	}
+
+	sett.SetBenchChannelID(channelID)
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "settings.SettingBenchChannel.withChannelID",
+		Other: "Benched players will now be moved to {{.channelID}}!",
+	},
+		map[string]interface{}{
+			"channelID": discord.MentionByChannelID(channelID),
+		}), true
+}
@@ -0,0 +1,36 @@
+package setting
+
+import "testing"
+
+func TestFnBenchChannel(t *testing.T) {
+	sett, err := testSettingsFn(FnBenchChannel)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, valid := FnBenchChannel(sett, []string{"sett", "bench", "clear"})
+	if valid {
+		t.Error("Clearing a bench channel that isn't set shouldn't result in a valid settings change")
+	}
+
+	_, valid = FnBenchChannel(sett, []string{"sett", "bench", "somegarbage"})
+	if valid {
+		t.Error("Garbage channel arg shouldn't result in a valid settings change")
+	}
+
+	_, valid = FnBenchChannel(sett, []string{"sett", "bench", "<#754465589958803548>"})
+	if !valid {
+		t.Error("Valid channel mention should result in a valid settings change")
+	}
+	if sett.GetBenchChannelID() != "754465589958803548" {
+		t.Error("Valid bench channel was not set correctly")
+	}
+
+	_, valid = FnBenchChannel(sett, []string{"sett", "bench", "clear"})
+	if !valid {
+		t.Error("Clearing the bench channel should result in a valid settings change")
+	}
+	if sett.GetBenchChannelID() != "" {
+		t.Error("Bench channel was not cleared")
+	}
+}
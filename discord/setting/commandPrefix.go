@@ -1,11 +1,12 @@
 package setting
 
 import (
+	"github.com/automuteus/automuteus/storage"
 	"github.com/automuteus/utils/pkg/settings"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 )
 
-func FnCommandPrefix(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+func FnCommandPrefix(sett *storage.GuildSettings, args []string) (interface{}, bool) {
 	if sett == nil || len(args) < 2 {
 		return nil, false
 	}
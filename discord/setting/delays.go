@@ -2,13 +2,13 @@ package setting
 
 import (
 	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/automuteus/utils/pkg/game"
-	"github.com/automuteus/utils/pkg/settings"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"strconv"
 )
 
-func FnDelays(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+func FnDelays(sett *storage.GuildSettings, args []string) (interface{}, bool) {
 	if sett == nil || len(args) < 2 {
 		return nil, false
 	}
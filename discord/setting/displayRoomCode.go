@@ -2,12 +2,12 @@ package setting
 
 import (
 	"fmt"
-	"github.com/automuteus/utils/pkg/settings"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"strings"
 )
 
-func FnDisplayRoomCode(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+func FnDisplayRoomCode(sett *storage.GuildSettings, args []string) (interface{}, bool) {
 	if sett == nil || len(args) < 2 {
 		return nil, false
 	}
@@ -2,13 +2,15 @@ package setting
 
 import (
 	"fmt"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/automuteus/utils/pkg/locale"
-	"github.com/automuteus/utils/pkg/settings"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
-	"os"
 )
 
-func FnLanguage(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+// LocalePath and DefaultLanguage are the ones the bot started with, so reloading the localization files uses them too
+var LocalePath, DefaultLanguage string
+
+func FnLanguage(sett *storage.GuildSettings, args []string) (interface{}, bool) {
 	if sett == nil || len(args) < 2 {
 		return nil, false
 	}
@@ -17,7 +19,7 @@ func FnLanguage(sett *settings.GuildSettings, args []string) (interface{}, bool)
 	}
 
 	if args[2] == "reload" {
-		locale.InitLang(os.Getenv("LOCALE_PATH"), os.Getenv("BOT_LANG"))
+		locale.InitLang(LocalePath, DefaultLanguage)
 
 		return sett.LocalizeMessage(&i18n.Message{
 			ID:    "settings.SettingLanguage.reloaded",
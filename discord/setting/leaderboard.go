@@ -2,12 +2,12 @@ package setting
 
 import (
 	"fmt"
-	"github.com/automuteus/utils/pkg/settings"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"strconv"
 )
 
-func FnLeaderboardMin(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+func FnLeaderboardMin(sett *storage.GuildSettings, args []string) (interface{}, bool) {
 	if sett == nil || len(args) < 2 {
 		return nil, false
 	}
@@ -44,7 +44,7 @@ func FnLeaderboardMin(sett *settings.GuildSettings, args []string) (interface{},
 		}), true
 }
 
-func FnLeaderboardNameMention(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+func FnLeaderboardNameMention(sett *storage.GuildSettings, args []string) (interface{}, bool) {
 	if sett == nil || len(args) < 2 {
 		return nil, false
 	}
@@ -79,7 +79,7 @@ func FnLeaderboardNameMention(sett *settings.GuildSettings, args []string) (inte
 	}
 }
 
-func FnLeaderboardSize(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+func FnLeaderboardSize(sett *storage.GuildSettings, args []string) (interface{}, bool) {
 	if sett == nil || len(args) < 2 {
 		return nil, false
 	}
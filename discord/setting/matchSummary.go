@@ -2,13 +2,13 @@ package setting
 
 import (
 	"fmt"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/automuteus/utils/pkg/discord"
-	"github.com/automuteus/utils/pkg/settings"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"strconv"
 )
 
-func FnMatchSummary(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+func FnMatchSummary(sett *storage.GuildSettings, args []string) (interface{}, bool) {
 	if sett == nil || len(args) < 2 {
 		return nil, false
 	}
@@ -57,7 +57,7 @@ func FnMatchSummary(sett *settings.GuildSettings, args []string) (interface{}, b
 	}
 }
 
-func FnMatchSummaryChannel(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+func FnMatchSummaryChannel(sett *storage.GuildSettings, args []string) (interface{}, bool) {
 	if sett == nil || len(args) < 2 {
 		return nil, false
 	}
@@ -0,0 +1,110 @@
+package setting
+
+import (
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/discord"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+func FnMoveDeadPlayers(sett *storage.GuildSettings, args []string) (interface{}, bool) {
+	if sett == nil || len(args) < 2 {
+		return nil, false
+	}
+	moveDead := sett.GetMoveDeadPlayers()
+	if len(args) == 2 {
+		current := "false"
+		if moveDead {
+			current = "true"
+		}
+		return ConstructEmbedForSetting(current, AllSettings[MoveDeadPlayers], sett), false
+	}
+	switch {
+	case args[2] == "true":
+		if moveDead {
+			return Unchanged(sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingMoveDeadPlayers.alreadyTrue",
+				Other: "It's already true!",
+			})), false
+		}
+		sett.SetMoveDeadPlayers(true)
+		if sett.GetGhostChannelID() == "" {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingMoveDeadPlayers.true_noGhostChannel",
+				Other: "I will move dead players once you set the voice channel to move them to, with `{{.CommandPrefix}} settings ghostChannel`",
+			},
+				map[string]interface{}{
+					"CommandPrefix": sett.GetCommandPrefix(),
+				}), true
+		}
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingMoveDeadPlayers.true_moveDeadPlayers",
+			Other: "I will now move dead players to {{.channel}} during tasks, and move everyone back when the game ends",
+		},
+			map[string]interface{}{
+				"channel": discord.MentionByChannelID(sett.GetGhostChannelID()),
+			}), true
+	case args[2] == "false":
+		if moveDead {
+			sett.SetMoveDeadPlayers(false)
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingMoveDeadPlayers.false_noMoveDeadPlayers",
+				Other: "I will no longer move dead players",
+			}), true
+		}
+		return Unchanged(sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingMoveDeadPlayers.alreadyFalse",
+			Other: "It's already false!",
+		})), false
+	default:
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingMoveDeadPlayers.wrongArg",
+			Other: "Sorry, `{{.Arg}}` is neither `true` nor `false`.",
+		},
+			map[string]interface{}{
+				"Arg": args[2],
+			}), false
+	}
+}
+
+func FnGhostChannel(sett *storage.GuildSettings, args []string) (interface{}, bool) {
+	if sett == nil || len(args) < 2 {
+		return nil, false
+	}
+	if len(args) == 2 {
+		return ConstructEmbedForSetting(sett.GetGhostChannelID(), AllSettings[GhostChannel], sett), false
+	}
+
+	if args[2] == "clear" || args[2] == "c" {
+		if sett.GetGhostChannelID() == "" {
+			return Unchanged(sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingGhostChannel.alreadyClear",
+				Other: "Dead players aren't moved to any channel!",
+			})), false
+		}
+		sett.SetGhostChannelID("")
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingGhostChannel.cleared",
+			Other: "Dead players will no longer be moved to a channel",
+		}), true
+	}
+
+	channelID, err := discord.ExtractChannelIDFromMention(args[2])
+	if err != nil {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingGhostChannel.invalidChannelID",
+			Other: "{{.channelID}} is not a valid voice channel ID or mention!",
+		},
+			map[string]interface{}{
+				"channelID": args[2],
+			}), false
+	}
+
+	sett.SetGhostChannelID(channelID)
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "settings.SettingGhostChannel.withChannelID",
+		Other: "Dead players will now be moved to {{.channelID}}!",
+	},
+		map[string]interface{}{
+			"channelID": discord.MentionByChannelID(channelID),
+		}), true
+}
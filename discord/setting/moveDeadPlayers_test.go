@@ -0,0 +1,69 @@
+package setting
+
+import "testing"
+
+func TestFnMoveDeadPlayers(t *testing.T) {
+	sett, err := testSettingsFn(FnMoveDeadPlayers)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, valid := FnMoveDeadPlayers(sett, []string{"sett", "movedead", "nottrueorfalse"})
+	if valid {
+		t.Error("Invalid move dead players arg should never result in a valid settings change")
+	}
+
+	_, valid = FnMoveDeadPlayers(sett, []string{"sett", "movedead", "false"})
+	if valid {
+		t.Error("Identical move dead players arg to default should never result in a valid settings change")
+	}
+
+	_, valid = FnMoveDeadPlayers(sett, []string{"sett", "movedead", "true"})
+	if !valid {
+		t.Error("Valid move dead players arg should result in a valid settings change")
+	}
+	if !sett.GetMoveDeadPlayers() {
+		t.Error("Valid move dead players (\"true\") was not set correctly")
+	}
+
+	_, valid = FnMoveDeadPlayers(sett, []string{"sett", "movedead", "false"})
+	if !valid {
+		t.Error("Valid move dead players arg should result in a valid settings change")
+	}
+	if sett.GetMoveDeadPlayers() {
+		t.Error("Valid move dead players (\"false\") was not set correctly")
+	}
+}
+
+func TestFnGhostChannel(t *testing.T) {
+	sett, err := testSettingsFn(FnGhostChannel)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, valid := FnGhostChannel(sett, []string{"sett", "ghostchan", "clear"})
+	if valid {
+		t.Error("Clearing a ghost channel that isn't set shouldn't result in a valid settings change")
+	}
+
+	_, valid = FnGhostChannel(sett, []string{"sett", "ghostchan", "somegarbage"})
+	if valid {
+		t.Error("Garbage channel arg shouldn't result in a valid settings change")
+	}
+
+	_, valid = FnGhostChannel(sett, []string{"sett", "ghostchan", "<#754465589958803548>"})
+	if !valid {
+		t.Error("Valid channel mention should result in a valid settings change")
+	}
+	if sett.GetGhostChannelID() != "754465589958803548" {
+		t.Error("Valid ghost channel was not set correctly")
+	}
+
+	_, valid = FnGhostChannel(sett, []string{"sett", "ghostchan", "c"})
+	if !valid {
+		t.Error("Clearing the ghost channel should result in a valid settings change")
+	}
+	if sett.GetGhostChannelID() != "" {
+		t.Error("Ghost channel was not cleared")
+	}
+}
@@ -1,11 +1,11 @@
 package setting
 
 import (
-	"github.com/automuteus/utils/pkg/settings"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 )
 
-func FnMuteSpectators(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+func FnMuteSpectators(sett *storage.GuildSettings, args []string) (interface{}, bool) {
 	if sett == nil || len(args) < 2 {
 		return nil, false
 	}
@@ -20,10 +20,10 @@ func FnMuteSpectators(sett *settings.GuildSettings, args []string) (interface{},
 	switch {
 	case args[2] == "true":
 		if muteSpec {
-			return sett.LocalizeMessage(&i18n.Message{
+			return Unchanged(sett.LocalizeMessage(&i18n.Message{
 				ID:    "settings.SettingUnmuteDeadDuringTasks.true_noUnmuteDead",
 				Other: "It's already true!",
-			}), false
+			})), false
 		} else {
 			sett.SetMuteSpectator(true)
 			return sett.LocalizeMessage(&i18n.Message{
@@ -39,10 +39,10 @@ func FnMuteSpectators(sett *settings.GuildSettings, args []string) (interface{},
 				Other: "I will no longer mute spectators like dead players",
 			}), true
 		}
-		return sett.LocalizeMessage(&i18n.Message{
+		return Unchanged(sett.LocalizeMessage(&i18n.Message{
 			ID:    "settings.SettingUnmuteDeadDuringTasks.false_noUnmuteDead",
 			Other: "It's already false!",
-		}), false
+		})), false
 	default:
 		return sett.LocalizeMessage(&i18n.Message{
 			ID:    "settings.SettingUnmuteDeadDuringTasks.wrongArg",
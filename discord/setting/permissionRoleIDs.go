@@ -1,12 +1,12 @@
 package setting
 
 import (
+	"github.com/automuteus/automuteus/storage"
 	"github.com/automuteus/utils/pkg/discord"
-	"github.com/automuteus/utils/pkg/settings"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 )
 
-func FnPermissionRoleIDs(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+func FnPermissionRoleIDs(sett *storage.GuildSettings, args []string) (interface{}, bool) {
 	if sett == nil || len(args) < 2 {
 		return nil, false
 	}
@@ -1,7 +1,7 @@
 package setting
 
 import (
-	"github.com/automuteus/utils/pkg/settings"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/bwmarrin/discordgo"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"strings"
@@ -26,15 +26,29 @@ const (
 	LeaderboardMin
 	MuteSpectators
 	DisplayRoomCode
+	VoiceOverrides
+	MoveDeadPlayers
+	GhostChannel
+	AuditChannel
+	VoicePresets
+	BenchChannel
+	Export
+	Import
+	History
+	Rollback
 	Show
 	Reset
 	NullSetting
 )
 
 type ISetting interface {
-	HandleSetting(*discordgo.MessageCreate, *settings.GuildSettings, []string) (interface{}, bool)
+	HandleSetting(*discordgo.MessageCreate, *storage.GuildSettings, []string) (interface{}, bool)
 }
 
+// Unchanged is the response to setting a value the settings have already. It isn't a valid settings change, but the
+// value isn't invalid either
+type Unchanged string
+
 type Setting struct {
 	SettingType SettingType
 	Name        string
@@ -351,6 +365,196 @@ var AllSettings = []Setting{
 		Aliases: []string{"displayRoomCode", "roomcode", "code", "rc"},
 		Premium: true,
 	},
+	{
+		SettingType: VoiceOverrides,
+		Name:        "voiceOverrides",
+		Example:     "voiceOverrides @Soup exempt",
+		ShortDesc: &i18n.Message{
+			ID:    "settings.AllSettings.VoiceOverrides.shortDesc",
+			Other: "Per-User Voice Overrides",
+		},
+		Description: &i18n.Message{
+			ID:    "settings.AllSettings.VoiceOverrides.desc",
+			Other: "Override the voice rules for a single user, like streamers or casters. `exempt` users are never muted or deafened, `spectator` users are always treated like dead spectators, and `deafentasks` users are always deafened during tasks",
+		},
+		Arguments: &i18n.Message{
+			ID:    "settings.AllSettings.VoiceOverrides.args",
+			Other: "<User @ mention> <exempt/spectator/deafentasks/clear>, or clear",
+		},
+		Aliases: []string{"overrides", "override", "vo"},
+		Premium: false,
+	},
+	{
+		SettingType: MoveDeadPlayers,
+		Name:        "moveDeadPlayers",
+		Example:     "moveDeadPlayers true",
+		ShortDesc: &i18n.Message{
+			ID:    "settings.AllSettings.MoveDeadPlayers.shortDesc",
+			Other: "Move Dead Players",
+		},
+		Description: &i18n.Message{
+			ID:    "settings.AllSettings.MoveDeadPlayers.desc",
+			Other: "Whether or not the bot should move dead players to the `ghostChannel` during tasks (so they can talk freely), and move everyone back to the main voice channel when the game ends",
+		},
+		Arguments: &i18n.Message{
+			ID:    "settings.AllSettings.MoveDeadPlayers.args",
+			Other: "<true/false>",
+		},
+		Aliases: []string{"movedead", "moveghosts", "move"},
+		Premium: false,
+	},
+	{
+		SettingType: GhostChannel,
+		Name:        "ghostChannel",
+		Example:     "ghostChannel 754465589958803548",
+		ShortDesc: &i18n.Message{
+			ID:    "settings.AllSettings.GhostChannel.shortDesc",
+			Other: "Voice Channel for Dead Players",
+		},
+		Description: &i18n.Message{
+			ID:    "settings.AllSettings.GhostChannel.desc",
+			Other: "Specify the voice channel that dead players are moved to when `moveDeadPlayers` is true. Use the channel ID, or a mention, or `clear` to stop moving them",
+		},
+		Arguments: &i18n.Message{
+			ID:    "settings.AllSettings.GhostChannel.args",
+			Other: "<voice channel ID/mention> or clear",
+		},
+		Aliases: []string{"ghostchan", "deadchannel", "ghosts", "gc"},
+		Premium: false,
+	},
+	{
+		SettingType: AuditChannel,
+		Name:        "auditChannel",
+		Example:     "auditChannel #bot-logs",
+		ShortDesc: &i18n.Message{
+			ID:    "settings.AllSettings.AuditChannel.shortDesc",
+			Other: "Channel for Settings Changes",
+		},
+		Description: &i18n.Message{
+			ID:    "settings.AllSettings.AuditChannel.desc",
+			Other: "Specify the text channel where every change to the bot settings is posted. Use `#bot-logs`, for example, or `clear` to stop posting changes",
+		},
+		Arguments: &i18n.Message{
+			ID:    "settings.AllSettings.AuditChannel.args",
+			Other: "<text channel mention> or clear",
+		},
+		Aliases: []string{"auditchan", "audit", "logchannel", "logs"},
+		Premium: false,
+	},
+	{
+		SettingType: VoicePresets,
+		Name:        "voicePresets",
+		Example:     "voicePresets save tournament",
+		ShortDesc: &i18n.Message{
+			ID:    "settings.AllSettings.VoicePresets.shortDesc",
+			Other: "Voice Presets",
+		},
+		Description: &i18n.Message{
+			ID:    "settings.AllSettings.VoicePresets.desc",
+			Other: "Voice presets set the `voiceRules`, `delays`, `unmuteDeadDuringTasks` and `muteSpectators` settings all at once. `save` the current ones as a preset of your own, `apply` a preset as this server's settings, or `delete` one. Use the `mode` command to switch the preset of a single game instead",
+		},
+		Arguments: &i18n.Message{
+			ID:    "settings.AllSettings.VoicePresets.args",
+			Other: "<save/apply/delete> <preset name>, or clear",
+		},
+		Aliases: []string{"presets", "preset", "vp"},
+		Premium: false,
+	},
+	{
+		SettingType: BenchChannel,
+		Name:        "benchChannel",
+		Example:     "benchChannel #waiting-room",
+		ShortDesc: &i18n.Message{
+			ID:    "settings.AllSettings.BenchChannel.shortDesc",
+			Other: "Voice Channel for Benched Players",
+		},
+		Description: &i18n.Message{
+			ID:    "settings.AllSettings.BenchChannel.desc",
+			Other: "Specify the voice channel that players sitting out are moved to when the waitlist of a game rotates, after a game ends. The next players on the waitlist are moved from it into the game's voice channel too. Use `clear` to only post who rotates in and out, without moving anyone",
+		},
+		Arguments: &i18n.Message{
+			ID:    "settings.AllSettings.BenchChannel.args",
+			Other: "<voice channel ID/mention> or clear",
+		},
+		Aliases: []string{"benchchan", "bench", "bc"},
+		Premium: false,
+	},
+	{
+		SettingType: Export,
+		Name:        "export",
+		Example:     "export",
+		ShortDesc: &i18n.Message{
+			ID:    "settings.AllSettings.Export.shortDesc",
+			Other: "Export Bot Settings",
+		},
+		Description: &i18n.Message{
+			ID:    "settings.AllSettings.Export.desc",
+			Other: "Upload all the bot settings for this server as a JSON (or YAML) file, to `import` them in another server",
+		},
+		Arguments: &i18n.Message{
+			ID:    "settings.AllSettings.Export.args",
+			Other: "<json/yaml> (json by default)",
+		},
+		Aliases: []string{"exp"},
+		Premium: false,
+	},
+	{
+		SettingType: Import,
+		Name:        "import",
+		Example:     "import",
+		ShortDesc: &i18n.Message{
+			ID:    "settings.AllSettings.Import.shortDesc",
+			Other: "Import Bot Settings",
+		},
+		Description: &i18n.Message{
+			ID:    "settings.AllSettings.Import.desc",
+			Other: "Import the bot settings from a JSON or YAML file made with `export` (attach it to the message). The changes are shown before they're applied",
+		},
+		Arguments: &i18n.Message{
+			ID:    "settings.AllSettings.Import.args",
+			Other: "<attached settings file>",
+		},
+		Aliases: []string{"imp"},
+		Premium: false,
+	},
+	{
+		SettingType: History,
+		Name:        "history",
+		Example:     "history 2",
+		ShortDesc: &i18n.Message{
+			ID:    "settings.AllSettings.History.shortDesc",
+			Other: "Settings History",
+		},
+		Description: &i18n.Message{
+			ID:    "settings.AllSettings.History.desc",
+			Other: "Show who changed the bot settings for this server, and when. Newest changes are shown first",
+		},
+		Arguments: &i18n.Message{
+			ID:    "settings.AllSettings.History.args",
+			Other: "<page>",
+		},
+		Aliases: []string{"hist", "changes", "log"},
+		Premium: false,
+	},
+	{
+		SettingType: Rollback,
+		Name:        "rollback",
+		Example:     "rollback 2",
+		ShortDesc: &i18n.Message{
+			ID:    "settings.AllSettings.Rollback.shortDesc",
+			Other: "Rollback Bot Settings",
+		},
+		Description: &i18n.Message{
+			ID:    "settings.AllSettings.Rollback.desc",
+			Other: "Restore the bot settings from before the last changes (1 is the most recent version). The changes are shown before they're applied",
+		},
+		Arguments: &i18n.Message{
+			ID:    "settings.AllSettings.Rollback.args",
+			Other: "<number of versions back>",
+		},
+		Aliases: []string{"undo", "rb"},
+		Premium: false,
+	},
 	{
 		SettingType: Show,
 		Name:        "show",
@@ -380,7 +584,7 @@ var AllSettings = []Setting{
 		},
 		Description: &i18n.Message{
 			ID:    "settings.AllSettings.Reset.desc",
-			Other: "Reset all bot settings to their default values",
+			Other: "Reset all bot settings to their default values. The reset can be undone with `rollback`",
 		},
 		Arguments: &i18n.Message{
 			ID:    "settings.AllSettings.Reset.args",
@@ -391,7 +595,7 @@ var AllSettings = []Setting{
 	},
 }
 
-func ConstructEmbedForSetting(value string, setting Setting, sett *settings.GuildSettings) discordgo.MessageEmbed {
+func ConstructEmbedForSetting(value string, setting Setting, sett *storage.GuildSettings) discordgo.MessageEmbed {
 	title := setting.Name
 	if setting.Premium {
 		title = "💎 " + title
@@ -2,17 +2,17 @@ package setting
 
 import (
 	"errors"
-	"github.com/automuteus/utils/pkg/settings"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/bwmarrin/discordgo"
 )
 
-func testSettingsFn(fn func(settings *settings.GuildSettings, args []string) (interface{}, bool)) (*settings.GuildSettings, error) {
+func testSettingsFn(fn func(settings *storage.GuildSettings, args []string) (interface{}, bool)) (*storage.GuildSettings, error) {
 	_, valid := fn(nil, []string{})
 	if valid {
 		return nil, errors.New("sending nil settings should never result in valid settings change")
 	}
 
-	sett := settings.MakeGuildSettings("")
+	sett := storage.MakeGuildSettings("")
 	_, valid = fn(sett, []string{})
 	if valid {
 		return nil, errors.New("sending no args should never result in valid settings change")
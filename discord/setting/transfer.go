@@ -0,0 +1,192 @@
+package setting
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/discord"
+	"github.com/automuteus/utils/pkg/game"
+)
+
+// ChangeArgs returns the settings commands (args, just like the settings command receives them) that change the
+// settings in `from` into the ones in `to`. This way, settings changed another way (like importing them from a file)
+// go through the exact same validation as when typed by hand.
+// The match summary channel can't be unset with the settings command, so it's only ever changed.
+// Custom voice presets are only ever saved from the current settings, so they aren't transferred at all
+func ChangeArgs(from, to *storage.GuildSettings) [][]string {
+	var changes [][]string
+	add := func(settType SettingType, values ...string) {
+		changes = append(changes, append([]string{"settings", AllSettings[settType].Name}, values...))
+	}
+
+	if from.CommandPrefix != to.CommandPrefix {
+		add(Prefix, to.CommandPrefix)
+	}
+	if from.Language != to.Language {
+		add(Language, to.Language)
+	}
+	if !sameIDs(from.AdminUserIDs, to.AdminUserIDs) {
+		// admins are added to the existing ones, so start from scratch
+		if len(from.AdminUserIDs) > 0 {
+			add(AdminUserIDs, "clear")
+		}
+		if len(to.AdminUserIDs) > 0 {
+			mentions := make([]string, len(to.AdminUserIDs))
+			for i, id := range to.AdminUserIDs {
+				mentions[i] = discord.MentionByUserID(id)
+			}
+			add(AdminUserIDs, mentions...)
+		}
+	}
+	if !sameIDs(from.PermissionRoleIDs, to.PermissionRoleIDs) {
+		if len(to.PermissionRoleIDs) == 0 {
+			add(RoleIDs, "clear")
+		} else {
+			mentions := make([]string, len(to.PermissionRoleIDs))
+			for i, id := range to.PermissionRoleIDs {
+				mentions[i] = "<@&" + id + ">"
+			}
+			add(RoleIDs, mentions...)
+		}
+	}
+	if from.UnmuteDeadDuringTasks != to.UnmuteDeadDuringTasks {
+		add(UnmuteDead, strconv.FormatBool(to.UnmuteDeadDuringTasks))
+	}
+	for _, origin := range sortedPhaseNames(to.Delays.Delays) {
+		for _, dest := range sortedPhaseNames(to.Delays.Delays[origin]) {
+			delay := to.Delays.Delays[origin][dest]
+			if old, ok := from.Delays.Delays[origin][dest]; !ok || old != delay {
+				add(Delays, strings.ToLower(string(origin)), strings.ToLower(string(dest)), strconv.Itoa(delay))
+			}
+		}
+	}
+	for _, rule := range []struct {
+		name     string
+		from, to map[game.PhaseNameString]map[string]bool
+	}{
+		{"mute", from.VoiceRules.MuteRules, to.VoiceRules.MuteRules},
+		{"deaf", from.VoiceRules.DeafRules, to.VoiceRules.DeafRules},
+	} {
+		for _, phase := range sortedPhaseNames(rule.to) {
+			for _, alive := range []string{"alive", "dead"} {
+				value, ok := rule.to[phase][alive]
+				if !ok {
+					continue
+				}
+				if old, ok := rule.from[phase][alive]; !ok || old != value {
+					add(VoiceRules, rule.name, strings.ToLower(string(phase)), alive, strconv.FormatBool(value))
+				}
+			}
+		}
+	}
+	if from.MapVersion != to.MapVersion {
+		add(MapVersion, to.MapVersion)
+	}
+	if from.DeleteGameSummaryMinutes != to.DeleteGameSummaryMinutes {
+		add(MatchSummary, strconv.Itoa(to.DeleteGameSummaryMinutes))
+	}
+	if to.MatchSummaryChannelID != "" && from.MatchSummaryChannelID != to.MatchSummaryChannelID {
+		add(MatchSummaryChannel, to.MatchSummaryChannelID)
+	}
+	if from.AutoRefresh != to.AutoRefresh {
+		add(AutoRefresh, strconv.FormatBool(to.AutoRefresh))
+	}
+	if from.LeaderboardMention != to.LeaderboardMention {
+		add(LeaderboardMention, strconv.FormatBool(to.LeaderboardMention))
+	}
+	if from.LeaderboardSize != to.LeaderboardSize {
+		add(LeaderboardSize, strconv.Itoa(to.LeaderboardSize))
+	}
+	if from.LeaderboardMin != to.LeaderboardMin {
+		add(LeaderboardMin, strconv.Itoa(to.LeaderboardMin))
+	}
+	if from.MuteSpectator != to.MuteSpectator {
+		add(MuteSpectators, strconv.FormatBool(to.MuteSpectator))
+	}
+	if from.DisplayRoomCode != to.DisplayRoomCode {
+		add(DisplayRoomCode, to.DisplayRoomCode)
+	}
+
+	userIDs := make([]string, 0, len(from.VoiceOverrides)+len(to.VoiceOverrides))
+	for userID := range from.VoiceOverrides {
+		userIDs = append(userIDs, userID)
+	}
+	for userID := range to.VoiceOverrides {
+		if _, ok := from.VoiceOverrides[userID]; !ok {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	sort.Strings(userIDs)
+	for _, userID := range userIDs {
+		old, hadOverride := from.VoiceOverrides[userID]
+		override, hasOverride := to.VoiceOverrides[userID]
+		switch {
+		case !hasOverride:
+			add(VoiceOverrides, discord.MentionByUserID(userID), "clear")
+		case !hadOverride || old != override:
+			add(VoiceOverrides, discord.MentionByUserID(userID), string(override))
+		}
+	}
+
+	if from.MoveDeadPlayers != to.MoveDeadPlayers {
+		add(MoveDeadPlayers, strconv.FormatBool(to.MoveDeadPlayers))
+	}
+	if from.GhostChannelID != to.GhostChannelID {
+		if to.GhostChannelID == "" {
+			add(GhostChannel, "clear")
+		} else {
+			add(GhostChannel, to.GhostChannelID)
+		}
+	}
+	if from.AuditChannelID != to.AuditChannelID {
+		if to.AuditChannelID == "" {
+			add(AuditChannel, "clear")
+		} else {
+			add(AuditChannel, to.AuditChannelID)
+		}
+	}
+	if from.BenchChannelID != to.BenchChannelID {
+		if to.BenchChannelID == "" {
+			add(BenchChannel, "clear")
+		} else {
+			add(BenchChannel, to.BenchChannelID)
+		}
+	}
+	return changes
+}
+
+func sameIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, id := range a {
+		if !contains(b, id) {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedPhaseNames(m interface{}) []game.PhaseNameString {
+	var names []game.PhaseNameString
+	switch v := m.(type) {
+	case map[game.PhaseNameString]map[game.PhaseNameString]int:
+		for name := range v {
+			names = append(names, name)
+		}
+	case map[game.PhaseNameString]int:
+		for name := range v {
+			names = append(names, name)
+		}
+	case map[game.PhaseNameString]map[string]bool:
+		for name := range v {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+	return names
+}
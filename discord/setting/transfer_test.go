@@ -0,0 +1,47 @@
+package setting
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/game"
+)
+
+func TestChangeArgs(t *testing.T) {
+	from := storage.MakeGuildSettings("")
+	to := storage.MakeGuildSettings("")
+	if changes := ChangeArgs(from, to); len(changes) != 0 {
+		t.Errorf("Identical settings shouldn't have any changes, got %v", changes)
+	}
+
+	to.SetAdminUserIDs([]string{"140581066283941888"})
+	to.SetDelay(game.LOBBY, game.TASKS, 3)
+	to.SetVoiceOverride("140581066283941888", storage.OverrideExempt)
+	changes := ChangeArgs(from, to)
+	if len(changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %v", changes)
+	}
+	if changes[0][1] != "adminUserIDs" || !strings.Contains(changes[0][2], "140581066283941888") {
+		t.Errorf("Expected the admin to be added, got %v", changes[0])
+	}
+	if changes[1][1] != "delays" || changes[1][2] != "lobby" || changes[1][3] != "tasks" || changes[1][4] != "3" {
+		t.Errorf("Expected the delay to be changed, got %v", changes[1])
+	}
+	if changes[2][1] != "voiceOverrides" || changes[2][3] != "exempt" {
+		t.Errorf("Expected the voice override to be set, got %v", changes[2])
+	}
+
+	// admins are only ever added by the settings command, so replacing them needs to clear them first
+	changes = ChangeArgs(to, from)
+	if len(changes) != 3 || changes[0][2] != "clear" || changes[2][3] != "clear" {
+		t.Errorf("Expected the admins and voice override to be cleared, got %v", changes)
+	}
+
+	from, to = storage.MakeGuildSettings(""), storage.MakeGuildSettings("")
+	from.SetGhostChannelID("754465589958803548")
+	changes = ChangeArgs(from, to)
+	if len(changes) != 1 || changes[0][1] != "ghostChannel" || changes[0][2] != "clear" {
+		t.Errorf("Expected the ghost channel to be cleared, got %v", changes)
+	}
+}
@@ -1,11 +1,11 @@
 package setting
 
 import (
-	"github.com/automuteus/utils/pkg/settings"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 )
 
-func FnUnmuteDeadDuringTasks(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+func FnUnmuteDeadDuringTasks(sett *storage.GuildSettings, args []string) (interface{}, bool) {
 	if sett == nil || len(args) < 2 {
 		return nil, false
 	}
@@ -20,10 +20,10 @@ func FnUnmuteDeadDuringTasks(sett *settings.GuildSettings, args []string) (inter
 	switch {
 	case args[2] == "true":
 		if unmuteDead {
-			return sett.LocalizeMessage(&i18n.Message{
+			return Unchanged(sett.LocalizeMessage(&i18n.Message{
 				ID:    "settings.SettingUnmuteDeadDuringTasks.true_unmuteDead",
 				Other: "It's already true!",
-			}), false
+			})), false
 		} else {
 			sett.SetUnmuteDeadDuringTasks(true)
 			return sett.LocalizeMessage(&i18n.Message{
@@ -39,10 +39,10 @@ func FnUnmuteDeadDuringTasks(sett *settings.GuildSettings, args []string) (inter
 				Other: "I will no longer immediately unmute dead people. Good choice!",
 			}), true
 		}
-		return sett.LocalizeMessage(&i18n.Message{
+		return Unchanged(sett.LocalizeMessage(&i18n.Message{
 			ID:    "settings.SettingUnmuteDeadDuringTasks.false_noUnmuteDead",
 			Other: "It's already false!",
-		}), false
+		})), false
 	default:
 		return sett.LocalizeMessage(&i18n.Message{
 			ID:    "settings.SettingUnmuteDeadDuringTasks.wrongArg",
@@ -0,0 +1,100 @@
+package setting
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/discord"
+)
+
+// Values flattens the settings into a single value per name, where settings holding several values (like the delays
+// or the voice rules) are split by the args used to change them: "delays lobby tasks", "voiceRules mute tasks dead"...
+// This way, two versions of the settings can be compared setting by setting
+func Values(sett *storage.GuildSettings) map[string]string {
+	values := map[string]string{}
+	set := func(settType SettingType, value string, args ...string) {
+		values[strings.Join(append([]string{AllSettings[settType].Name}, args...), " ")] = value
+	}
+
+	set(Prefix, sett.CommandPrefix)
+	set(Language, sett.Language)
+	set(AdminUserIDs, joinedMentions(sett.AdminUserIDs, discord.MentionByUserID))
+	set(RoleIDs, joinedMentions(sett.PermissionRoleIDs, func(id string) string {
+		return "<@&" + id + ">"
+	}))
+	set(UnmuteDead, strconv.FormatBool(sett.UnmuteDeadDuringTasks))
+	for _, origin := range sortedPhaseNames(sett.Delays.Delays) {
+		for _, dest := range sortedPhaseNames(sett.Delays.Delays[origin]) {
+			set(Delays, strconv.Itoa(sett.Delays.Delays[origin][dest]), strings.ToLower(string(origin)), strings.ToLower(string(dest)))
+		}
+	}
+	for _, phase := range sortedPhaseNames(sett.VoiceRules.MuteRules) {
+		for alive, value := range sett.VoiceRules.MuteRules[phase] {
+			set(VoiceRules, strconv.FormatBool(value), "mute", strings.ToLower(string(phase)), alive)
+		}
+	}
+	for _, phase := range sortedPhaseNames(sett.VoiceRules.DeafRules) {
+		for alive, value := range sett.VoiceRules.DeafRules[phase] {
+			set(VoiceRules, strconv.FormatBool(value), "deaf", strings.ToLower(string(phase)), alive)
+		}
+	}
+	set(MapVersion, sett.MapVersion)
+	set(MatchSummary, strconv.Itoa(sett.DeleteGameSummaryMinutes))
+	set(MatchSummaryChannel, mentionIfSet(sett.MatchSummaryChannelID))
+	set(AutoRefresh, strconv.FormatBool(sett.AutoRefresh))
+	set(LeaderboardMention, strconv.FormatBool(sett.LeaderboardMention))
+	set(LeaderboardSize, strconv.Itoa(sett.LeaderboardSize))
+	set(LeaderboardMin, strconv.Itoa(sett.LeaderboardMin))
+	set(MuteSpectators, strconv.FormatBool(sett.MuteSpectator))
+	set(DisplayRoomCode, sett.DisplayRoomCode)
+	for userID, override := range sett.VoiceOverrides {
+		set(VoiceOverrides, string(override), discord.MentionByUserID(userID))
+	}
+	set(MoveDeadPlayers, strconv.FormatBool(sett.MoveDeadPlayers))
+	set(GhostChannel, mentionIfSet(sett.GhostChannelID))
+	set(AuditChannel, mentionIfSet(sett.AuditChannelID))
+	set(BenchChannel, mentionIfSet(sett.BenchChannelID))
+	for name, preset := range sett.VoicePresets {
+		jBytes, _ := json.Marshal(preset)
+		set(VoicePresets, string(jBytes), name)
+	}
+	return values
+}
+
+// ChangedValues returns the names of the values that differ between the two settings (including values only in one
+// of them), in alphabetical order
+func ChangedValues(from, to map[string]string) []string {
+	var names []string
+	for name, value := range to {
+		if old, ok := from[name]; !ok || old != value {
+			names = append(names, name)
+		}
+	}
+	for name := range from {
+		if _, ok := to[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func joinedMentions(ids []string, mention func(string) string) string {
+	sorted := make([]string, len(ids))
+	copy(sorted, ids)
+	sort.Strings(sorted)
+	for i, id := range sorted {
+		sorted[i] = mention(id)
+	}
+	return strings.Join(sorted, " ")
+}
+
+func mentionIfSet(channelID string) string {
+	if channelID == "" {
+		return ""
+	}
+	return discord.MentionByChannelID(channelID)
+}
@@ -0,0 +1,40 @@
+package setting
+
+import (
+	"testing"
+
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/game"
+)
+
+func TestChangedValues(t *testing.T) {
+	from := storage.MakeGuildSettings("")
+	to := storage.MakeGuildSettings("")
+	if changed := ChangedValues(Values(from), Values(to)); len(changed) != 0 {
+		t.Errorf("Identical settings shouldn't have any changed values, got %v", changed)
+	}
+
+	to.CommandPrefix = "!"
+	to.SetDelay(game.LOBBY, game.TASKS, 3)
+	to.VoiceRules.MuteRules[game.PhaseNames[game.TASKS]]["dead"] = true
+	to.SetVoiceOverride("140581066283941888", storage.OverrideExempt)
+	changed := ChangedValues(Values(from), Values(to))
+	expected := []string{
+		"commandPrefix",
+		"delays lobby tasks",
+		"voiceOverrides <@!140581066283941888>",
+		"voiceRules mute tasks dead",
+	}
+	if len(changed) != len(expected) {
+		t.Fatalf("Expected %v to be changed, got %v", expected, changed)
+	}
+	for i, name := range expected {
+		if changed[i] != name {
+			t.Errorf("Expected %s to be changed, got %s", name, changed[i])
+		}
+	}
+
+	if changed = ChangedValues(Values(to), Values(from)); len(changed) != len(expected) {
+		t.Errorf("Removed voice overrides should be changed values, got %v", changed)
+	}
+}
@@ -0,0 +1,130 @@
+package setting
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/discord"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+func FnVoiceOverrides(sett *storage.GuildSettings, args []string) (interface{}, bool) {
+	if sett == nil || len(args) < 2 {
+		return nil, false
+	}
+	if len(args) == 2 {
+		if len(sett.VoiceOverrides) == 0 {
+			return ConstructEmbedForSetting(sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingVoiceOverrides.noOverrides",
+				Other: "No Voice Overrides",
+			}), AllSettings[VoiceOverrides], sett), false
+		}
+		overrides := make([]string, 0, len(sett.VoiceOverrides))
+		for userID, override := range sett.VoiceOverrides {
+			overrides = append(overrides, discord.MentionByUserID(userID)+": "+string(override))
+		}
+		sort.Strings(overrides)
+		return ConstructEmbedForSetting(strings.Join(overrides, "\n"), AllSettings[VoiceOverrides], sett), false
+	}
+
+	if args[2] == "clear" || args[2] == "c" {
+		if len(sett.VoiceOverrides) == 0 {
+			return Unchanged(sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingVoiceOverrides.alreadyClear",
+				Other: "There are no voice overrides to clear!",
+			})), false
+		}
+		sett.VoiceOverrides = map[string]storage.VoiceOverride{}
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingVoiceOverrides.clearOverrides",
+			Other: "Clearing all voice overrides!",
+		}), true
+	}
+
+	userID, err := discord.ExtractUserIDFromMention(args[2])
+	if userID == "" || err != nil {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingVoiceOverrides.notFound",
+			Other: "Sorry, I don't know who `{{.UserName}}` is. You can pass in ID or @mention",
+		},
+			map[string]interface{}{
+				"UserName": args[2],
+			}), false
+	}
+	current, hasOverride := sett.GetVoiceOverride(userID)
+
+	if len(args) == 3 {
+		if !hasOverride {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingVoiceOverrides.noOverride",
+				Other: "<@{{.UserID}}> has no voice override",
+			},
+				map[string]interface{}{
+					"UserID": userID,
+				}), false
+		}
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingVoiceOverrides.currentOverride",
+			Other: "<@{{.UserID}}> is marked as `{{.Override}}`",
+		},
+			map[string]interface{}{
+				"UserID":   userID,
+				"Override": current,
+			}), false
+	}
+
+	arg := strings.ToLower(args[3])
+	if arg == "clear" || arg == "c" {
+		if !hasOverride {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingVoiceOverrides.noOverride",
+				Other: "<@{{.UserID}}> has no voice override",
+			},
+				map[string]interface{}{
+					"UserID": userID,
+				}), false
+		}
+		sett.ClearVoiceOverride(userID)
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingVoiceOverrides.cleared",
+			Other: "<@{{.UserID}}> will now follow the voice rules like everyone else",
+		},
+			map[string]interface{}{
+				"UserID": userID,
+			}), true
+	}
+
+	for _, override := range storage.AllVoiceOverrides {
+		if arg != string(override) {
+			continue
+		}
+		if hasOverride && current == override {
+			return Unchanged(sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingVoiceOverrides.alreadySet",
+				Other: "<@{{.UserID}}> is already marked as `{{.Override}}`!",
+			},
+				map[string]interface{}{
+					"UserID":   userID,
+					"Override": override,
+				})), false
+		}
+		sett.SetVoiceOverride(userID, override)
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingVoiceOverrides.set",
+			Other: "<@{{.UserID}}> is now marked as `{{.Override}}`",
+		},
+			map[string]interface{}{
+				"UserID":   userID,
+				"Override": override,
+			}), true
+	}
+
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "settings.SettingVoiceOverrides.wrongArg",
+		Other: "Sorry, `{{.Arg}}` is not a voice override. Use `exempt`, `spectator`, `deafentasks` or `clear`",
+	},
+		map[string]interface{}{
+			"Arg": args[3],
+		}), false
+}
@@ -0,0 +1,70 @@
+package setting
+
+import (
+	"testing"
+
+	"github.com/automuteus/automuteus/storage"
+)
+
+func TestFnVoiceOverrides(t *testing.T) {
+	sett, err := testSettingsFn(FnVoiceOverrides)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, valid := FnVoiceOverrides(sett, []string{"sett", "overrides", "somegarbage", "exempt"})
+	if valid {
+		t.Error("Garbage user arg shouldn't result in a valid settings change")
+	}
+
+	_, valid = FnVoiceOverrides(sett, []string{"sett", "overrides", "<@!888888066283941888>", "nevermute"})
+	if valid {
+		t.Error("Unknown override shouldn't result in a valid settings change")
+	}
+
+	_, valid = FnVoiceOverrides(sett, []string{"sett", "overrides", "<@!888888066283941888>", "exempt"})
+	if !valid {
+		t.Error("Valid override should result in a valid settings change")
+	}
+	if override, ok := sett.GetVoiceOverride("888888066283941888"); !ok || override != storage.OverrideExempt {
+		t.Error("Valid override (\"exempt\") was not set correctly")
+	}
+
+	_, valid = FnVoiceOverrides(sett, []string{"sett", "overrides", "888888066283941888", "exempt"})
+	if valid {
+		t.Error("Identical override shouldn't result in a valid settings change")
+	}
+
+	_, valid = FnVoiceOverrides(sett, []string{"sett", "overrides", "888888066283941888", "DeafenTasks"})
+	if !valid {
+		t.Error("Changing the override should result in a valid settings change")
+	}
+	if override, _ := sett.GetVoiceOverride("888888066283941888"); override != storage.OverrideDeafenTasks {
+		t.Error("Valid override (\"deafentasks\") was not set correctly")
+	}
+
+	_, valid = FnVoiceOverrides(sett, []string{"sett", "overrides", "<@!140581888888888888>", "clear"})
+	if valid {
+		t.Error("Clearing a user without an override shouldn't result in a valid settings change")
+	}
+
+	_, valid = FnVoiceOverrides(sett, []string{"sett", "overrides", "<@!140581888888888888>", "spectator"})
+	if !valid {
+		t.Error("Valid override should result in a valid settings change")
+	}
+	_, valid = FnVoiceOverrides(sett, []string{"sett", "overrides", "<@!140581888888888888>", "clear"})
+	if !valid {
+		t.Error("Clearing a user's override should result in a valid settings change")
+	}
+	if _, ok := sett.GetVoiceOverride("140581888888888888"); ok {
+		t.Error("Cleared override should be removed")
+	}
+
+	_, valid = FnVoiceOverrides(sett, []string{"sett", "overrides", "clear"})
+	if !valid {
+		t.Error("Clearing all overrides should be a valid settings change")
+	}
+	if len(sett.VoiceOverrides) != 0 {
+		t.Error("Expected 0 overrides after clearing")
+	}
+}
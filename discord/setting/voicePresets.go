@@ -0,0 +1,153 @@
+package setting
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/automuteus/automuteus/storage"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+var voicePresetNameRegex = regexp.MustCompile(`^[a-z0-9-]{1,24}$`)
+
+func FnVoicePresets(sett *storage.GuildSettings, args []string) (interface{}, bool) {
+	if sett == nil || len(args) < 2 {
+		return nil, false
+	}
+	if len(args) == 2 {
+		return ConstructEmbedForSetting(VoicePresetList(sett), AllSettings[VoicePresets], sett), false
+	}
+
+	arg := strings.ToLower(args[2])
+	if arg == "clear" || arg == "c" {
+		if len(sett.VoicePresets) == 0 {
+			return Unchanged(sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingVoicePresets.alreadyClear",
+				Other: "There are no voice presets to clear!",
+			})), false
+		}
+		sett.VoicePresets = map[string]storage.VoicePreset{}
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingVoicePresets.clearPresets",
+			Other: "Deleting all the voice presets of this server!",
+		}), true
+	}
+	if len(args) == 3 {
+		return ConstructEmbedForSetting(VoicePresetList(sett), AllSettings[VoicePresets], sett), false
+	}
+
+	name := strings.ToLower(args[3])
+	_, isBuiltin := storage.BuiltinVoicePresets[name]
+	_, isCustom := sett.VoicePresets[name]
+	switch arg {
+	case "save", "s":
+		switch {
+		case isBuiltin:
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingVoicePresets.builtin",
+				Other: "`{{.Name}}` is a built-in preset; please pick another name",
+			},
+				map[string]interface{}{
+					"Name": name,
+				}), false
+		case !voicePresetNameRegex.MatchString(name):
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingVoicePresets.invalidName",
+				Other: "Sorry, `{{.Name}}` is not a valid preset name. Use up to 24 letters, numbers or dashes",
+			},
+				map[string]interface{}{
+					"Name": args[3],
+				}), false
+		case !isCustom && len(sett.VoicePresets) >= storage.MaxVoicePresets:
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingVoicePresets.tooMany",
+				Other: "Sorry, a server can't have more than {{.Max}} voice presets. Please `delete` one first",
+			},
+				map[string]interface{}{
+					"Max": storage.MaxVoicePresets,
+				}), false
+		}
+		sett.SetVoicePreset(name, sett.CurrentVoicePreset())
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingVoicePresets.saved",
+			Other: "Saved the current voice rules, delays, `unmuteDeadDuringTasks` and `muteSpectators` settings as the `{{.Name}}` preset",
+		},
+			map[string]interface{}{
+				"Name": name,
+			}), true
+	case "apply", "a":
+		preset, ok := sett.GetVoicePreset(name)
+		if !ok {
+			return unknownVoicePreset(sett, name), false
+		}
+		sett.ApplyVoicePreset(preset)
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingVoicePresets.applied",
+			Other: "The voice settings of this server are now the ones of the `{{.Name}}` preset",
+		},
+			map[string]interface{}{
+				"Name": name,
+			}), true
+	case "delete", "d":
+		if isBuiltin {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingVoicePresets.deleteBuiltin",
+				Other: "`{{.Name}}` is a built-in preset, so it can't be deleted",
+			},
+				map[string]interface{}{
+					"Name": name,
+				}), false
+		}
+		if !isCustom {
+			return unknownVoicePreset(sett, name), false
+		}
+		sett.DeleteVoicePreset(name)
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingVoicePresets.deleted",
+			Other: "Deleted the `{{.Name}}` preset",
+		},
+			map[string]interface{}{
+				"Name": name,
+			}), true
+	default:
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingVoicePresets.wrongArg",
+			Other: "Sorry, `{{.Arg}}` is not `save`, `apply`, `delete` or `clear`",
+		},
+			map[string]interface{}{
+				"Arg": args[2],
+			}), false
+	}
+}
+
+// VoicePresetList lists the names of the builtin and custom voice presets
+func VoicePresetList(sett *storage.GuildSettings) string {
+	builtin, custom := sett.VoicePresetNames()
+	list := sett.LocalizeMessage(&i18n.Message{
+		ID:    "settings.SettingVoicePresets.builtinList",
+		Other: "Built-in: `{{.Presets}}`",
+	},
+		map[string]interface{}{
+			"Presets": strings.Join(builtin, "`, `"),
+		})
+	if len(custom) > 0 {
+		list += "\n" + sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingVoicePresets.customList",
+			Other: "Custom: `{{.Presets}}`",
+		},
+			map[string]interface{}{
+				"Presets": strings.Join(custom, "`, `"),
+			})
+	}
+	return list
+}
+
+func unknownVoicePreset(sett *storage.GuildSettings, name string) string {
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "settings.SettingVoicePresets.unknown",
+		Other: "Sorry, there's no `{{.Name}}` preset",
+	},
+		map[string]interface{}{
+			"Name": name,
+		})
+}
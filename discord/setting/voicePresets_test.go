@@ -0,0 +1,69 @@
+package setting
+
+import (
+	"testing"
+
+	"github.com/automuteus/utils/pkg/game"
+)
+
+func TestFnVoicePresets(t *testing.T) {
+	sett, err := testSettingsFn(FnVoicePresets)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, valid := FnVoicePresets(sett, []string{"sett", "presets", "apply", "nosuchpreset"})
+	if valid {
+		t.Error("Applying an unknown preset shouldn't result in a valid settings change")
+	}
+
+	_, valid = FnVoicePresets(sett, []string{"sett", "presets", "save", "classic"})
+	if valid {
+		t.Error("Saving over a built-in preset shouldn't result in a valid settings change")
+	}
+
+	_, valid = FnVoicePresets(sett, []string{"sett", "presets", "save", "not a name!"})
+	if valid {
+		t.Error("Invalid preset name shouldn't result in a valid settings change")
+	}
+
+	_, valid = FnVoicePresets(sett, []string{"sett", "presets", "apply", "casual"})
+	if !valid {
+		t.Error("Applying a built-in preset should result in a valid settings change")
+	}
+	if mute, deaf := sett.GetVoiceState(true, true, game.TASKS); !mute || deaf {
+		t.Error("The casual preset should mute alive players during tasks, without deafening them")
+	}
+
+	_, valid = FnVoicePresets(sett, []string{"sett", "presets", "save", "Quiet"})
+	if !valid {
+		t.Error("Saving a preset should result in a valid settings change")
+	}
+	if _, ok := sett.VoicePresets["quiet"]; !ok {
+		t.Error("The preset should be saved with a lowercase name")
+	}
+
+	_, valid = FnVoicePresets(sett, []string{"sett", "presets", "apply", "classic"})
+	if !valid {
+		t.Error("Applying a built-in preset should result in a valid settings change")
+	}
+	_, valid = FnVoicePresets(sett, []string{"sett", "presets", "apply", "quiet"})
+	if !valid {
+		t.Error("Applying a custom preset should result in a valid settings change")
+	}
+	if _, deaf := sett.GetVoiceState(true, true, game.TASKS); deaf {
+		t.Error("The custom preset wasn't applied")
+	}
+
+	_, valid = FnVoicePresets(sett, []string{"sett", "presets", "delete", "classic"})
+	if valid {
+		t.Error("Deleting a built-in preset shouldn't result in a valid settings change")
+	}
+	_, valid = FnVoicePresets(sett, []string{"sett", "presets", "delete", "quiet"})
+	if !valid {
+		t.Error("Deleting a custom preset should result in a valid settings change")
+	}
+	if len(sett.VoicePresets) != 0 {
+		t.Error("Expected 0 custom presets after deleting")
+	}
+}
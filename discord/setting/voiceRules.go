@@ -2,12 +2,12 @@ package setting
 
 import (
 	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/storage"
 	"github.com/automuteus/utils/pkg/game"
-	"github.com/automuteus/utils/pkg/settings"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 )
 
-func FnVoiceRules(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+func FnVoiceRules(sett *storage.GuildSettings, args []string) (interface{}, bool) {
 	if sett == nil || len(args) < 2 {
 		return nil, false
 	}
@@ -113,7 +113,7 @@ func FnVoiceRules(sett *settings.GuildSettings, args []string) (interface{}, boo
 
 	if newValue == oldValue {
 		if newValue {
-			return sett.LocalizeMessage(&i18n.Message{
+			return Unchanged(sett.LocalizeMessage(&i18n.Message{
 				ID:    "settings.SettingVoiceRules.queryingAlreadyValues",
 				Other: "When in `{{.PhaseName}}` phase, {{.PlayerGameState}} players are already {{.PlayerDiscordState}}!",
 			},
@@ -121,9 +121,9 @@ func FnVoiceRules(sett *settings.GuildSettings, args []string) (interface{}, boo
 					"PhaseName":          args[3],
 					"PlayerGameState":    args[4],
 					"PlayerDiscordState": args[2],
-				}), false
+				})), false
 		} else {
-			return sett.LocalizeMessage(&i18n.Message{
+			return Unchanged(sett.LocalizeMessage(&i18n.Message{
 				ID:    "settings.SettingVoiceRules.queryingAlreadyUnValues",
 				Other: "When in `{{.PhaseName}}` phase, {{.PlayerGameState}} players are already un{{.PlayerDiscordState}}!",
 			},
@@ -131,7 +131,7 @@ func FnVoiceRules(sett *settings.GuildSettings, args []string) (interface{}, boo
 					"PhaseName":          args[3],
 					"PlayerGameState":    args[4],
 					"PlayerDiscordState": args[2],
-				}), false
+				})), false
 		}
 	}
 
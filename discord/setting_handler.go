@@ -4,8 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/automuteus/automuteus/discord/setting"
-	"github.com/automuteus/utils/pkg/settings"
-	"os"
+	"github.com/automuteus/automuteus/storage"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
@@ -29,101 +28,150 @@ func getSetting(arg string) setting.SettingType {
 	return setting.NullSetting
 }
 
-func (bot *Bot) HandleSettingsCommand(m *discordgo.MessageCreate, sett *settings.GuildSettings, args []string, prem bool) (string, interface{}) {
+func (bot *Bot) HandleSettingsCommand(m *discordgo.MessageCreate, sett *storage.GuildSettings, args []string, prem bool) (string, interface{}) {
 	if len(args) == 1 {
 		return m.ChannelID, settingResponse(sett.GetCommandPrefix(), setting.AllSettings, sett, prem)
 	}
 	var sendMsg interface{}
 	// if command invalid, no need to reapply changes to json file
 	isValid := false
+	before := copyGuildSettings(sett)
 
 	settType := getSetting(args[1])
+	switch settType {
+	case setting.Show:
+		jBytes, err := json.MarshalIndent(sett, "", "  ")
+		if err != nil {
+			log.Println(err)
+			return m.ChannelID, err
+		}
+		// TODO need to consider if the settings are too long? Is that possible?
+		return m.ChannelID, fmt.Sprintf("```JSON\n%s\n```", jBytes)
+	case setting.Export:
+		return m.ChannelID, bot.handleSettingsExport(m, sett, args)
+	case setting.Import:
+		return m.ChannelID, bot.handleSettingsImport(m, sett, prem)
+	case setting.History:
+		return m.ChannelID, bot.handleSettingsHistory(m, sett, args)
+	case setting.Rollback:
+		return m.ChannelID, bot.handleSettingsRollback(m, sett, args)
+	case setting.Reset:
+		sett = storage.MakeGuildSettings(bot.StorageInterface.GlobalPrefix)
+		sendMsg = sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.HandleSettingsCommand.reset",
+			Other: "Resetting guild settings to default values. Type `{{.CommandPrefix}} settings rollback` to undo it",
+		},
+			map[string]interface{}{
+				"CommandPrefix": before.GetCommandPrefix(),
+			})
+		isValid = true
+	case setting.NullSetting:
+		return m.ChannelID, sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.HandleSettingsCommand.default",
+			Other: "Sorry, `{{.Arg}}` is not a valid setting!\n",
+		},
+			map[string]interface{}{
+				"Arg": args[1],
+			})
+	default:
+		sendMsg, isValid, _ = applySetting(sett, settType, args, prem)
+	}
+
+	// TODO do another check of validation for roleIDs and channelIDs here
+	// we have the bot/discord scope to allow querying discord and making sure they're valid
+	if isValid {
+		err := bot.StorageInterface.SetGuildSettings(m.GuildID, sett)
+		if err != nil {
+			log.Println(err)
+		} else {
+			bot.recordSettingsChanges(m.GuildID, before, sett, settingsChanges(m.Author.ID, before, sett))
+		}
+	}
+	return m.ChannelID, sendMsg
+}
+
+// applySetting views or changes a single setting, with the same args as the settings command. Show and reset aren't
+// single settings, so they're only handled by HandleSettingsCommand. isValid is only true if the setting changed;
+// unchanged is true instead if the args are valid, but the setting has that value already
+func applySetting(sett *storage.GuildSettings, settType setting.SettingType, args []string, prem bool) (msg interface{}, isValid bool, unchanged bool) {
+	msg, isValid = changeSetting(sett, settType, args, prem)
+	if m, ok := msg.(setting.Unchanged); ok {
+		return string(m), false, true
+	}
+	return msg, isValid, false
+}
+
+func changeSetting(sett *storage.GuildSettings, settType setting.SettingType, args []string, prem bool) (interface{}, bool) {
 	switch settType {
 	case setting.Prefix:
-		sendMsg, isValid = setting.FnCommandPrefix(sett, args)
+		return setting.FnCommandPrefix(sett, args)
 	case setting.Language:
-		sendMsg, isValid = setting.FnLanguage(sett, args)
+		return setting.FnLanguage(sett, args)
 	case setting.AdminUserIDs:
-		sendMsg, isValid = setting.FnAdminUserIDs(sett, args)
+		return setting.FnAdminUserIDs(sett, args)
 	case setting.RoleIDs:
-		sendMsg, isValid = setting.FnPermissionRoleIDs(sett, args)
+		return setting.FnPermissionRoleIDs(sett, args)
 	case setting.UnmuteDead:
-		sendMsg, isValid = setting.FnUnmuteDeadDuringTasks(sett, args)
+		return setting.FnUnmuteDeadDuringTasks(sett, args)
 	case setting.Delays:
-		sendMsg, isValid = setting.FnDelays(sett, args)
+		return setting.FnDelays(sett, args)
 	case setting.VoiceRules:
-		sendMsg, isValid = setting.FnVoiceRules(sett, args)
+		return setting.FnVoiceRules(sett, args)
 	case setting.MapVersion:
-		sendMsg, isValid = setting.FnMapVersion(sett, args)
+		return setting.FnMapVersion(sett, args)
 	case setting.MatchSummary:
 		if !prem {
-			return m.ChannelID, nonPremiumSettingResponse(sett)
+			return nonPremiumSettingResponse(sett), false
 		}
-		sendMsg, isValid = setting.FnMatchSummary(sett, args)
+		return setting.FnMatchSummary(sett, args)
 	case setting.MatchSummaryChannel:
 		if !prem {
-			return m.ChannelID, nonPremiumSettingResponse(sett)
+			return nonPremiumSettingResponse(sett), false
 		}
-		sendMsg, isValid = setting.FnMatchSummaryChannel(sett, args)
+		return setting.FnMatchSummaryChannel(sett, args)
 	case setting.AutoRefresh:
 		if !prem {
-			return m.ChannelID, nonPremiumSettingResponse(sett)
+			return nonPremiumSettingResponse(sett), false
 		}
-		sendMsg, isValid = setting.FnAutoRefresh(sett, args)
+		return setting.FnAutoRefresh(sett, args)
 	case setting.LeaderboardMention:
 		if !prem {
-			return m.ChannelID, nonPremiumSettingResponse(sett)
+			return nonPremiumSettingResponse(sett), false
 		}
-		sendMsg, isValid = setting.FnLeaderboardNameMention(sett, args)
+		return setting.FnLeaderboardNameMention(sett, args)
 	case setting.LeaderboardSize:
 		if !prem {
-			return m.ChannelID, nonPremiumSettingResponse(sett)
+			return nonPremiumSettingResponse(sett), false
 		}
-		sendMsg, isValid = setting.FnLeaderboardSize(sett, args)
+		return setting.FnLeaderboardSize(sett, args)
 	case setting.LeaderboardMin:
 		if !prem {
-			return m.ChannelID, nonPremiumSettingResponse(sett)
+			return nonPremiumSettingResponse(sett), false
 		}
-		sendMsg, isValid = setting.FnLeaderboardMin(sett, args)
+		return setting.FnLeaderboardMin(sett, args)
 	case setting.MuteSpectators:
 		if !prem {
-			return m.ChannelID, nonPremiumSettingResponse(sett)
+			return nonPremiumSettingResponse(sett), false
 		}
-		sendMsg, isValid = setting.FnMuteSpectators(sett, args)
+		return setting.FnMuteSpectators(sett, args)
 	case setting.DisplayRoomCode:
 		if !prem {
-			return m.ChannelID, nonPremiumSettingResponse(sett)
-		}
-		sendMsg, isValid = setting.FnDisplayRoomCode(sett, args)
-	case setting.Show:
-		jBytes, err := json.MarshalIndent(sett, "", "  ")
-		if err != nil {
-			log.Println(err)
-			return m.ChannelID, err
+			return nonPremiumSettingResponse(sett), false
 		}
-		// TODO need to consider if the settings are too long? Is that possible?
-		return m.ChannelID, fmt.Sprintf("```JSON\n%s\n```", jBytes)
-	case setting.Reset:
-		sett = settings.MakeGuildSettings(os.Getenv("AUTOMUTEUS_GLOBAL_PREFIX"))
-		sendMsg = "Resetting guild settings to default values"
-		isValid = true
+		return setting.FnDisplayRoomCode(sett, args)
+	case setting.VoiceOverrides:
+		return setting.FnVoiceOverrides(sett, args)
+	case setting.MoveDeadPlayers:
+		return setting.FnMoveDeadPlayers(sett, args)
+	case setting.GhostChannel:
+		return setting.FnGhostChannel(sett, args)
+	case setting.AuditChannel:
+		return setting.FnAuditChannel(sett, args)
+	case setting.VoicePresets:
+		return setting.FnVoicePresets(sett, args)
+	case setting.BenchChannel:
+		return setting.FnBenchChannel(sett, args)
 	default:
-		return m.ChannelID, sett.LocalizeMessage(&i18n.Message{
-			ID:    "settings.HandleSettingsCommand.default",
-			Other: "Sorry, `{{.Arg}}` is not a valid setting!\n",
-		},
-			map[string]interface{}{
-				"Arg": args[1],
-			})
-	}
-
-	// TODO do another check of validation for roleIDs and channelIDs here
-	// we have the bot/discord scope to allow querying discord and making sure they're valid
-	if isValid {
-		err := bot.StorageInterface.SetGuildSettings(m.GuildID, sett)
-		if err != nil {
-			log.Println(err)
-		}
+		return nil, false
 	}
-	return m.ChannelID, sendMsg
 }
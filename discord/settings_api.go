@@ -0,0 +1,287 @@
+package discord
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/automuteus/automuteus/discord/setting"
+	"github.com/automuteus/automuteus/logging"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/premium"
+	"github.com/bwmarrin/discordgo"
+	"github.com/gorilla/mux"
+)
+
+// SettingsAPIUserTTL is how long the User owning a bearer token is cached for
+const SettingsAPIUserTTL = time.Minute
+
+type settingsStore interface {
+	GetGuildSettings(guildID string) *storage.GuildSettings
+	SetGuildSettings(guildID string, guildSettings *storage.GuildSettings) error
+}
+
+// SettingsAPI serves the settings of a guild over HTTP (for dashboards, for example). Requests are authenticated with
+// the Discord OAuth2 bearer token of a User, who needs the same permissions as for the settings command.
+//
+// PUT and PATCH take a JSON object of setting names (or aliases) to values, where a value is the same arguments that
+// the settings command takes (as a string, or a list of strings). PUT resets any setting that isn't provided
+type SettingsAPI struct {
+	store     settingsStore
+	identify  func(token string) (*discordgo.User, error)
+	guild     func(guildID string) (*discordgo.Guild, error)
+	member    func(guildID, userID string) (*discordgo.Member, error)
+	isPremium func(guildID string) bool
+	record    func(guildID string, before, after *storage.GuildSettings, changes []storage.SettingsChange)
+	logger    *logging.Logger
+	// globalPrefix is the prefix of the default settings that PUT starts from
+	globalPrefix string
+}
+
+func (bot *Bot) NewSettingsAPI() *SettingsAPI {
+	return &SettingsAPI{
+		store:    bot.StorageInterface,
+		identify: cachedUsers(identifyDiscordUser, SettingsAPIUserTTL),
+		guild:    bot.PrimarySession.State.Guild,
+		member: func(guildID, userID string) (*discordgo.Member, error) {
+			member, err := bot.PrimarySession.State.Member(guildID, userID)
+			if err != nil {
+				return bot.PrimarySession.GuildMember(guildID, userID)
+			}
+			return member, nil
+		},
+		isPremium: func(guildID string) bool {
+			return bot.getPremiumTier(guildID) != premium.FreeTier
+		},
+		record:       bot.recordSettingsChanges,
+		logger:       bot.logger.With("component", "settingsAPI"),
+		globalPrefix: bot.StorageInterface.GlobalPrefix,
+	}
+}
+
+func identifyDiscordUser(token string) (*discordgo.User, error) {
+	sess, err := discordgo.New("Bearer " + token)
+	if err != nil {
+		return nil, err
+	}
+	return sess.User("@me")
+}
+
+type cachedUser struct {
+	user    *discordgo.User
+	expires time.Time
+}
+
+// cachedUsers caches the User owning each bearer token for ttl, so dashboards polling the settings don't identify
+// their User with Discord on every single request (and run into the rate limits). Failures aren't cached
+func cachedUsers(identify func(token string) (*discordgo.User, error), ttl time.Duration) func(token string) (*discordgo.User, error) {
+	var lock sync.Mutex
+	users := map[[sha256.Size]byte]cachedUser{}
+	return func(token string) (*discordgo.User, error) {
+		// don't keep the tokens themselves around
+		key := sha256.Sum256([]byte(token))
+		now := time.Now()
+		lock.Lock()
+		cached, ok := users[key]
+		lock.Unlock()
+		if ok && now.Before(cached.expires) {
+			return cached.user, nil
+		}
+
+		user, err := identify(token)
+		if err != nil || user == nil {
+			return user, err
+		}
+		lock.Lock()
+		for k, v := range users {
+			if !now.Before(v.expires) {
+				delete(users, k)
+			}
+		}
+		users[key] = cachedUser{user: user, expires: now.Add(ttl)}
+		lock.Unlock()
+		return user, nil
+	}
+}
+
+func (bot *Bot) StartSettingsAPIServer(port string) {
+	bot.logger.Info("serving the guild settings API", "port", port)
+	err := http.ListenAndServe(":"+port, bot.NewSettingsAPI().Router())
+	if err != nil {
+		bot.logger.Error("settings API server stopped", "err", err)
+	}
+}
+
+func (api *SettingsAPI) Router() *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/guilds/{guildID}/settings", api.handleGet).Methods(http.MethodGet)
+	r.HandleFunc("/guilds/{guildID}/settings", api.handlePut).Methods(http.MethodPut)
+	r.HandleFunc("/guilds/{guildID}/settings", api.handlePatch).Methods(http.MethodPatch)
+	return r
+}
+
+type settingsAPIError struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+func (api *SettingsAPI) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		api.logger.Error("failed to write the response", "err", err)
+	}
+}
+
+// authorize returns the settings of the guild (and the User ID) if the User owning the bearer token is an admin for
+// the bot; it writes the error response otherwise
+func (api *SettingsAPI) authorize(w http.ResponseWriter, r *http.Request) (string, string, *storage.GuildSettings, bool) {
+	guildID := mux.Vars(r)["guildID"]
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == r.Header.Get("Authorization") {
+		api.writeJSON(w, http.StatusUnauthorized, settingsAPIError{Error: "missing bearer token"})
+		return "", "", nil, false
+	}
+	user, err := api.identify(token)
+	if err != nil || user == nil {
+		api.writeJSON(w, http.StatusUnauthorized, settingsAPIError{Error: "invalid bearer token"})
+		return "", "", nil, false
+	}
+
+	g, err := api.guild(guildID)
+	if err != nil || g == nil {
+		api.writeJSON(w, http.StatusNotFound, settingsAPIError{Error: "unknown guild"})
+		return "", "", nil, false
+	}
+	member, err := api.member(guildID, user.ID)
+	if err != nil || member == nil {
+		api.writeJSON(w, http.StatusForbidden, settingsAPIError{Error: "not a member of the guild"})
+		return "", "", nil, false
+	}
+
+	sett := api.store.GetGuildSettings(guildID)
+	isAdmin, _ := getPermissions(g, sett, user, member)
+	if !isAdmin {
+		api.writeJSON(w, http.StatusForbidden, settingsAPIError{Error: "missing admin permissions for the bot"})
+		return "", "", nil, false
+	}
+	return guildID, user.ID, sett, true
+}
+
+func (api *SettingsAPI) handleGet(w http.ResponseWriter, r *http.Request) {
+	_, _, sett, ok := api.authorize(w, r)
+	if !ok {
+		return
+	}
+	api.writeJSON(w, http.StatusOK, sett)
+}
+
+func (api *SettingsAPI) handlePut(w http.ResponseWriter, r *http.Request) {
+	guildID, userID, sett, ok := api.authorize(w, r)
+	if !ok {
+		return
+	}
+	api.update(w, r, guildID, userID, sett, storage.MakeGuildSettings(api.globalPrefix))
+}
+
+func (api *SettingsAPI) handlePatch(w http.ResponseWriter, r *http.Request) {
+	guildID, userID, sett, ok := api.authorize(w, r)
+	if !ok {
+		return
+	}
+	api.update(w, r, guildID, userID, sett, copyGuildSettings(sett))
+}
+
+// update applies the changes in the body onto sett, and saves it in place of the current settings of the guild
+func (api *SettingsAPI) update(w http.ResponseWriter, r *http.Request, guildID, userID string, current, sett *storage.GuildSettings) {
+	changes := map[string]interface{}{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&changes); err != nil {
+		api.writeJSON(w, http.StatusBadRequest, settingsAPIError{Error: "body must be a JSON object of settings to values"})
+		return
+	}
+
+	prem := api.isPremium(guildID)
+	names := make([]string, 0, len(changes))
+	for name := range changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	invalid := map[string]string{}
+	for _, name := range names {
+		values, err := settingAPIArgs(changes[name])
+		if err != nil {
+			invalid[name] = err.Error()
+			continue
+		}
+		if msg := applySettingFromAPI(sett, name, values, prem); msg != "" {
+			invalid[name] = msg
+		}
+	}
+	if len(invalid) > 0 {
+		api.writeJSON(w, http.StatusUnprocessableEntity, settingsAPIError{Error: "invalid settings", Fields: invalid})
+		return
+	}
+
+	err := api.store.SetGuildSettings(guildID, sett)
+	if err != nil {
+		api.logger.Error("failed to save the settings", "guildID", guildID, "err", err)
+		api.writeJSON(w, http.StatusInternalServerError, settingsAPIError{Error: "couldn't save the settings"})
+		return
+	}
+	api.record(guildID, current, sett, settingsChanges(userID, current, sett))
+	api.writeJSON(w, http.StatusOK, sett)
+}
+
+// settingAPIArgs converts a JSON value into the arguments the settings command would take
+func settingAPIArgs(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v), nil
+	case bool, json.Number:
+		return []string{fmt.Sprint(v)}, nil
+	case []interface{}:
+		args := make([]string, 0, len(v))
+		for _, arg := range v {
+			str, ok := arg.(string)
+			if !ok {
+				return nil, errors.New("lists of values may only contain strings")
+			}
+			args = append(args, str)
+		}
+		return args, nil
+	default:
+		return nil, errors.New("values must be a string, list of strings, number or boolean")
+	}
+}
+
+// applySettingFromAPI changes a setting just like the settings command would, returning why the change is invalid
+// (if it is). Values identical to the current ones aren't a change for the settings command, but they're accepted here
+func applySettingFromAPI(sett *storage.GuildSettings, name string, values []string, prem bool) string {
+	settType := getSetting(strings.ToLower(name))
+	switch settType {
+	case setting.NullSetting, setting.Show, setting.Export, setting.Import, setting.History, setting.Rollback, setting.Reset:
+		return "unknown setting"
+	}
+	if len(values) == 0 {
+		return "missing value"
+	}
+	msg, isValid, unchanged := applySetting(sett, settType, append([]string{"settings", name}, values...), prem)
+	if isValid || unchanged {
+		return ""
+	}
+	if str, ok := msg.(string); ok {
+		return strings.TrimSpace(str)
+	}
+	return "invalid value"
+}
@@ -0,0 +1,221 @@
+package discord
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/automuteus/automuteus/storage"
+	"github.com/bwmarrin/discordgo"
+)
+
+type testSettingsStore map[string]*storage.GuildSettings
+
+func (store testSettingsStore) GetGuildSettings(guildID string) *storage.GuildSettings {
+	if sett, ok := store[guildID]; ok {
+		// copy, like fetching from Redis would
+		jBytes, _ := json.Marshal(sett)
+		cpy := storage.GuildSettings{}
+		json.Unmarshal(jBytes, &cpy)
+		return &cpy
+	}
+	return storage.MakeGuildSettings("")
+}
+
+func (store testSettingsStore) SetGuildSettings(guildID string, guildSettings *storage.GuildSettings) error {
+	store[guildID] = guildSettings
+	return nil
+}
+
+func testSettingsAPI(store testSettingsStore, history *[]storage.SettingsChange) *httptest.Server {
+	api := &SettingsAPI{
+		store: store,
+		identify: func(token string) (*discordgo.User, error) {
+			// tokens are just user IDs here
+			if token == "invalid" {
+				return nil, errors.New("401 Unauthorized")
+			}
+			return &discordgo.User{ID: token}, nil
+		},
+		guild: func(guildID string) (*discordgo.Guild, error) {
+			if guildID != "1" {
+				return nil, errors.New("state cache not found")
+			}
+			return &discordgo.Guild{ID: "1", OwnerID: "140581066283941000"}, nil
+		},
+		member: func(guildID, userID string) (*discordgo.Member, error) {
+			return &discordgo.Member{User: &discordgo.User{ID: userID}}, nil
+		},
+		isPremium: func(guildID string) bool {
+			return false
+		},
+		record: func(guildID string, before, after *storage.GuildSettings, changes []storage.SettingsChange) {
+			*history = append(*history, changes...)
+		},
+	}
+	return httptest.NewServer(api.Router())
+}
+
+func settingsAPIRequest(t *testing.T, method, url, token, body string) *http.Response {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestSettingsAPI(t *testing.T) {
+	admin := storage.MakeGuildSettings(".au")
+	admin.AdminUserIDs = []string{"140581066283941888"}
+	store := testSettingsStore{"1": admin}
+	var history []storage.SettingsChange
+	server := testSettingsAPI(store, &history)
+	defer server.Close()
+	url := server.URL + "/guilds/1/settings"
+
+	for token, status := range map[string]int{"": http.StatusUnauthorized, "invalid": http.StatusUnauthorized, "140581066283941999": http.StatusForbidden, "140581066283941888": http.StatusOK, "140581066283941000": http.StatusOK} {
+		resp := settingsAPIRequest(t, http.MethodGet, url, token, "")
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("Expected status %d for token \"%s\", got %d", status, token, resp.StatusCode)
+		}
+	}
+	resp := settingsAPIRequest(t, http.MethodGet, server.URL+"/guilds/2/settings", "140581066283941888", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown guild, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	resp = settingsAPIRequest(t, http.MethodPatch, url, "140581066283941888", `{"commandPrefix": "!", "muteSpectators": true, "unmuteDead": "notabool"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d for invalid settings, got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+	if store["1"].GetCommandPrefix() == "!" {
+		t.Error("Settings shouldn't be saved if any of them are invalid")
+	}
+
+	resp = settingsAPIRequest(t, http.MethodPatch, url, "140581066283941888", `{"commandPrefix": "!", "unmuteDead": true, "moveDeadPlayers": false}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status %d for valid settings, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if store["1"].GetCommandPrefix() != "!" || !store["1"].GetUnmuteDeadDuringTasks() {
+		t.Error("Valid settings weren't saved")
+	}
+	if len(store["1"].AdminUserIDs) != 1 {
+		t.Error("Patching settings shouldn't change the other settings")
+	}
+	if len(history) != 2 || history[0].Setting != "commandPrefix" || history[0].OldValue != ".au" || history[0].NewValue != "!" || history[0].UserID != "140581066283941888" {
+		t.Errorf("Expected the prefix and unmuteDead changes to be recorded, got %v", history)
+	}
+
+	resp = settingsAPIRequest(t, http.MethodPut, url, "140581066283941888", `{"adminUserIDs": ["<@140581066283941888>"], "commandPrefix": "?"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status %d for valid settings, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if store["1"].GetCommandPrefix() != "?" || store["1"].GetUnmuteDeadDuringTasks() {
+		t.Error("Putting settings should reset the settings that aren't provided")
+	}
+	if len(history) != 4 || history[2].OldValue != "!" || history[3].Setting != "unmuteDeadDuringTasks" {
+		t.Errorf("Expected the changes from the current settings to be recorded, got %v", history[2:])
+	}
+
+	// repeating the current values isn't a change, but it's not invalid either
+	for i := 0; i < 2; i++ {
+		resp = settingsAPIRequest(t, http.MethodPatch, url, "140581066283941888", `{"commandPrefix": "?", "voiceRules": "deaf discussion alive true", "delays": ["lobby", "tasks", "3"]}`)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status %d for repeated settings, got %d", http.StatusOK, resp.StatusCode)
+		}
+	}
+	recorded := len(history)
+	resp = settingsAPIRequest(t, http.MethodPut, url, "140581066283941888", `{"adminUserIDs": ["<@140581066283941888>"], "unmuteDead": false, "voiceRules": "mute tasks alive true"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status %d for repeated default settings, got %d", http.StatusOK, resp.StatusCode)
+	}
+	resp = settingsAPIRequest(t, http.MethodPatch, url, "140581066283941888", `{"voiceRules": "deaf discussion alive maybe"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d for an invalid voice rule, got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+	if len(history) <= recorded {
+		t.Error("Expected the PUT changes to be recorded")
+	}
+}
+
+func TestApplySettingFromAPIUnchanged(t *testing.T) {
+	sett := storage.MakeGuildSettings(".au")
+	sett.SetGhostChannelID("754465589958803548")
+	tests := []struct {
+		name   string
+		values []string
+		valid  bool
+	}{
+		{"unmuteDead", []string{"false"}, true},
+		{"ghostChannel", []string{"754465589958803548"}, true},
+		{"auditChannel", []string{"clear"}, true},
+		{"moveDeadPlayers", []string{"false"}, true},
+		{"unmuteDead", []string{"maybe"}, false},
+		{"delays", []string{"lobby", "tasks", "soon"}, false},
+		{"commandPrefix", []string{"waytoolongprefix"}, false},
+	}
+	for _, test := range tests {
+		msg := applySettingFromAPI(copyGuildSettings(sett), test.name, test.values, false)
+		if test.valid && msg != "" {
+			t.Errorf("%s %v: expected the current value to be accepted, got %q", test.name, test.values, msg)
+		}
+		if !test.valid && msg == "" {
+			t.Errorf("%s %v: expected an invalid value", test.name, test.values)
+		}
+	}
+}
+
+func TestCachedUsers(t *testing.T) {
+	calls := 0
+	identify := cachedUsers(func(token string) (*discordgo.User, error) {
+		calls++
+		if token == "invalid" {
+			return nil, errors.New("401 Unauthorized")
+		}
+		return &discordgo.User{ID: token}, nil
+	}, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		user, err := identify("140581066283941888")
+		if err != nil || user.ID != "140581066283941888" {
+			t.Fatalf("Expected the User owning the token, got %v (%v)", user, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected the User to be identified once and then cached, got %d calls", calls)
+	}
+	identify("invalid")
+	identify("invalid")
+	if calls != 3 {
+		t.Errorf("Invalid tokens shouldn't be cached, got %d calls", calls)
+	}
+
+	expired := cachedUsers(func(token string) (*discordgo.User, error) {
+		calls++
+		return &discordgo.User{ID: token}, nil
+	}, 0)
+	expired("140581066283941888")
+	expired("140581066283941888")
+	if calls != 5 {
+		t.Errorf("Expired Users should be identified again, got %d calls", calls)
+	}
+}
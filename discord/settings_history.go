@@ -0,0 +1,188 @@
+package discord
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/automuteus/automuteus/discord/setting"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	SettingsHistoryPageSize = 10
+	// MaxSettingsHistoryValueLength keeps long values (like a lot of admins) from blowing past the embed limits
+	MaxSettingsHistoryValueLength = 100
+	// MaxAuditChanges is how many changes are posted to the audit channel at once; resetting the settings, for
+	// example, can change a lot of them
+	MaxAuditChanges = 20
+)
+
+// copyGuildSettings deep-copies the settings, so they can be compared after they're changed
+func copyGuildSettings(sett *storage.GuildSettings) *storage.GuildSettings {
+	cpy := storage.GuildSettings{}
+	jBytes, err := json.Marshal(sett)
+	if err == nil {
+		err = json.Unmarshal(jBytes, &cpy)
+	}
+	if err != nil {
+		log.Println(err)
+	}
+	return &cpy
+}
+
+// settingsChanges lists every setting value that the User changed from `before` to `after`
+func settingsChanges(userID string, before, after *storage.GuildSettings) []storage.SettingsChange {
+	oldValues := setting.Values(before)
+	newValues := setting.Values(after)
+	now := time.Now().Unix()
+
+	var changes []storage.SettingsChange
+	for _, name := range setting.ChangedValues(oldValues, newValues) {
+		changes = append(changes, storage.SettingsChange{
+			UserID:   userID,
+			Time:     now,
+			Setting:  name,
+			OldValue: oldValues[name],
+			NewValue: newValues[name],
+		})
+	}
+	return changes
+}
+
+// recordSettingsChanges adds the changes to the settings history of the guild, and posts them to the audit channel
+// (if there is one). The audit channel the settings had before the change is notified too, so unsetting it (or
+// resetting the settings) doesn't go by silently
+func (bot *Bot) recordSettingsChanges(guildID string, before, after *storage.GuildSettings, changes []storage.SettingsChange) {
+	if len(changes) == 0 {
+		return
+	}
+	err := bot.StorageInterface.AddSettingsChanges(guildID, changes)
+	if err != nil {
+		log.Println(err)
+	}
+
+	channelIDs := auditChannelIDs(before, after)
+	if len(channelIDs) == 0 {
+		return
+	}
+	embed := settingsChangesEmbed(after, changes, MaxAuditChanges)
+	embed.Title = after.LocalizeMessage(&i18n.Message{
+		ID:    "settings_history.recordSettingsChanges.Title",
+		Other: "Settings Changed",
+	})
+	for _, channelID := range channelIDs {
+		_, err = bot.PrimarySession.ChannelMessageSendEmbed(channelID, embed)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// auditChannelIDs returns the audit channels to post changes to; the one from before the change, and the new one
+func auditChannelIDs(before, after *storage.GuildSettings) []string {
+	var channelIDs []string
+	if before.GetAuditChannelID() != "" {
+		channelIDs = append(channelIDs, before.GetAuditChannelID())
+	}
+	if after.GetAuditChannelID() != "" && after.GetAuditChannelID() != before.GetAuditChannelID() {
+		channelIDs = append(channelIDs, after.GetAuditChannelID())
+	}
+	return channelIDs
+}
+
+func settingsChangesEmbed(sett *storage.GuildSettings, changes []storage.SettingsChange, limit int) *discordgo.MessageEmbed {
+	buf := bytes.NewBuffer([]byte{})
+	for i, change := range changes {
+		if i == limit {
+			buf.WriteString(sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings_history.settingsChangesEmbed.more",
+				Other: "...and {{.Count}} more",
+			},
+				map[string]interface{}{
+					"Count": len(changes) - limit,
+				}))
+			break
+		}
+		buf.WriteString(fmt.Sprintf("<t:%d:f> <@%s> **%s**: `%s` → `%s`\n", change.Time, change.UserID, change.Setting,
+			settingsHistoryValue(change.OldValue), settingsHistoryValue(change.NewValue)))
+	}
+	return &discordgo.MessageEmbed{
+		Description: buf.String(),
+		Color:       15844367, // GOLD
+	}
+}
+
+func settingsHistoryValue(value string) string {
+	if value == "" {
+		return "null"
+	}
+	if runes := []rune(value); len(runes) > MaxSettingsHistoryValueLength {
+		return string(runes[:MaxSettingsHistoryValueLength]) + "…"
+	}
+	return value
+}
+
+// handleSettingsHistory shows a page of the settings history of the guild, newest changes first
+func (bot *Bot) handleSettingsHistory(m *discordgo.MessageCreate, sett *storage.GuildSettings, args []string) interface{} {
+	page := 1
+	if len(args) > 2 {
+		num, err := strconv.Atoi(args[2])
+		if err != nil || num < 1 {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings_history.handleSettingsHistory.invalidPage",
+				Other: "Sorry, `{{.Arg}}` is not a valid page number",
+			},
+				map[string]interface{}{
+					"Arg": args[2],
+				})
+		}
+		page = num
+	}
+
+	changes, err := bot.StorageInterface.GetSettingsChanges(m.GuildID, int64((page-1)*SettingsHistoryPageSize), SettingsHistoryPageSize)
+	if err != nil {
+		log.Println(err)
+		return err.Error()
+	}
+	if len(changes) == 0 {
+		if page > 1 {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings_history.handleSettingsHistory.noPage",
+				Other: "There are no changes on page {{.Page}}",
+			},
+				map[string]interface{}{
+					"Page": page,
+				})
+		}
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings_history.handleSettingsHistory.empty",
+			Other: "The settings haven't been changed yet",
+		})
+	}
+
+	embed := settingsChangesEmbed(sett, changes, SettingsHistoryPageSize)
+	embed.Title = sett.LocalizeMessage(&i18n.Message{
+		ID:    "settings_history.handleSettingsHistory.Title",
+		Other: "Settings History (page {{.Page}})",
+	},
+		map[string]interface{}{
+			"Page": page,
+		})
+	embed.Footer = &discordgo.MessageEmbedFooter{
+		Text: sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings_history.handleSettingsHistory.Footer",
+			Other: "Type {{.CommandPrefix}} settings history {{.NextPage}} to see older changes",
+		},
+			map[string]interface{}{
+				"CommandPrefix": sett.GetCommandPrefix(),
+				"NextPage":      page + 1,
+			}),
+	}
+	return embed
+}
@@ -0,0 +1,33 @@
+package discord
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/automuteus/automuteus/storage"
+)
+
+func TestAuditChannelIDs(t *testing.T) {
+	withChannel := func(channelID string) *storage.GuildSettings {
+		sett := storage.MakeGuildSettings("")
+		sett.SetAuditChannelID(channelID)
+		return sett
+	}
+	tests := []struct {
+		name          string
+		before, after string
+		expected      []string
+	}{
+		{"no audit channel", "", "", nil},
+		{"unchanged", "1", "1", []string{"1"}},
+		{"cleared", "1", "", []string{"1"}},
+		{"set", "", "2", []string{"2"}},
+		{"moved", "1", "2", []string{"1", "2"}},
+	}
+	for _, test := range tests {
+		channelIDs := auditChannelIDs(withChannel(test.before), withChannel(test.after))
+		if !reflect.DeepEqual(channelIDs, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, channelIDs)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/automuteus/automuteus/storage"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+const (
+	settingsRollbackApplyID  = "settings-rollback-apply"
+	settingsRollbackCancelID = "settings-rollback-cancel"
+
+	SettingsRollbackTimeout = 10 * time.Minute
+)
+
+// settingsRollbackAction returns the rollback a button is for, and if it applies the rollback (or cancels it)
+func settingsRollbackAction(customID string) (string, bool) {
+	rollbackID := customID[strings.Index(customID, ":")+1:]
+	return rollbackID, strings.HasPrefix(customID, settingsRollbackApplyID)
+}
+
+func settingsRollbackDescription(sett *storage.GuildSettings, changes []storage.SettingsChange) string {
+	buf := bytes.NewBuffer([]byte{})
+	for i, change := range changes {
+		if i == MaxAuditChanges {
+			buf.WriteString(sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings_history.settingsChangesEmbed.more",
+				Other: "...and {{.Count}} more",
+			},
+				map[string]interface{}{
+					"Count": len(changes) - MaxAuditChanges,
+				}))
+			break
+		}
+		buf.WriteString(fmt.Sprintf("**%s**: `%s` → `%s`\n", change.Setting,
+			settingsHistoryValue(change.OldValue), settingsHistoryValue(change.NewValue)))
+	}
+	return buf.String()
+}
+
+// handleSettingsRollback shows the changes that restoring a previous version of the settings would make, with buttons
+// to apply or cancel them. The version to restore is kept in the storage until then
+func (bot *Bot) handleSettingsRollback(m *discordgo.MessageCreate, sett *storage.GuildSettings, args []string) interface{} {
+	num := 1
+	if len(args) > 2 {
+		var err error
+		num, err = strconv.Atoi(args[2])
+		if err != nil || num < 1 || num > storage.SettingsRevisions {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings_rollback.handleSettingsRollback.invalidRevision",
+				Other: "Sorry, `{{.Arg}}` is not a valid number of versions back. Use a number from 1 to {{.Max}}",
+			},
+				map[string]interface{}{
+					"Arg": args[2],
+					"Max": storage.SettingsRevisions,
+				})
+		}
+	}
+
+	revisions, err := bot.StorageInterface.GetGuildSettingsRevisions(m.GuildID)
+	if err != nil {
+		log.Println(err)
+		return err.Error()
+	}
+	if num > len(revisions) {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings_rollback.handleSettingsRollback.noRevision",
+			Other: "There are only {{.Count}} previous versions of the settings to roll back to",
+		},
+			map[string]interface{}{
+				"Count": len(revisions),
+			})
+	}
+	revision := revisions[num-1]
+
+	changes := settingsChanges(m.Author.ID, sett, revision)
+	if len(changes) == 0 {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings_rollback.handleSettingsRollback.noChanges",
+			Other: "That version of the settings is the same as the current one; there's nothing to roll back!",
+		})
+	}
+
+	err = bot.StorageInterface.SetPendingSettingsRollback(m.GuildID, m.ID, revision, SettingsRollbackTimeout)
+	if err != nil {
+		log.Println(err)
+		return err.Error()
+	}
+
+	return &discordgo.MessageSend{
+		Embed: &discordgo.MessageEmbed{
+			Title: sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings_rollback.handleSettingsRollback.title",
+				Other: "Rollback Settings",
+			}),
+			Description: sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings_rollback.handleSettingsRollback.desc",
+				Other: "Rolling back {{.Num}} version(s) will make the following changes:\n\n{{.Changes}}",
+			},
+				map[string]interface{}{
+					"Num":     num,
+					"Changes": settingsRollbackDescription(sett, changes),
+				}),
+			Color: 15844367, // GOLD
+		},
+		Components: settingsConfirmComponents(sett, settingsRollbackApplyID+":"+m.ID, settingsRollbackCancelID+":"+m.ID),
+	}
+}
+
+// handleSettingsRollbackComponent restores (or not) a previous version of the settings, once an admin confirms it
+func (bot *Bot) handleSettingsRollbackComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.GuildID == "" || i.Member == nil || i.Member.User == nil {
+		return
+	}
+	g, err := s.State.Guild(i.GuildID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	sett := bot.StorageInterface.GetGuildSettings(i.GuildID)
+	if isAdmin, _ := getPermissions(g, sett, i.Member.User, i.Member); !isAdmin {
+		respondEphemeral(s, i.Interaction, noPermsResponse(sett))
+		return
+	}
+
+	rollbackID, apply := settingsRollbackAction(i.MessageComponentData().CustomID)
+	revision, err := bot.StorageInterface.TakePendingSettingsRollback(i.GuildID, rollbackID)
+	if err != nil {
+		log.Println(err)
+	}
+
+	var content string
+	switch {
+	case revision == nil:
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings_rollback.handleSettingsRollbackComponent.expired",
+			Other: "This rollback has expired; please roll back the settings again",
+		})
+	case !apply:
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings_rollback.handleSettingsRollbackComponent.cancelled",
+			Other: "Rollback cancelled; no settings were changed",
+		})
+	default:
+		// the current settings become a revision themselves, so the rollback can be undone as well
+		err = bot.StorageInterface.SetGuildSettings(i.GuildID, revision)
+		if err != nil {
+			log.Println(err)
+			content = err.Error()
+			break
+		}
+		bot.recordSettingsChanges(i.GuildID, sett, revision, settingsChanges(i.Member.User.ID, sett, revision))
+		content = revision.LocalizeMessage(&i18n.Message{
+			ID:    "settings_rollback.handleSettingsRollbackComponent.applied",
+			Other: "Rolled back the settings!",
+		})
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,48 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/automuteus/automuteus/storage"
+)
+
+func TestSettingsRollbackAction(t *testing.T) {
+	tests := []struct {
+		customID   string
+		rollbackID string
+		apply      bool
+	}{
+		{settingsRollbackApplyID + ":123", "123", true},
+		{settingsRollbackCancelID + ":123", "123", false},
+		{settingsRollbackApplyID + ":", "", true},
+	}
+	for _, test := range tests {
+		rollbackID, apply := settingsRollbackAction(test.customID)
+		if rollbackID != test.rollbackID || apply != test.apply {
+			t.Errorf("%s: expected rollback %q (apply %v), got %q (apply %v)", test.customID, test.rollbackID, test.apply, rollbackID, apply)
+		}
+	}
+}
+
+func TestSettingsChangesRollbackReset(t *testing.T) {
+	sett := storage.MakeGuildSettings("!")
+	sett.SetAuditChannelID("754465589958803548")
+	sett.SetMoveDeadPlayers(true)
+	reset := storage.MakeGuildSettings("!")
+
+	changes := settingsChanges("140581066283941888", reset, sett)
+	changed := map[string]bool{}
+	for _, change := range changes {
+		changed[change.Setting] = true
+		if change.UserID != "140581066283941888" {
+			t.Errorf("Expected the rollback to be attributed to the admin confirming it, got %s", change.UserID)
+		}
+	}
+	if len(changes) != 2 || !changed["auditChannel"] || !changed["moveDeadPlayers"] {
+		t.Errorf("Rolling back a reset should list the settings it restores, got %v", changes)
+	}
+
+	if changes := settingsChanges("140581066283941888", sett, sett); len(changes) != 0 {
+		t.Errorf("Rolling back to the current settings shouldn't change anything, got %v", changes)
+	}
+}
@@ -0,0 +1,366 @@
+package discord
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/automuteus/automuteus/discord/setting"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/premium"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"gopkg.in/yaml.v3"
+)
+
+// SettingsExportVersion is the version of the exported settings files. Bump it whenever a change to the settings
+// means older files can't be imported as-is
+const SettingsExportVersion = 1
+
+const (
+	SettingsExportJSON = "json"
+	SettingsExportYAML = "yaml"
+)
+
+const (
+	settingsImportApplyID  = "settings-import-apply"
+	settingsImportCancelID = "settings-import-cancel"
+
+	// MaxSettingsImportBytes is far more than any settings file should ever need
+	MaxSettingsImportBytes = 256 * 1024
+	SettingsImportTimeout  = 10 * time.Minute
+)
+
+type SettingsExport struct {
+	Version    int                    `json:"version"`
+	GuildID    string                 `json:"guildID"`
+	ExportedAt string                 `json:"exportedAt"`
+	Settings   *storage.GuildSettings `json:"settings"`
+}
+
+// settingsExportMessage uploads the settings as a file in the format (SettingsExportJSON or SettingsExportYAML)
+func settingsExportMessage(guildID string, sett *storage.GuildSettings, format string) (*discordgo.MessageSend, error) {
+	jBytes, err := json.MarshalIndent(SettingsExport{
+		Version:    SettingsExportVersion,
+		GuildID:    guildID,
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		Settings:   sett,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	file := &discordgo.File{
+		Name:        fmt.Sprintf("automuteus-settings-%s.json", guildID),
+		ContentType: "application/json",
+		Reader:      bytes.NewReader(jBytes),
+	}
+	if format == SettingsExportYAML {
+		yBytes, err := jsonToYAML(jBytes)
+		if err != nil {
+			return nil, err
+		}
+		file = &discordgo.File{
+			Name:        fmt.Sprintf("automuteus-settings-%s.yaml", guildID),
+			ContentType: "application/yaml",
+			Reader:      bytes.NewReader(yBytes),
+		}
+	}
+	return &discordgo.MessageSend{
+		Content: sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings_transfer.settingsExportMessage.content",
+			Other: "Here are the settings for this server. Use `{{.CommandPrefix}} settings import` with this file attached to import them",
+		},
+			map[string]interface{}{
+				"CommandPrefix": sett.GetCommandPrefix(),
+			}),
+		Files: []*discordgo.File{file},
+	}, nil
+}
+
+// jsonToYAML converts JSON to YAML with the same keys; the settings only have JSON tags
+func jsonToYAML(jBytes []byte) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(jBytes, &v); err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer([]byte{})
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	err := encoder.Close()
+	return buf.Bytes(), err
+}
+
+func parseSettingsExport(r io.Reader) (*SettingsExport, error) {
+	jBytes, err := ioutil.ReadAll(io.LimitReader(r, MaxSettingsImportBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(jBytes) > MaxSettingsImportBytes {
+		return nil, errors.New("the file is too big to be a settings file")
+	}
+	if !json.Valid(jBytes) {
+		// YAML files are converted to JSON, so they're read with the same (JSON) keys
+		var v interface{}
+		if yaml.Unmarshal(jBytes, &v) != nil {
+			return nil, errors.New("the file isn't a valid settings file")
+		}
+		jBytes, err = json.Marshal(v)
+		if err != nil {
+			return nil, errors.New("the file isn't a valid settings file")
+		}
+	}
+	export := SettingsExport{}
+	err = json.Unmarshal(jBytes, &export)
+	if err != nil {
+		return nil, errors.New("the file isn't a valid settings file")
+	}
+	if export.Version < 1 || export.Version > SettingsExportVersion || export.Settings == nil {
+		return nil, fmt.Errorf("settings files with version %d aren't supported", export.Version)
+	}
+	return &export, nil
+}
+
+// importSettingsChanges applies the changes between the current and imported settings to the current ones, with the
+// same validation as the settings command. It returns the changes that were applied, and why any others were invalid
+func importSettingsChanges(sett, imported *storage.GuildSettings, prem bool) ([][]string, []string) {
+	var applied [][]string
+	var invalid []string
+	for _, args := range setting.ChangeArgs(sett, imported) {
+		msg, isValid, unchanged := applySetting(sett, getSetting(strings.ToLower(args[1])), args, prem)
+		if isValid {
+			applied = append(applied, args)
+			continue
+		}
+		if unchanged {
+			continue
+		}
+		reason := "invalid value"
+		switch m := msg.(type) {
+		case string:
+			reason = strings.TrimSpace(m)
+		case []string:
+			reason = strings.TrimSpace(strings.Join(m, " "))
+		}
+		invalid = append(invalid, fmt.Sprintf("`%s`: %s", strings.Join(args[1:], " "), reason))
+	}
+	return applied, invalid
+}
+
+func settingsChangesDescription(changes [][]string) string {
+	buf := bytes.NewBuffer([]byte{})
+	for _, args := range changes {
+		buf.WriteString(fmt.Sprintf("**%s** → `%s`\n", args[1], strings.Join(args[2:], " ")))
+	}
+	return buf.String()
+}
+
+func (bot *Bot) handleSettingsExport(m *discordgo.MessageCreate, sett *storage.GuildSettings, args []string) interface{} {
+	format := SettingsExportJSON
+	if len(args) > 2 {
+		format = strings.ToLower(args[2])
+	}
+	if format != SettingsExportJSON && format != SettingsExportYAML {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings_transfer.handleSettingsExport.wrongFormat",
+			Other: "Sorry, `{{.Format}}` is neither `json` nor `yaml`.",
+		},
+			map[string]interface{}{
+				"Format": args[2],
+			})
+	}
+	msg, err := settingsExportMessage(m.GuildID, sett, format)
+	if err != nil {
+		log.Println(err)
+		return err.Error()
+	}
+	return msg
+}
+
+// handleSettingsImport shows the changes an attached settings file would make, with buttons to apply or cancel them.
+// The changes are kept in the storage until then, and validated again when they're applied
+func (bot *Bot) handleSettingsImport(m *discordgo.MessageCreate, sett *storage.GuildSettings, prem bool) interface{} {
+	if len(m.Attachments) == 0 {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings_transfer.handleSettingsImport.noAttachment",
+			Other: "Please attach a settings file made with `{{.CommandPrefix}} settings export` to import it",
+		},
+			map[string]interface{}{
+				"CommandPrefix": sett.GetCommandPrefix(),
+			})
+	}
+
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(m.Attachments[0].URL)
+	if err != nil {
+		log.Println(err)
+		return err.Error()
+	}
+	defer resp.Body.Close()
+	export, err := parseSettingsExport(resp.Body)
+	if err != nil {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings_transfer.handleSettingsImport.invalidFile",
+			Other: "I couldn't import that file: {{.Error}}",
+		},
+			map[string]interface{}{
+				"Error": err.Error(),
+			})
+	}
+
+	// validate a copy; the real settings are only changed once the import is confirmed
+	preview := bot.StorageInterface.GetGuildSettings(m.GuildID)
+	changes, invalid := importSettingsChanges(preview, export.Settings, prem)
+	if len(invalid) > 0 {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings_transfer.handleSettingsImport.invalidSettings",
+			Other: "I can't import that file, because some of the settings are invalid:\n{{.Invalid}}",
+		},
+			map[string]interface{}{
+				"Invalid": strings.Join(invalid, "\n"),
+			})
+	}
+	if len(changes) == 0 {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings_transfer.handleSettingsImport.noChanges",
+			Other: "The settings in that file are the same as the current ones; there's nothing to import!",
+		})
+	}
+
+	err = bot.StorageInterface.SetPendingSettingsImport(m.GuildID, m.ID, changes, SettingsImportTimeout)
+	if err != nil {
+		log.Println(err)
+		return err.Error()
+	}
+
+	return &discordgo.MessageSend{
+		Embed: &discordgo.MessageEmbed{
+			Title: sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings_transfer.handleSettingsImport.title",
+				Other: "Import Settings",
+			}),
+			Description: sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings_transfer.handleSettingsImport.desc",
+				Other: "Importing the file will make the following changes:\n\n{{.Changes}}",
+			},
+				map[string]interface{}{
+					"Changes": settingsChangesDescription(changes),
+				}),
+			Color: 15844367, // GOLD
+		},
+		Components: settingsConfirmComponents(sett, settingsImportApplyID+":"+m.ID, settingsImportCancelID+":"+m.ID),
+	}
+}
+
+// settingsConfirmComponents are the buttons to apply or cancel a pending change to the settings
+func settingsConfirmComponents(sett *storage.GuildSettings, applyID, cancelID string) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label: sett.LocalizeMessage(&i18n.Message{
+						ID:    "settings_transfer.settingsConfirmComponents.apply",
+						Other: "Apply",
+					}),
+					Style:    discordgo.SuccessButton,
+					CustomID: applyID,
+				},
+				discordgo.Button{
+					Label: sett.LocalizeMessage(&i18n.Message{
+						ID:    "settings_transfer.settingsConfirmComponents.cancel",
+						Other: "Cancel",
+					}),
+					Style:    discordgo.SecondaryButton,
+					CustomID: cancelID,
+				},
+			},
+		},
+	}
+}
+
+// handleSettingsImportComponent applies (or cancels) a pending settings import, once an admin confirms it
+func (bot *Bot) handleSettingsImportComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.GuildID == "" || i.Member == nil || i.Member.User == nil {
+		return
+	}
+	g, err := s.State.Guild(i.GuildID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	sett := bot.StorageInterface.GetGuildSettings(i.GuildID)
+	if isAdmin, _ := getPermissions(g, sett, i.Member.User, i.Member); !isAdmin {
+		respondEphemeral(s, i.Interaction, noPermsResponse(sett))
+		return
+	}
+
+	customID := i.MessageComponentData().CustomID
+	importID := customID[strings.Index(customID, ":")+1:]
+
+	var content string
+	// taking the import means it's only ever applied (or cancelled) once
+	changes, err := bot.StorageInterface.TakePendingSettingsImport(i.GuildID, importID)
+	switch {
+	case err != nil || changes == nil:
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings_transfer.handleSettingsImportComponent.expired",
+			Other: "This import has expired; please import the file again",
+		})
+	case strings.HasPrefix(customID, settingsImportCancelID):
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings_transfer.handleSettingsImportComponent.cancelled",
+			Other: "Import cancelled; no settings were changed",
+		})
+	default:
+		prem := bot.getPremiumTier(i.GuildID) != premium.FreeTier
+		before := copyGuildSettings(sett)
+		var invalid []string
+		for _, args := range changes {
+			msg, isValid, unchanged := applySetting(sett, getSetting(strings.ToLower(args[1])), args, prem)
+			if !isValid && !unchanged {
+				invalid = append(invalid, fmt.Sprintf("`%s`: %v", strings.Join(args[1:], " "), msg))
+			}
+		}
+		if len(invalid) > 0 {
+			// the settings changed since the preview, so the changes may not make sense anymore
+			content = sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings_transfer.handleSettingsImportComponent.invalidSettings",
+				Other: "The settings changed since the file was imported, and some of its settings can't be applied anymore:\n{{.Invalid}}",
+			},
+				map[string]interface{}{
+					"Invalid": strings.Join(invalid, "\n"),
+				})
+			break
+		}
+		err = bot.StorageInterface.SetGuildSettings(i.GuildID, sett)
+		if err != nil {
+			log.Println(err)
+			content = err.Error()
+			break
+		}
+		bot.recordSettingsChanges(i.GuildID, before, sett, settingsChanges(i.Member.User.ID, before, sett))
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings_transfer.handleSettingsImportComponent.applied",
+			Other: "Imported the settings!",
+		})
+	}
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
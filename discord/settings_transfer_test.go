@@ -0,0 +1,99 @@
+package discord
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/automuteus/automuteus/discord/setting"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/game"
+)
+
+func TestImportSettingsChanges(t *testing.T) {
+	exported := storage.MakeGuildSettings("")
+	exported.CommandPrefix = "!"
+	exported.SetAdminUserIDs([]string{"140581066283941888"})
+	exported.SetPermissionRoleIDs([]string{"754465589958803548"})
+	exported.SetDelay(game.DISCUSS, game.TASKS, 3)
+	exported.VoiceRules.DeafRules[game.PhaseNames[game.TASKS]]["dead"] = true
+	exported.SetLeaderboardSize(5)
+	exported.SetVoiceOverride("140581066283941888", storage.OverrideSpectator)
+	exported.SetMoveDeadPlayers(true)
+	exported.SetGhostChannelID("754465589958803549")
+
+	msg, err := settingsExportMessage("1", exported, SettingsExportJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	export, err := parseSettingsExport(msg.Files[0].Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sett := storage.MakeGuildSettings("")
+	_, invalid := importSettingsChanges(sett, export.Settings, false)
+	if len(invalid) != 1 || !strings.Contains(invalid[0], "leaderboardSize") {
+		t.Errorf("Premium settings shouldn't be imported for free guilds, got %v", invalid)
+	}
+
+	sett = storage.MakeGuildSettings("")
+	changes, invalid := importSettingsChanges(sett, export.Settings, true)
+	if len(invalid) > 0 {
+		t.Errorf("Expected all the exported settings to be valid, got %v", invalid)
+	}
+	if len(changes) == 0 {
+		t.Error("Expected the exported settings to change the default ones")
+	}
+	if remaining := setting.ChangeArgs(sett, exported); len(remaining) > 0 {
+		t.Errorf("Imported settings should match the exported ones, but %v differ", remaining)
+	}
+}
+
+func TestParseSettingsExport(t *testing.T) {
+	for _, file := range []string{
+		"not json",
+		"version: [1",
+		`{"version": 1}`,
+		"version: 1\n",
+		`{"version": 99, "settings": {}}`,
+	} {
+		if _, err := parseSettingsExport(strings.NewReader(file)); err == nil {
+			t.Errorf("Expected an error parsing the settings file %s", file)
+		}
+	}
+
+	jBytes, _ := json.Marshal(SettingsExport{Version: SettingsExportVersion, Settings: storage.MakeGuildSettings("")})
+	if _, err := parseSettingsExport(bytes.NewReader(jBytes)); err != nil {
+		t.Error(err)
+	}
+	if _, err := parseSettingsExport(bytes.NewReader(make([]byte, MaxSettingsImportBytes+1))); err == nil {
+		t.Error("Expected an error parsing a settings file that's too big")
+	}
+}
+
+func TestSettingsExportYAML(t *testing.T) {
+	exported := storage.MakeGuildSettings("!")
+	exported.SetDelay(game.DISCUSS, game.TASKS, 3)
+	exported.SetVoiceOverride("140581066283941888", storage.OverrideSpectator)
+	exported.SetGhostChannelID("754465589958803549")
+
+	msg, err := settingsExportMessage("1", exported, SettingsExportYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(msg.Files[0].Name, ".yaml") {
+		t.Errorf("Expected a YAML file, got %s", msg.Files[0].Name)
+	}
+	export, err := parseSettingsExport(msg.Files[0].Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if export.GuildID != "1" {
+		t.Errorf("Expected the YAML file to keep the guild ID, got %s", export.GuildID)
+	}
+	if changes := setting.ChangeArgs(exported, export.Settings); len(changes) > 0 {
+		t.Errorf("Settings exported as YAML should be imported as-is, but %v differ", changes)
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"github.com/automuteus/utils/pkg/settings"
-	"github.com/automuteus/utils/pkg/storage"
+	"github.com/automuteus/automuteus/storage"
+	storageutils "github.com/automuteus/utils/pkg/storage"
 	"log"
 	"strconv"
 	"strings"
@@ -16,7 +16,7 @@ import (
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 )
 
-func (bot *Bot) UserStatsEmbed(userID, guildID string, sett *settings.GuildSettings, isPrem bool) *discordgo.MessageEmbed {
+func (bot *Bot) UserStatsEmbed(userID, guildID string, sett *storage.GuildSettings, isPrem bool) *discordgo.MessageEmbed {
 	gamesPlayed := bot.PostgresInterface.NumGamesPlayedByUserOnServer(userID, guildID)
 	wins := bot.PostgresInterface.NumWinsOnServer(userID, guildID)
 
@@ -524,7 +524,7 @@ func (bot *Bot) CheckOrFetchCachedUserData(userID, guildID string) (string, stri
 	return split[0], split[1], split[2]
 }
 
-func (bot *Bot) MentionWithCacheData(userID, guildID string, sett *settings.GuildSettings) string {
+func (bot *Bot) MentionWithCacheData(userID, guildID string, sett *storage.GuildSettings) string {
 	if !sett.LeaderboardMention {
 		userName, nickname, _ := bot.CheckOrFetchCachedUserData(userID, guildID)
 		if nickname != "" {
@@ -537,7 +537,7 @@ func (bot *Bot) MentionWithCacheData(userID, guildID string, sett *settings.Guil
 	return "<@" + userID + ">"
 }
 
-func (bot *Bot) GuildStatsEmbed(guildID string, sett *settings.GuildSettings, isPrem bool) *discordgo.MessageEmbed {
+func (bot *Bot) GuildStatsEmbed(guildID string, sett *storage.GuildSettings, isPrem bool) *discordgo.MessageEmbed {
 	gname := ""
 	avatarURL := ""
 	g, err := bot.PrimarySession.Guild(guildID)
@@ -894,13 +894,13 @@ func (bot *Bot) GuildStatsEmbed(guildID string, sett *settings.GuildSettings, is
 	return &embed
 }
 
-func (bot *Bot) GameStatsEmbed(guildID, matchID, connectCode string, sett *settings.GuildSettings, isPrem bool) *discordgo.MessageEmbed {
+func (bot *Bot) GameStatsEmbed(guildID, matchID, connectCode string, sett *storage.GuildSettings, isPrem bool) *discordgo.MessageEmbed {
 	gameData, err := bot.PostgresInterface.GetGame(guildID, connectCode, matchID)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	var events []*storage.PostgresGameEvent
+	var events []*storageutils.PostgresGameEvent
 	if gameData != nil {
 		events, err = bot.PostgresInterface.GetGameEvents(matchID)
 		if err != nil {
@@ -908,8 +908,8 @@ func (bot *Bot) GameStatsEmbed(guildID, matchID, connectCode string, sett *setti
 		}
 	}
 
-	stats := storage.StatsFromGameAndEvents(gameData, events)
-	return stats.ToDiscordEmbed(connectCode+":"+matchID, sett)
+	stats := storageutils.StatsFromGameAndEvents(gameData, events)
+	return stats.ToDiscordEmbed(connectCode+":"+matchID, &sett.GuildSettings)
 }
 
 func TrimEmbedFields(fields []*discordgo.MessageEmbedField) []*discordgo.MessageEmbedField {
@@ -2,12 +2,14 @@ package discord
 
 import (
 	"context"
+	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/logging"
+	"github.com/automuteus/automuteus/metrics"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/game"
 	"github.com/automuteus/utils/pkg/premium"
-	"github.com/automuteus/utils/pkg/settings"
 	"github.com/automuteus/utils/pkg/task"
-	"github.com/bsm/redislock"
 	"github.com/bwmarrin/discordgo"
-	"log"
 	"strconv"
 	"time"
 )
@@ -20,13 +22,28 @@ const (
 	DeadPriority  HandlePriority = 2
 )
 
-func (bot *Bot) applyToSingle(dgs *GameState, userID string, mute, deaf bool) {
-	log.Println("Forcibly applying mute/deaf to " + userID)
-	prem, days := bot.PostgresInterface.GetGuildPremiumStatus(dgs.GuildID)
-	premTier := premium.FreeTier
-	if !premium.IsExpired(prem, days) {
-		premTier = prem
+// getPremiumTier returns the active premium tier of the guild, or the free tier if it has expired (or if
+// there's no Postgres to ask, like when replaying a recorded game)
+func (bot *Bot) getPremiumTier(guildID string) premium.Tier {
+	if bot.PostgresInterface == nil {
+		return premium.FreeTier
 	}
+	prem, days := bot.PostgresInterface.GetGuildPremiumStatus(guildID)
+	if premium.IsExpired(prem, days) {
+		return premium.FreeTier
+	}
+	return prem
+}
+
+// gameLogger tags the bot's logger with the guild, connect code and match of a game, for the paths that don't get the
+// logger of the game's subscription passed down
+func (bot *Bot) gameLogger(dgs *GameState) *logging.Logger {
+	return bot.logger.With("guildID", dgs.GuildID, "connectCode", dgs.ConnectCode, "matchID", dgs.MatchID)
+}
+
+func (bot *Bot) applyToSingle(logger *logging.Logger, dgs *GameState, userID string, mute, deaf bool) {
+	logger.Debug("forcibly applying mute/deaf", "userID", userID, "mute", mute, "deaf", deaf)
+	premTier := bot.getPremiumTier(dgs.GuildID)
 	uid, _ := strconv.ParseUint(userID, 10, 64)
 	req := task.UserModifyRequest{
 		Premium: premTier,
@@ -39,18 +56,13 @@ func (bot *Bot) applyToSingle(dgs *GameState, userID string, mute, deaf bool) {
 		},
 	}
 	// nil lock because this is an override; we don't care about legitimately obtaining the lock
-	mdsc := bot.GalactusClient.ModifyUsers(dgs.GuildID, dgs.ConnectCode, req, nil)
-	if mdsc == nil {
-		log.Println("Nil response from modifyUsers, probably not good...")
-	} else {
-		go RecordDiscordRequestsByCounts(bot.RedisInterface.client, mdsc)
-	}
+	bot.issueMutesAndRecord(logger, dgs.GuildID, dgs.ConnectCode, req, nil, metrics.NoTransition)
 }
 
-func (bot *Bot) applyToAll(dgs *GameState, mute, deaf bool) {
+func (bot *Bot) applyToAll(logger *logging.Logger, dgs *GameState, mute, deaf bool) {
 	g, err := bot.PrimarySession.State.Guild(dgs.GuildID)
 	if err != nil {
-		log.Println(err)
+		logger.Error("failed to get the guild from the state cache", "err", err)
 		return
 	}
 
@@ -67,11 +79,11 @@ func (bot *Bot) applyToAll(dgs *GameState, mute, deaf bool) {
 			}
 		}
 
-		tracked := voiceState.ChannelID != "" && dgs.Tracking.ChannelID == voiceState.ChannelID
+		role, tracked := dgs.TrackedChannelRole(voiceState.ChannelID)
 
 		_, linked := dgs.AmongUsData.GetByName(userData.InGameName)
-		// only actually tracked if we're in a tracked channel AND linked to a player
-		tracked = tracked && linked
+		// only actually tracked if we're in a tracked channel AND linked to a player (or in a spectators channel)
+		tracked = tracked && (linked || role == RoleSpectators)
 
 		if tracked {
 			uid, _ := strconv.ParseUint(userData.User.UserID, 10, 64)
@@ -80,35 +92,51 @@ func (bot *Bot) applyToAll(dgs *GameState, mute, deaf bool) {
 				Mute:   mute,
 				Deaf:   deaf,
 			})
-			log.Println("Forcibly applying mute/deaf to " + userData.User.UserID)
+			logger.Debug("forcibly applying mute/deaf", "userID", userData.User.UserID, "mute", mute, "deaf", deaf)
 		}
 	}
 	if len(users) > 0 {
-		prem, days := bot.PostgresInterface.GetGuildPremiumStatus(dgs.GuildID)
-		premTier := premium.FreeTier
-		if !premium.IsExpired(prem, days) {
-			premTier = prem
-		}
+		premTier := bot.getPremiumTier(dgs.GuildID)
 		req := task.UserModifyRequest{
 			Premium: premTier,
 			Users:   users,
 		}
 		// nil lock because this is an override; we don't care about legitimately obtaining the lock
-		mdsc := bot.GalactusClient.ModifyUsers(dgs.GuildID, dgs.ConnectCode, req, nil)
-		if mdsc == nil {
-			log.Println("Nil response from modifyUsers, probably not good...")
-		} else {
-			go RecordDiscordRequestsByCounts(bot.RedisInterface.client, mdsc)
-		}
+		bot.issueMutesAndRecord(logger, dgs.GuildID, dgs.ConnectCode, req, nil, metrics.NoTransition)
 	}
 }
 
+// getVoiceState returns if the User should be muted and deafened, applying their voice override (if any) and the
+// role of their (tracked) voice channel before the guild-wide voice rules. The last return value is false if the bot
+// shouldn't touch the User at all
+func getVoiceState(sett *storage.GuildSettings, userID string, tracked bool, role ChannelRole, auData amongus.PlayerData, found bool, phase game.Phase) (bool, bool, bool) {
+	override, _ := sett.GetVoiceOverride(userID)
+	if override == storage.OverrideExempt {
+		return false, false, false
+	}
+
+	spectator := override == storage.OverrideSpectator || (tracked && role == RoleSpectators) || (!found && sett.GetMuteSpectator())
+	// only linked players (or spectators) are handled, to not accidentally undeafen music bots, for example
+	if !found && !spectator {
+		return false, false, false
+	}
+
+	// we just assume spectators (and anyone in the ghosts channel) are dead
+	isAlive := !spectator && !(tracked && role == RoleGhosts) && auData.IsAlive
+	mute, deaf := sett.GetVoiceState(isAlive, tracked, phase)
+	if override == storage.OverrideDeafenTasks && tracked && phase == game.TASKS {
+		deaf = true
+	}
+	return mute, deaf, true
+}
+
 // handleTrackedMembers moves/mutes players according to the current game state
-func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.GuildSettings, delay int, handlePriority HandlePriority, gsr GameStateRequest) {
+func (bot *Bot) handleTrackedMembers(logger *logging.Logger, sess *discordgo.Session, sett *storage.GuildSettings, delay int, handlePriority HandlePriority, gsr GameStateRequest, transition string) {
+	start := time.Now()
 
-	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
 	for lock == nil {
-		lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+		lock, dgs = bot.GameStateStore.GetDiscordGameStateAndLock(gsr)
 	}
 
 	g, err := sess.State.Guild(dgs.GuildID)
@@ -117,6 +145,8 @@ func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.Gui
 		lock.Release(ctx)
 		return
 	}
+	// the callers may only have the guild settings
+	sett = dgs.gameSettings(logger, sett)
 
 	users := []task.UserModify{}
 
@@ -132,32 +162,16 @@ func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.Gui
 			}
 		}
 
-		tracked := voiceState.ChannelID != "" && dgs.Tracking.ChannelID == voiceState.ChannelID
+		role, tracked := dgs.TrackedChannelRole(voiceState.ChannelID)
 
 		auData, found := dgs.AmongUsData.GetByName(userData.InGameName)
-		// only actually tracked if we're in a tracked channel AND linked to a player
-		var isAlive bool
-
-		// only actually tracked if we're in a tracked channel AND linked to a player
-		if !sett.GetMuteSpectator() {
-			tracked = tracked && found
-			isAlive = auData.IsAlive
-		} else {
-			if !found {
-				// we just assume the spectator is dead
-				isAlive = false
-			} else {
-				isAlive = auData.IsAlive
-			}
-		}
-		shouldMute, shouldDeaf := sett.GetVoiceState(isAlive, tracked, dgs.AmongUsData.GetPhase())
+		shouldMute, shouldDeaf, handled := getVoiceState(sett, voiceState.UserID, tracked, role, auData, found, dgs.AmongUsData.GetPhase())
+		isAlive := found && auData.IsAlive
 
 		incorrectMuteDeafenState := shouldMute != userData.ShouldBeMute || shouldDeaf != userData.ShouldBeDeaf
 
 		// only issue a change if the User isn't in the right state already
-		// nicksmatch can only be false if the in-game data is != nil, so the reference to .audata below is safe
-		// check the userdata is linked here to not accidentally undeafen music bots, for example
-		if incorrectMuteDeafenState && (found || sett.GetMuteSpectator()) {
+		if incorrectMuteDeafenState && handled {
 			uid, _ := strconv.ParseUint(userData.User.UserID, 10, 64)
 			userModify := task.UserModify{
 				UserID: uid,
@@ -177,21 +191,18 @@ func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.Gui
 	}
 
 	// we relinquish the lock while we wait
-	bot.RedisInterface.SetDiscordGameState(dgs, lock)
+	bot.GameStateStore.SetDiscordGameState(dgs, lock)
 
-	voiceLock := bot.RedisInterface.LockVoiceChanges(dgs.ConnectCode, time.Second*time.Duration(delay+1))
+	// long enough for the mutes to be retried after the delay; it's released as soon as they're done
+	voiceLock := bot.GameStateStore.LockVoiceChanges(dgs.ConnectCode, time.Second*time.Duration(delay)+GalactusRetryDeadline)
 
 	if delay > 0 {
-		log.Printf("Sleeping for %d seconds before applying changes to users\n", delay)
+		logger.Debug("sleeping before applying the voice changes", "delaySeconds", delay, "transition", transition)
 		time.Sleep(time.Second * time.Duration(delay))
 	}
 
 	if dgs.Running && len(users) > 0 {
-		prem, days := bot.PostgresInterface.GetGuildPremiumStatus(dgs.GuildID)
-		premTier := premium.FreeTier
-		if !premium.IsExpired(prem, days) {
-			premTier = prem
-		}
+		premTier := bot.getPremiumTier(dgs.GuildID)
 
 		if priorityRequests > 0 {
 			req := task.UserModifyRequest{
@@ -199,34 +210,132 @@ func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.Gui
 				Users:   users[:priorityRequests],
 			}
 			// no lock; we're not done yet
-			bot.issueMutesAndRecord(dgs.GuildID, dgs.ConnectCode, req, nil)
-			log.Println("Finished issuing high priority mutes")
+			bot.issueMutesAndRecord(logger, dgs.GuildID, dgs.ConnectCode, req, nil, transition)
+			logger.Debug("finished issuing the high priority mutes", "users", priorityRequests, "transition", transition)
 			rem := users[priorityRequests:]
 			if len(rem) > 0 {
 				req = task.UserModifyRequest{
 					Premium: premTier,
 					Users:   rem,
 				}
-				bot.issueMutesAndRecord(dgs.GuildID, dgs.ConnectCode, req, voiceLock)
+				bot.issueMutesAndRecord(logger, dgs.GuildID, dgs.ConnectCode, req, voiceLock, transition)
 			} else if voiceLock != nil {
 				voiceLock.Release(context.Background())
 			}
 		} else {
 			// no priority; issue all at once
-			log.Println("Issuing mutes/deafens with no particular priority")
+			logger.Debug("issuing mutes/deafens with no particular priority", "users", len(users), "transition", transition)
 			req := task.UserModifyRequest{
 				Premium: premTier,
 				Users:   users,
 			}
-			bot.issueMutesAndRecord(dgs.GuildID, dgs.ConnectCode, req, voiceLock)
+			bot.issueMutesAndRecord(logger, dgs.GuildID, dgs.ConnectCode, req, voiceLock, transition)
+		}
+		if transition != metrics.NoTransition {
+			// how much longer than the configured delay it took for the mutes to be applied
+			metrics.MuteDelaySkew.WithLabelValues(transition).Observe((time.Since(start) - time.Second*time.Duration(delay)).Seconds())
+		}
+	} else if voiceLock != nil {
+		// nothing to change; don't make the next changes wait for the lock to expire
+		voiceLock.Release(context.Background())
+	}
+}
+
+// moveDeadPlayers moves the dead players in the players channels of the game to the guild's ghost channel (which is
+// tracked for the game as a ghosts channel, replacing any previous ghost channel). Only applies during tasks, and if
+// it's enabled. The game state is expected to be locked; the members are moved in the background so it isn't held
+// during the Discord requests
+func (bot *Bot) moveDeadPlayers(logger *logging.Logger, dgs *GameState, sett *storage.GuildSettings) {
+	ghostChannelID := sett.GetGhostChannelID()
+	if dgs.GhostTracking.ChannelID != ghostChannelID {
+		// the guild's ghost channel changed (or was cleared) since the last dead players were moved
+		dgs.GhostTracking = TrackingChannel{}
+	}
+	if !sett.GetMoveDeadPlayers() || ghostChannelID == "" || !dgs.Running || dgs.AmongUsData.GetPhase() != game.TASKS {
+		return
+	}
+
+	g, err := bot.PrimarySession.State.Guild(dgs.GuildID)
+	if err != nil || g == nil {
+		logger.Error("failed to get the guild from the state cache", "err", err)
+		return
+	}
+
+	if dgs.GhostTracking.ChannelID == "" {
+		dgs.GhostTracking = TrackingChannel{ChannelID: ghostChannelID, Role: RoleGhosts}
+		if c, err := bot.PrimarySession.State.Channel(ghostChannelID); err == nil {
+			dgs.GhostTracking.ChannelName = c.Name
+		}
+	}
+
+	userIDs := []string{}
+	for _, voiceState := range g.VoiceStates {
+		role, tracked := dgs.TrackedChannelRole(voiceState.ChannelID)
+		if !tracked || role != RolePlayers {
+			continue
+		}
+		if override, _ := sett.GetVoiceOverride(voiceState.UserID); override == storage.OverrideExempt {
+			continue
+		}
+		userData, err := dgs.GetUser(voiceState.UserID)
+		if err != nil {
+			continue
+		}
+		auData, found := dgs.AmongUsData.GetByName(userData.InGameName)
+		if found && !auData.IsAlive {
+			userIDs = append(userIDs, voiceState.UserID)
+		}
+	}
+	if len(userIDs) > 0 {
+		go bot.moveMembers(logger, dgs.GuildID, userIDs, ghostChannelID)
+	}
+}
+
+// moveGhostsBack moves everyone in the game out of the guild's ghost channel, and back to the main voice channel of
+// the game. The game state shouldn't be locked, as the members are moved right away
+func (bot *Bot) moveGhostsBack(logger *logging.Logger, dgs *GameState, sett *storage.GuildSettings) {
+	ghostChannelID := sett.GetGhostChannelID()
+	if !sett.GetMoveDeadPlayers() || ghostChannelID == "" || dgs.Tracking.ChannelID == "" || dgs.Tracking.ChannelID == ghostChannelID {
+		return
+	}
+
+	g, err := bot.PrimarySession.State.Guild(dgs.GuildID)
+	if err != nil || g == nil {
+		logger.Error("failed to get the guild from the state cache", "err", err)
+		return
+	}
+
+	userIDs := []string{}
+	for _, voiceState := range g.VoiceStates {
+		if voiceState.ChannelID != ghostChannelID {
+			continue
+		}
+		// including the members unlinked since they died; they're still part of the game
+		if _, err := dgs.GetUser(voiceState.UserID); err != nil {
+			continue
+		}
+		userIDs = append(userIDs, voiceState.UserID)
+	}
+	bot.moveMembers(logger, dgs.GuildID, userIDs, dgs.Tracking.ChannelID)
+}
+
+// moveMembers moves Users to another voice channel directly through Discord; Galactus only mutes and deafens
+func (bot *Bot) moveMembers(logger *logging.Logger, guildID string, userIDs []string, channelID string) {
+	for _, userID := range userIDs {
+		err := bot.PrimarySession.GuildMemberMove(guildID, userID, &channelID)
+		if err != nil {
+			logger.Error("failed to move member", "userID", userID, "channelID", channelID, "err", err)
+			continue
 		}
+		logger.Debug("moved member", "userID", userID, "channelID", channelID)
+		metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MemberMove, 1)
 	}
 }
 
-func (bot *Bot) issueMutesAndRecord(guildID, connectCode string, req task.UserModifyRequest, lock *redislock.Lock) {
-	mdsc := bot.GalactusClient.ModifyUsers(guildID, connectCode, req, lock)
+func (bot *Bot) issueMutesAndRecord(logger *logging.Logger, guildID, connectCode string, req task.UserModifyRequest, lock Lock, transition string) {
+	mdsc := bot.VoiceModifier.ModifyUsers(guildID, connectCode, req, lock, transition)
 	if mdsc == nil {
-		log.Println("Nil response from modifyUsers, probably not good...")
+		logger.Error("nil response from modifying the users", "users", len(req.Users), "transition", transition)
 	} else {
 		go RecordDiscordRequestsByCounts(bot.RedisInterface.client, mdsc)
 	}
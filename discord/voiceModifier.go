@@ -0,0 +1,193 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/automuteus/automuteus/logging"
+	"github.com/automuteus/utils/pkg/task"
+	"github.com/bwmarrin/discordgo"
+)
+
+// VoiceModifier mutes and deafens users; either through Galactus, or directly through the Discord API
+type VoiceModifier interface {
+	// ModifyUsers mutes/deafens the users, and releases the lock (if any) once it's done. The transition that caused
+	// the request labels its latency
+	ModifyUsers(guildID, connectCode string, request task.UserModifyRequest, lock Lock, transition string) *task.MuteDeafenSuccessCounts
+	// AddToken adds the token of a worker bot to spread the requests over
+	AddToken(token string) error
+	// Degraded reports whether players can't be muted/unmuted right now
+	Degraded() bool
+	// OnDegradedChange calls fn whenever muting starts failing, or recovers
+	OnDegradedChange(fn func(degraded bool))
+}
+
+// voiceSession is a bot session that mutes/deafens users, along with how busy it is
+type voiceSession struct {
+	sess     *discordgo.Session
+	official bool
+	inFlight int
+	// limitedUntil is when the session's mute/deafen requests stop being rate limited
+	limitedUntil time.Time
+}
+
+// DiscordVoiceModifier mutes/deafens users with the bot's own session, so self-hosted bots don't need Galactus.
+// Worker bots only make REST requests, without connecting to the gateway; each user is modified by whichever session
+// has the fewest requests in flight, skipping the ones that are rate limited
+type DiscordVoiceModifier struct {
+	lock     sync.Mutex
+	primary  *voiceSession
+	sessions []*voiceSession
+	logger   *logging.Logger
+}
+
+func NewDiscordVoiceModifier(sess *discordgo.Session, logger *logging.Logger) *DiscordVoiceModifier {
+	dvm := DiscordVoiceModifier{logger: logger}
+	dvm.primary = dvm.addSession(sess, true)
+	return &dvm
+}
+
+func (dvm *DiscordVoiceModifier) AddToken(token string) error {
+	dvm.lock.Lock()
+	for _, vs := range dvm.sessions {
+		if vs.sess.Token == "Bot "+token {
+			dvm.lock.Unlock()
+			return errors.New("this token has already been added")
+		}
+	}
+	dvm.lock.Unlock()
+
+	sess, err := discordgo.New("Bot " + token)
+	if err != nil {
+		return err
+	}
+	dvm.addSession(sess, false)
+	return nil
+}
+
+func (dvm *DiscordVoiceModifier) addSession(sess *discordgo.Session, official bool) *voiceSession {
+	vs := &voiceSession{sess: sess, official: official}
+	sess.AddHandler(func(_ *discordgo.Session, rl *discordgo.RateLimit) {
+		// only the rate limits of mutes/deafens; the primary session also sends messages
+		if !strings.Contains(rl.URL, "/members/") {
+			return
+		}
+		dvm.logger.Warn("rate limited while modifying users", "official", official, "retryAfter", rl.RetryAfter)
+		dvm.lock.Lock()
+		vs.limitedUntil = time.Now().Add(rl.RetryAfter)
+		dvm.lock.Unlock()
+	})
+
+	dvm.lock.Lock()
+	defer dvm.lock.Unlock()
+	dvm.sessions = append(dvm.sessions, vs)
+	return vs
+}
+
+// Degraded is always false; Discord itself being unreachable is reported by the health checks of the session
+func (dvm *DiscordVoiceModifier) Degraded() bool {
+	return false
+}
+
+func (dvm *DiscordVoiceModifier) OnDegradedChange(_ func(degraded bool)) {}
+
+func (dvm *DiscordVoiceModifier) ModifyUsers(guildID, connectCode string, request task.UserModifyRequest, lock Lock, _ string) *task.MuteDeafenSuccessCounts {
+	if lock != nil {
+		defer lock.Release(context.Background())
+	}
+	dvm.logger.Debug("modifying users", "guildID", guildID, "connectCode", connectCode, "request", request)
+
+	counts := task.MuteDeafenSuccessCounts{}
+	countsLock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, user := range request.Users {
+		wg.Add(1)
+		go func(user task.UserModify) {
+			defer wg.Done()
+			official, worker := dvm.modifyUser(guildID, user)
+			countsLock.Lock()
+			counts.Official += official
+			counts.Worker += worker
+			countsLock.Unlock()
+		}(user)
+	}
+	wg.Wait()
+	return &counts
+}
+
+// modifyUser mutes and deafens a single user, and returns how many requests were sent by the primary session and by
+// the workers. If a worker can't modify the user (like when it isn't in the guild), the primary session does
+func (dvm *DiscordVoiceModifier) modifyUser(guildID string, user task.UserModify) (official, worker int64) {
+	userID := strconv.FormatUint(user.UserID, 10)
+	vs := dvm.pickSession()
+	sent, err := vs.modify(guildID, userID, user.Mute, user.Deaf)
+	dvm.release(vs)
+	if vs.official {
+		official += sent
+	} else {
+		worker += sent
+	}
+	if err == nil || vs.official {
+		if err != nil {
+			dvm.logger.Error("failed to modify user", "guildID", guildID, "userID", userID, "err", err)
+		}
+		return official, worker
+	}
+
+	dvm.logger.Debug("worker failed to modify user, falling back to the primary session", "guildID", guildID, "userID", userID, "err", err)
+	vs = dvm.primary
+	sent, err = vs.modify(guildID, userID, user.Mute, user.Deaf)
+	official += sent
+	if err != nil {
+		dvm.logger.Error("failed to modify user", "guildID", guildID, "userID", userID, "err", err)
+	}
+	return official, worker
+}
+
+// pickSession chooses the session with the fewest requests in flight that isn't rate limited; if they all are, the one
+// that stops being rate limited first. Ties go to the workers, to spare the primary session's rate limits
+func (dvm *DiscordVoiceModifier) pickSession() *voiceSession {
+	dvm.lock.Lock()
+	defer dvm.lock.Unlock()
+
+	now := time.Now()
+	var best *voiceSession
+	for i := len(dvm.sessions) - 1; i >= 0; i-- {
+		vs := dvm.sessions[i]
+		switch {
+		case best == nil:
+			best = vs
+		case best.limitedUntil.After(now) || vs.limitedUntil.After(now):
+			if vs.limitedUntil.Before(best.limitedUntil) {
+				best = vs
+			}
+		case vs.inFlight < best.inFlight:
+			best = vs
+		}
+	}
+	best.inFlight++
+	return best
+}
+
+func (dvm *DiscordVoiceModifier) release(vs *voiceSession) {
+	dvm.lock.Lock()
+	defer dvm.lock.Unlock()
+	vs.inFlight--
+}
+
+// modify sends the mute and deafen requests for a user, and returns how many were sent successfully
+func (vs *voiceSession) modify(guildID, userID string, mute, deaf bool) (int64, error) {
+	err := vs.sess.GuildMemberMute(guildID, userID, mute)
+	if err != nil {
+		return 0, err
+	}
+	err = vs.sess.GuildMemberDeafen(guildID, userID, deaf)
+	if err != nil {
+		return 1, err
+	}
+	return 2, nil
+}
@@ -0,0 +1,42 @@
+package discord
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/automuteus/automuteus/logging"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestPickSession(t *testing.T) {
+	sess, _ := discordgo.New("Bot official")
+	dvm := NewDiscordVoiceModifier(sess, logging.New(ioutil.Discard, logging.LevelError, logging.FormatLogfmt))
+	if err := dvm.AddToken("worker"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dvm.AddToken("worker"); err == nil {
+		t.Error("the same token shouldn't be added twice")
+	}
+
+	worker := dvm.pickSession()
+	if worker.official {
+		t.Error("the worker should be picked over the primary session when neither is busy")
+	}
+	if vs := dvm.pickSession(); !vs.official {
+		t.Error("the primary session should be picked while the worker is busy")
+	}
+	dvm.release(worker)
+	dvm.release(dvm.primary)
+
+	worker.limitedUntil = time.Now().Add(time.Minute)
+	if vs := dvm.pickSession(); !vs.official {
+		t.Error("a rate limited worker shouldn't be picked")
+	}
+	dvm.release(dvm.primary)
+
+	dvm.primary.limitedUntil = time.Now().Add(time.Hour)
+	if vs := dvm.pickSession(); vs != worker {
+		t.Error("the session that stops being rate limited first should be picked")
+	}
+}
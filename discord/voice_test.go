@@ -0,0 +1,120 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/game"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestGetVoiceState(t *testing.T) {
+	sett := storage.MakeGuildSettings("")
+	alive := amongus.PlayerData{Name: "Alice", IsAlive: true}
+	dead := amongus.PlayerData{Name: "Bob", IsAlive: false}
+
+	mute, deaf, handled := getVoiceState(sett, "1", true, RolePlayers, alive, true, game.TASKS)
+	if !handled || !mute || !deaf {
+		t.Error("Alive players should follow the voice rules without an override")
+	}
+	if _, _, handled = getVoiceState(sett, "1", true, RolePlayers, amongus.PlayerData{}, false, game.TASKS); handled {
+		t.Error("Unlinked users shouldn't be handled unless spectators are muted")
+	}
+
+	sett.SetVoiceOverride("1", storage.OverrideExempt)
+	if _, _, handled = getVoiceState(sett, "1", true, RolePlayers, alive, true, game.TASKS); handled {
+		t.Error("Exempt users should never be handled")
+	}
+
+	sett.SetVoiceOverride("1", storage.OverrideSpectator)
+	mute, deaf, handled = getVoiceState(sett, "1", true, RolePlayers, alive, true, game.DISCUSS)
+	if !handled || !mute || deaf {
+		t.Error("Spectator overrides should follow the rules for dead players")
+	}
+	if _, _, handled = getVoiceState(sett, "1", true, RolePlayers, amongus.PlayerData{}, false, game.DISCUSS); !handled {
+		t.Error("Spectator overrides should be handled even if they aren't linked")
+	}
+
+	sett.SetVoiceOverride("2", storage.OverrideDeafenTasks)
+	mute, deaf, handled = getVoiceState(sett, "2", true, RolePlayers, dead, true, game.TASKS)
+	if !handled || mute || !deaf {
+		t.Error("Dead players marked deafentasks should be deafened (but not muted) during tasks")
+	}
+	mute, deaf, _ = getVoiceState(sett, "2", false, "", dead, true, game.TASKS)
+	if mute || deaf {
+		t.Error("Users outside the tracked channel should never be deafened")
+	}
+
+	mute, deaf, handled = getVoiceState(sett, "3", true, RoleGhosts, alive, true, game.TASKS)
+	if !handled || mute || deaf {
+		t.Error("Players in a ghosts channel should follow the rules for dead players")
+	}
+	mute, deaf, handled = getVoiceState(sett, "3", true, RoleSpectators, amongus.PlayerData{}, false, game.DISCUSS)
+	if !handled || !mute || deaf {
+		t.Error("Unlinked users in a spectators channel should follow the rules for spectators")
+	}
+}
+
+func TestTrackChannels(t *testing.T) {
+	sett := storage.MakeGuildSettings("")
+	channels := []*discordgo.Channel{
+		{ID: "754465589958803100", Name: "Among Us", Type: discordgo.ChannelTypeGuildVoice},
+		{ID: "754465589958803200", Name: "dead", Type: discordgo.ChannelTypeGuildVoice},
+		{ID: "754465589958803300", Name: "overflow", Type: discordgo.ChannelTypeGuildVoice},
+		{ID: "754465589958803400", Name: "text", Type: discordgo.ChannelTypeGuildText},
+	}
+	dgs := NewDiscordGameState("1")
+	dgs.Tracking = TrackingChannel{ChannelID: "754465589958803100", ChannelName: "Among Us"}
+
+	dgs.trackChannels(nil, []string{"dead", "ghosts", "<#754465589958803300>", "spectator"}, channels, sett)
+	if dgs.Tracking.ChannelID != "754465589958803100" {
+		t.Error("The main channel should be kept if no players channel is provided")
+	}
+	if role, ok := dgs.TrackedChannelRole("754465589958803200"); !ok || role != RoleGhosts {
+		t.Error("Expected the \"dead\" channel to be tracked as ghosts")
+	}
+	if role, ok := dgs.TrackedChannelRole("754465589958803300"); !ok || role != RoleSpectators {
+		t.Error("Expected the \"overflow\" channel to be tracked as spectators")
+	}
+	if role, ok := dgs.TrackedChannelRole("754465589958803100"); !ok || role != RolePlayers {
+		t.Error("Expected the main channel to be tracked as players")
+	}
+
+	dgs.trackChannels(nil, []string{"text"}, channels, sett)
+	if len(dgs.TrackedChannels()) != 3 {
+		t.Error("Text channels shouldn't be tracked, or change the tracked channels")
+	}
+
+	dgs.trackChannels(nil, []string{"ghosts"}, channels, sett)
+	if len(dgs.TrackedChannels()) != 3 {
+		t.Error("A role without a channel shouldn't change the tracked channels")
+	}
+
+	dgs.trackChannels(nil, []string{"754465589958803200"}, channels, sett)
+	if dgs.Tracking.ChannelID != "754465589958803200" || len(dgs.TrackedChannels()) != 1 {
+		t.Error("Tracking a single players channel should replace all the tracked channels")
+	}
+}
+
+func TestTrackedChannelsGhostChannel(t *testing.T) {
+	dgs := NewDiscordGameState("1")
+	dgs.Tracking = TrackingChannel{ChannelID: "754465589958803100", ChannelName: "Among Us"}
+	dgs.ExtraTracking = []TrackingChannel{{ChannelID: "754465589958803300", ChannelName: "overflow", Role: RoleSpectators}}
+
+	dgs.GhostTracking = TrackingChannel{ChannelID: "754465589958803200", ChannelName: "dead", Role: RoleGhosts}
+	if role, ok := dgs.TrackedChannelRole("754465589958803200"); !ok || role != RoleGhosts || len(dgs.TrackedChannels()) != 3 {
+		t.Error("Expected the ghost channel to be tracked as ghosts")
+	}
+
+	// the guild's ghost channel changed mid-game
+	dgs.GhostTracking = TrackingChannel{ChannelID: "754465589958803400", ChannelName: "graveyard", Role: RoleGhosts}
+	if _, ok := dgs.TrackedChannelRole("754465589958803200"); ok {
+		t.Error("The previous ghost channel shouldn't stay tracked")
+	}
+
+	dgs.GhostTracking = TrackingChannel{ChannelID: "754465589958803300", ChannelName: "overflow", Role: RoleGhosts}
+	if role, _ := dgs.TrackedChannelRole("754465589958803300"); role != RoleSpectators || len(dgs.TrackedChannels()) != 2 {
+		t.Error("A ghost channel tracked for the game already should keep the role it's tracked with")
+	}
+}
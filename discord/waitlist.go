@@ -0,0 +1,223 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/logging"
+	"github.com/automuteus/automuteus/metrics"
+	"github.com/automuteus/automuteus/storage"
+	"github.com/automuteus/utils/pkg/discord"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// MaxLobbySize is how many players fit in an Among Us lobby
+const MaxLobbySize = 15
+
+const waitlistButtonID = "gamestate-waitlist"
+
+// Waitlist is the queue of users waiting to play in a game, and how many matches everyone played since the game was
+// started. When a match ends, the players who played the most sit out to make room for the ones waiting
+type Waitlist struct {
+	// Queue are the IDs of the users waiting to play, first come first served
+	Queue []string `json:"queue"`
+	// GamesPlayed is how many matches each user played, by ID
+	GamesPlayed map[string]int `json:"gamesPlayed"`
+}
+
+func NewWaitlist() Waitlist {
+	return Waitlist{
+		Queue:       []string{},
+		GamesPlayed: map[string]int{},
+	}
+}
+
+func (wl *Waitlist) Contains(userID string) bool {
+	for _, id := range wl.Queue {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (wl *Waitlist) remove(userID string) {
+	for i, id := range wl.Queue {
+		if id == userID {
+			wl.Queue = append(wl.Queue[:i], wl.Queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// Toggle adds the user to the end of the queue, or removes them if they're in it already. Returns whether they're in
+// the queue now
+func (wl *Waitlist) Toggle(userID string) bool {
+	if wl.Contains(userID) {
+		wl.remove(userID)
+		return false
+	}
+	wl.Queue = append(wl.Queue, userID)
+	return true
+}
+
+// Rotate counts the match the players just played, and returns who rotates in from the queue and who sits out to make
+// room for them, so that no more than lobbySize play. The players who played the most sit out first, and go to the end
+// of the queue
+func (wl *Waitlist) Rotate(players []string, lobbySize int) (in, out []string) {
+	if wl.GamesPlayed == nil {
+		wl.GamesPlayed = map[string]int{}
+	}
+	for _, userID := range players {
+		wl.GamesPlayed[userID]++
+		// they got in some other way
+		wl.remove(userID)
+	}
+	if len(wl.Queue) == 0 {
+		return nil, nil
+	}
+
+	free := lobbySize - len(players)
+	if free < 0 {
+		free = 0
+	}
+	benched := len(wl.Queue) - free
+	if benched < 0 {
+		benched = 0
+	}
+	if benched > len(players) {
+		benched = len(players)
+	}
+
+	numIn := free + benched
+	if numIn > len(wl.Queue) {
+		numIn = len(wl.Queue)
+	}
+	in = append([]string{}, wl.Queue[:numIn]...)
+	wl.Queue = wl.Queue[numIn:]
+
+	sorted := append([]string{}, players...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if wl.GamesPlayed[sorted[i]] != wl.GamesPlayed[sorted[j]] {
+			return wl.GamesPlayed[sorted[i]] > wl.GamesPlayed[sorted[j]]
+		}
+		return sorted[i] < sorted[j]
+	})
+	out = sorted[:benched]
+	wl.Queue = append(wl.Queue, out...)
+	return in, out
+}
+
+// linkedUserIDs are the users linked to a player of the game
+func (dgs *GameState) linkedUserIDs() []string {
+	var userIDs []string
+	for userID, userData := range dgs.UserData {
+		if userData.InGameName != "" && userData.InGameName != amongus.UnlinkedPlayerName {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	sort.Strings(userIDs)
+	return userIDs
+}
+
+// rotateWaitlist rotates the waitlist once a match is over: the players sitting out are unlinked, and who rotates in and
+// out is posted in the channel of the game. If the guild has a bench channel, they're moved as well
+func (bot *Bot) rotateWaitlist(logger *logging.Logger, sett *storage.GuildSettings, dgsRequest GameStateRequest) *GameState {
+	lock, dgs := bot.GameStateStore.GetDiscordGameStateAndLock(dgsRequest)
+	for lock == nil {
+		lock, dgs = bot.GameStateStore.GetDiscordGameStateAndLock(dgsRequest)
+	}
+	in, out := dgs.Waitlist.Rotate(dgs.linkedUserIDs(), MaxLobbySize)
+	for _, userID := range out {
+		dgs.ClearPlayerData(userID)
+	}
+	bot.GameStateStore.SetDiscordGameState(dgs, lock)
+	if len(in) == 0 && len(out) == 0 {
+		return dgs
+	}
+	logger.Info("rotating the waitlist", "in", len(in), "out", len(out))
+
+	_, err := bot.PrimarySession.ChannelMessageSend(dgs.GameStateMsg.MessageChannelID, rotationMessage(sett, in, out))
+	if err == nil {
+		metrics.RecordDiscordRequests(bot.RedisInterface.client, metrics.MessageCreateDelete, 1)
+	}
+
+	benchChannelID := sett.GetBenchChannelID()
+	if benchChannelID == "" || dgs.Tracking.ChannelID == "" {
+		return dgs
+	}
+	g, err := bot.PrimarySession.State.Guild(dgs.GuildID)
+	if err != nil || g == nil {
+		return dgs
+	}
+	benched, next := rotationMoves(dgs, g.VoiceStates, in, out, benchChannelID)
+	bot.moveMembers(logger, dgs.GuildID, benched, benchChannelID)
+	bot.moveMembers(logger, dgs.GuildID, next, dgs.Tracking.ChannelID)
+	return dgs
+}
+
+// rotationMoves returns the members to move to the bench channel (the ones rotating out, from any channel of the game)
+// and to the main channel of the game (the ones rotating in). Members rotating in are only moved from the bench
+// channel; they may be AFK, or in another game, anywhere else. Only members connected to voice can be moved
+func rotationMoves(dgs *GameState, voiceStates []*discordgo.VoiceState, in, out []string, benchChannelID string) (benched, next []string) {
+	rotatingIn, rotatingOut := map[string]bool{}, map[string]bool{}
+	for _, userID := range in {
+		rotatingIn[userID] = true
+	}
+	for _, userID := range out {
+		rotatingOut[userID] = true
+	}
+	for _, voiceState := range voiceStates {
+		if _, tracked := dgs.TrackedChannelRole(voiceState.ChannelID); tracked && rotatingOut[voiceState.UserID] {
+			benched = append(benched, voiceState.UserID)
+		}
+		if voiceState.ChannelID == benchChannelID && rotatingIn[voiceState.UserID] {
+			next = append(next, voiceState.UserID)
+		}
+	}
+	return benched, next
+}
+
+func rotationMessage(sett *storage.GuildSettings, in, out []string) string {
+	mentions := func(userIDs []string) string {
+		if len(userIDs) == 0 {
+			return "-"
+		}
+		m := make([]string, len(userIDs))
+		for i, userID := range userIDs {
+			m[i] = discord.MentionByUserID(userID)
+		}
+		return strings.Join(m, " ")
+	}
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "waitlist.rotationMessage",
+		Other: "🔄 **Rotating players!**\nIn: {{.In}}\nOut: {{.Out}}",
+	},
+		map[string]interface{}{
+			"In":  mentions(in),
+			"Out": mentions(out),
+		})
+}
+
+// waitlistEmbedField lists the users waiting to play, in order
+func waitlistEmbedField(dgs *GameState, sett *storage.GuildSettings) *discordgo.MessageEmbedField {
+	buf := bytes.NewBuffer([]byte{})
+	for i, userID := range dgs.Waitlist.Queue {
+		buf.WriteString(fmt.Sprintf("%d. %s\n", i+1, discord.MentionByUserID(userID)))
+	}
+	return &discordgo.MessageEmbedField{
+		Name: sett.LocalizeMessage(&i18n.Message{
+			ID:    "waitlist.waitlistEmbedField.name",
+			Other: "⏳ Waitlist ({{.Count}})",
+		},
+			map[string]interface{}{
+				"Count": len(dgs.Waitlist.Queue),
+			}),
+		Value:  buf.String(),
+		Inline: false,
+	}
+}
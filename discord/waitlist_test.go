@@ -0,0 +1,74 @@
+package discord
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestWaitlistToggle(t *testing.T) {
+	wl := NewWaitlist()
+	if !wl.Toggle("1") || !wl.Toggle("2") {
+		t.Fatal("Users should join the waitlist")
+	}
+	if wl.Toggle("1") {
+		t.Error("Toggling again should leave the waitlist")
+	}
+	if !reflect.DeepEqual(wl.Queue, []string{"2"}) {
+		t.Error("Unexpected queue", wl.Queue)
+	}
+}
+
+func TestWaitlistRotate(t *testing.T) {
+	wl := NewWaitlist()
+	wl.Toggle("a")
+	wl.Toggle("b")
+
+	// there's room for one of them
+	in, out := wl.Rotate([]string{"1", "2"}, 3)
+	if !reflect.DeepEqual(in, []string{"a", "b"}) || !reflect.DeepEqual(out, []string{"1"}) {
+		t.Fatal("Unexpected rotation", in, out)
+	}
+	if !reflect.DeepEqual(wl.Queue, []string{"1"}) {
+		t.Error("Benched players should join the end of the queue", wl.Queue)
+	}
+
+	// the ones that played the most sit out first, ties by ID
+	wl.Toggle("c")
+	in, out = wl.Rotate([]string{"2", "a", "b"}, 3)
+	if !reflect.DeepEqual(in, []string{"1", "c"}) || !reflect.DeepEqual(out, []string{"2", "a"}) {
+		t.Fatal("Unexpected rotation", in, out)
+	}
+	if wl.GamesPlayed["2"] != 2 || wl.GamesPlayed["a"] != 1 || wl.GamesPlayed["1"] != 1 {
+		t.Error("Unexpected games played", wl.GamesPlayed)
+	}
+
+	// players that got in some other way leave the queue
+	in, out = wl.Rotate([]string{"1", "2", "a"}, 3)
+	if len(in) != 0 || len(out) != 0 || len(wl.Queue) != 0 {
+		t.Error("Nobody should rotate", in, out, wl.Queue)
+	}
+}
+
+func TestRotationMoves(t *testing.T) {
+	dgs := NewDiscordGameState("1")
+	dgs.Tracking = TrackingChannel{ChannelID: "754465589958803100"}
+	dgs.GhostTracking = TrackingChannel{ChannelID: "754465589958803200", Role: RoleGhosts}
+	voiceStates := []*discordgo.VoiceState{
+		{UserID: "1", ChannelID: "754465589958803100"},
+		{UserID: "2", ChannelID: "754465589958803200"},
+		{UserID: "a", ChannelID: "754465589958803300"}, // bench
+		{UserID: "b", ChannelID: "754465589958803400"}, // AFK
+		{UserID: "c", ChannelID: "754465589958803200"}, // ghost channel
+		{UserID: "d", ChannelID: "754465589958803500"}, // unrelated
+	}
+
+	benched, next := rotationMoves(dgs, voiceStates, []string{"a", "b", "c", "d"}, []string{"1", "2"}, "754465589958803300")
+	if !reflect.DeepEqual(benched, []string{"1", "2"}) {
+		t.Error("Players rotating out should be moved from any channel of the game", benched)
+	}
+	if !reflect.DeepEqual(next, []string{"a"}) {
+		t.Error("Players rotating in should only be moved from the bench channel", next)
+	}
+}
@@ -0,0 +1,220 @@
+// Package logging is a small structured, leveled logger. Every line is written as logfmt or JSON, with the time,
+// level and message, followed by the fields the logger was tagged with (like the guild ID and connect code of a game)
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	default:
+		return "error"
+	}
+}
+
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %s; use debug, info, warn or error", s)
+}
+
+type Format string
+
+const (
+	FormatLogfmt Format = "logfmt"
+	FormatJSON   Format = "json"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case "", FormatLogfmt:
+		return FormatLogfmt, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return FormatLogfmt, fmt.Errorf("unknown log format %s; use logfmt or json", s)
+}
+
+// output is shared by a Logger and all the Loggers derived from it with With, so lines are never interleaved
+type output struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+type Logger struct {
+	out    *output
+	level  Level
+	format Format
+	// fields are key/value pairs, added to every line
+	fields []interface{}
+}
+
+var defaultLogger = New(os.Stdout, LevelInfo, FormatLogfmt)
+
+func New(w io.Writer, level Level, format Format) *Logger {
+	return &Logger{
+		out:    &output{w: w},
+		level:  level,
+		format: format,
+	}
+}
+
+// With returns a Logger that adds the key/value pairs to every line. A nil Logger logs to stdout, at the info level
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	if l == nil {
+		l = defaultLogger
+	}
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals))
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyvals...)
+	return &Logger{
+		out:    l.out,
+		level:  l.level,
+		format: l.format,
+		fields: fields,
+	}
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	if l == nil {
+		l = defaultLogger
+	}
+	return level >= l.level
+}
+
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.log(LevelDebug, msg, keyvals)
+}
+
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.log(LevelInfo, msg, keyvals)
+}
+
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(LevelWarn, msg, keyvals)
+}
+
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.log(LevelError, msg, keyvals)
+}
+
+func (l *Logger) log(level Level, msg string, keyvals []interface{}) {
+	if l == nil {
+		l = defaultLogger
+	}
+	if level < l.level {
+		return
+	}
+	all := make([]interface{}, 0, 6+len(l.fields)+len(keyvals))
+	all = append(all, "time", time.Now().UTC().Format(time.RFC3339Nano), "level", level.String(), "msg", msg)
+	all = append(all, l.fields...)
+	all = append(all, keyvals...)
+	if len(all)%2 != 0 {
+		all = append(all, nil)
+	}
+
+	var line []byte
+	if l.format == FormatJSON {
+		line = formatJSON(all)
+	} else {
+		line = formatLogfmt(all)
+	}
+
+	l.out.lock.Lock()
+	defer l.out.lock.Unlock()
+	_, _ = l.out.w.Write(line)
+}
+
+// Writer returns an io.Writer that logs every write as a line at the level provided, so the standard library's log
+// package (and dependencies using it) end up in the same structured output
+func (l *Logger) Writer(level Level) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		l.log(level, strings.TrimRight(string(p), "\n"), nil)
+		return len(p), nil
+	})
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func value(v interface{}) interface{} {
+	switch t := v.(type) {
+	case error:
+		return t.Error()
+	case fmt.Stringer:
+		return t.String()
+	}
+	return v
+}
+
+func formatJSON(keyvals []interface{}) []byte {
+	buf := bytes.NewBufferString("{")
+	for i := 0; i < len(keyvals); i += 2 {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(fmt.Sprint(keyvals[i]))
+		buf.Write(key)
+		buf.WriteByte(':')
+		val, err := json.Marshal(value(keyvals[i+1]))
+		if err != nil {
+			val, _ = json.Marshal(fmt.Sprint(keyvals[i+1]))
+		}
+		buf.Write(val)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+func formatLogfmt(keyvals []interface{}) []byte {
+	buf := bytes.NewBuffer(nil)
+	for i := 0; i < len(keyvals); i += 2 {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(fmt.Sprint(keyvals[i]))
+		buf.WriteByte('=')
+		val := fmt.Sprint(value(keyvals[i+1]))
+		if val == "" || strings.ContainsAny(val, " =\"\n\t") {
+			val = strconv.Quote(val)
+		}
+		buf.WriteString(val)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
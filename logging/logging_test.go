@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	logger := New(buf, LevelInfo, FormatLogfmt).With("guildID", "1", "connectCode", "ABCDEFGH")
+
+	logger.Debug("not logged")
+	if buf.Len() != 0 {
+		t.Error("Lines below the level of the logger shouldn't be logged")
+	}
+
+	logger.Info("popped job", "type", 2, "err", errors.New("some error"))
+	line := buf.String()
+	for _, field := range []string{"level=info", `msg="popped job"`, "guildID=1", "connectCode=ABCDEFGH", "type=2", `err="some error"`} {
+		if !strings.Contains(line, field) {
+			t.Errorf("Expected %s in the line %s", field, line)
+		}
+	}
+
+	buf.Reset()
+	logger = New(buf, LevelDebug, FormatJSON).With("matchID", 5)
+	logger.Warn("lost the lease")
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["level"] != "warn" || fields["msg"] != "lost the lease" || fields["matchID"] != float64(5) {
+		t.Errorf("Unexpected JSON line %s", buf.String())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if level, err := ParseLevel("WARN"); err != nil || level != LevelWarn {
+		t.Error("Levels should be case insensitive")
+	}
+	if level, err := ParseLevel(""); err != nil || level != LevelInfo {
+		t.Error("The default level should be info")
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("Unknown levels should be an error")
+	}
+}
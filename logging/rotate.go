@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingFile appends to a log file, which is rotated once it grows past maxBytes: the file becomes file.1, file.1
+// becomes file.2, and so on, keeping up to maxBackups of them
+type RotatingFile struct {
+	lock       sync.Mutex
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	file *os.File
+	size int64
+}
+
+func OpenRotatingFile(path string, maxBytes int64, maxBackups int) (*RotatingFile, error) {
+	rf := &RotatingFile{
+		path:       path,
+		maxBytes:   maxBytes,
+		maxBackups: maxBackups,
+	}
+	return rf, rf.open()
+}
+
+func (rf *RotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rf.file = file
+	rf.size = info.Size()
+	return nil
+}
+
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.lock.Lock()
+	defer rf.lock.Unlock()
+
+	if rf.maxBytes > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxBytes {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+func (rf *RotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	if rf.maxBackups > 0 {
+		for i := rf.maxBackups - 1; i > 0; i-- {
+			// older backups may not exist (yet)
+			_ = os.Rename(backupPath(rf.path, i), backupPath(rf.path, i+1))
+		}
+		if err := os.Rename(rf.path, backupPath(rf.path, 1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(rf.path); err != nil {
+		return err
+	}
+	return rf.open()
+}
+
+func (rf *RotatingFile) Close() error {
+	rf.lock.Lock()
+	defer rf.lock.Unlock()
+	return rf.file.Close()
+}
+
+func backupPath(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
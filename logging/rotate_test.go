@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "logs.txt")
+
+	rf, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rf.Close()
+
+	for file, expected := range map[string]string{
+		path:        "dddddddd\n",
+		path + ".1": "cccccccc\n",
+		path + ".2": "bbbbbbbb\n",
+	} {
+		contents, err := ioutil.ReadFile(file)
+		if err != nil || string(contents) != expected {
+			t.Errorf("Expected %s to contain %q, got %q", file, expected, contents)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("Only maxBackups rotated files should be kept")
+	}
+
+	// reopening appends instead of recreating the file
+	rf, err = OpenRotatingFile(path, 100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rf.Write([]byte("eeeeeeee\n"))
+	rf.Close()
+	if contents, _ := ioutil.ReadFile(path); string(contents) != "dddddddd\neeeeeeee\n" {
+		t.Errorf("Expected the log file to be appended to, got %q", contents)
+	}
+}
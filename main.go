@@ -1,7 +1,7 @@
 package main
 
 import (
-	"errors"
+	"flag"
 	"fmt"
 	"github.com/automuteus/utils/pkg/locale"
 	storage2 "github.com/automuteus/utils/pkg/storage"
@@ -11,14 +11,16 @@ import (
 	"os"
 	"os/signal"
 	"path"
-	"strconv"
-	"strings"
 	"syscall"
 	"time"
 
+	"github.com/automuteus/automuteus/amongus"
+	"github.com/automuteus/automuteus/config"
 	"github.com/automuteus/automuteus/storage"
 
 	"github.com/automuteus/automuteus/discord"
+	"github.com/automuteus/automuteus/discord/setting"
+	"github.com/automuteus/automuteus/logging"
 	"github.com/joho/godotenv"
 )
 
@@ -28,8 +30,6 @@ var (
 	date    = "unknown"
 )
 
-const DefaultURL = "http://localhost:8123"
-
 func main() {
 	// seed the rand generator (used for making connection codes)
 	rand.Seed(time.Now().Unix())
@@ -42,10 +42,14 @@ func main() {
 }
 
 func discordMainWrapper() error {
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a TOML config file; env vars override its values")
+	printConfig := flag.Bool("print-config", false, "print the config (with secrets redacted) and exit")
+	flag.Parse()
+
 	err := godotenv.Load("final.txt")
 	if err != nil {
 		err = godotenv.Load("config.txt")
-		if err != nil && os.Getenv("DISCORD_BOT_TOKEN") == "" {
+		if err != nil && *configPath == "" && os.Getenv("DISCORD_BOT_TOKEN") == "" {
 			log.Println("Can't open config file and missing DISCORD_BOT_TOKEN; creating config.txt for you to use for your config")
 			f, err := os.Create("config.txt")
 			if err != nil {
@@ -57,127 +61,90 @@ func discordMainWrapper() error {
 		}
 	}
 
-	logPath := os.Getenv("LOG_PATH")
-	if logPath == "" {
-		logPath = "./"
+	cfg, err := config.Load(*configPath)
+	if *printConfig {
+		printErr := cfg.Print(os.Stdout)
+		if printErr != nil {
+			return printErr
+		}
+		return err
+	}
+	if err != nil {
+		return err
 	}
 
-	logEntry := os.Getenv("DISABLE_LOG_FILE")
-	if logEntry == "" {
-		file, err := os.Create(path.Join(logPath, "logs.txt"))
+	// validated already
+	logLevel, _ := logging.ParseLevel(cfg.LogLevel)
+	logFormat, _ := logging.ParseFormat(cfg.LogFormat)
+
+	var logOutput io.Writer = os.Stdout
+	if !cfg.DisableLogFile {
+		// logs are appended to (and rotated), instead of wiping the logs of the previous run
+		file, err := logging.OpenRotatingFile(path.Join(cfg.LogPath, "logs.txt"), int64(cfg.LogMaxSizeMB)*1024*1024, cfg.LogMaxBackups)
 		if err != nil {
 			return err
 		}
-		mw := io.MultiWriter(os.Stdout, file)
-		log.SetOutput(mw)
+		defer file.Close()
+		logOutput = io.MultiWriter(os.Stdout, file)
 	}
-
-	emojiGuildID := os.Getenv("EMOJI_GUILD_ID")
+	logger := logging.New(logOutput, logLevel, logFormat)
+	// anything still logged with the standard logger ends up in the same structured output
+	log.SetFlags(0)
+	log.SetOutput(logger.Writer(logging.LevelInfo))
 
 	log.Println(version + "-" + commit)
 
-	discordToken := os.Getenv("DISCORD_BOT_TOKEN")
-	if discordToken == "" {
-		return errors.New("no DISCORD_BOT_TOKEN provided")
-	}
-
-	var extraTokens []string
-	extraTokenStr := strings.ReplaceAll(os.Getenv("WORKER_BOT_TOKENS"), " ", "")
-	if extraTokenStr != "" {
-		extraTokens = strings.Split(extraTokenStr, ",")
-	}
-
-	discordToken2 := os.Getenv("DISCORD_BOT_TOKEN_2")
-	if discordToken2 != "" {
+	if cfg.DiscordBotToken2 != "" {
 		log.Println("[INFO] DISCORD_BOT_TOKEN_2 is deprecated. Please use WORKER_BOT_TOKENS in the future!")
-		extraTokens = append(extraTokens, discordToken2)
 	}
-	if len(extraTokens) > 0 {
-		log.Printf("You provided %d worker tokens so I'll be sending them to Galactus\n", len(extraTokens))
+	if extraTokens := cfg.AllWorkerTokens(); len(extraTokens) > 0 {
+		log.Printf("You provided %d worker tokens so I'll be muting/deafening with them too\n", len(extraTokens))
 	}
 
-	numShardsStr := os.Getenv("NUM_SHARDS")
-	numShards, err := strconv.Atoi(numShardsStr)
-	if err != nil {
-		numShards = 1
-	}
+	amongus.BaseMapURL = cfg.BaseMapURL
+	setting.LocalePath = cfg.LocalePath
+	setting.DefaultLanguage = cfg.BotLang
 
-	shardIDStr := os.Getenv("SHARD_ID")
-	shardID, err := strconv.Atoi(shardIDStr)
-	if shardID >= numShards {
-		return errors.New("you specified a shardID higher than or equal to the total number of shards")
-	}
+	var redisClient discord.RedisInterface
+	storageInterface := storage.StorageInterface{GlobalPrefix: cfg.GlobalPrefix}
+
+	err = redisClient.Init(storage.RedisParameters{
+		Addr:     cfg.RedisAddr,
+		Username: "",
+		Password: cfg.RedisPass,
+	})
 	if err != nil {
-		shardID = 0
+		log.Println(err)
 	}
-
-	url := os.Getenv("HOST")
-	if url == "" {
-		log.Printf("[Info] No valid HOST provided. Defaulting to %s\n", DefaultURL)
-		url = DefaultURL
+	err = storageInterface.Init(storage.RedisParameters{
+		Addr:     cfg.RedisAddr,
+		Username: "",
+		Password: cfg.RedisPass,
+	})
+	if err != nil {
+		log.Println(err)
 	}
 
-	var redisClient discord.RedisInterface
-	var storageInterface storage.StorageInterface
-
-	redisAddr := os.Getenv("REDIS_ADDR")
-	redisPassword := os.Getenv("REDIS_PASS")
-	if redisAddr != "" {
-		err := redisClient.Init(storage.RedisParameters{
-			Addr:     redisAddr,
-			Username: "",
-			Password: redisPassword,
-		})
-		if err != nil {
-			log.Println(err)
-		}
-		err = storageInterface.Init(storage.RedisParameters{
-			Addr:     redisAddr,
-			Username: "",
-			Password: redisPassword,
-		})
+	var galactusClient *discord.GalactusClient
+	if cfg.VoiceBackend == config.VoiceBackendGalactus {
+		galactusClient, err = discord.NewGalactusClient(cfg.GalactusAddr, logger)
 		if err != nil {
-			log.Println(err)
+			log.Println("Error connecting to Galactus!")
+			return err
 		}
 	} else {
-		return errors.New("no REDIS_ADDR specified; exiting")
+		log.Println("[Info] Muting/deafening directly through the Discord API instead of Galactus")
 	}
 
-	galactusAddr := os.Getenv("GALACTUS_ADDR")
-	if galactusAddr == "" {
-		return errors.New("no GALACTUS_ADDR specified; exiting")
-	}
-
-	galactusClient, err := discord.NewGalactusClient(galactusAddr)
-	if err != nil {
-		log.Println("Error connecting to Galactus!")
-		return err
-	}
-
-	locale.InitLang(os.Getenv("LOCALE_PATH"), os.Getenv("BOT_LANG"))
+	locale.InitLang(cfg.LocalePath, cfg.BotLang)
 
 	psql := storage2.PsqlInterface{}
-	pAddr := os.Getenv("POSTGRES_ADDR")
-	if pAddr == "" {
-		return errors.New("no POSTGRES_ADDR specified; exiting")
-	}
-
-	pUser := os.Getenv("POSTGRES_USER")
-	if pUser == "" {
-		return errors.New("no POSTGRES_USER specified; exiting")
-	}
-
-	pPass := os.Getenv("POSTGRES_PASS")
-	if pPass == "" {
-		return errors.New("no POSTGRES_PASS specified; exiting")
-	}
-
-	err = psql.Init(storage2.ConstructPsqlConnectURL(pAddr, pUser, pPass))
+	err = psql.Init(storage2.ConstructPsqlConnectURL(cfg.PostgresAddr, cfg.PostgresUser, cfg.PostgresPass))
 	if err != nil {
 		return err
 	}
 
-	if os.Getenv("AUTOMUTEUS_OFFICIAL") == "" {
+	if !cfg.Official {
 		go func() {
 			err := psql.LoadAndExecFromFile("./storage/postgres.sql")
 			if err != nil {
@@ -191,12 +158,11 @@ func discordMainWrapper() error {
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt, os.Kill)
 
-	bot := discord.MakeAndStartBot(version, commit, discordToken, url, emojiGuildID, extraTokens, numShards, shardID, &redisClient, &storageInterface, &psql, galactusClient, logPath)
+	bot := discord.MakeAndStartBot(version, commit, cfg, &redisClient, &redisClient, &storageInterface, &psql, galactusClient, logger)
 
 	<-sc
-	//bot.GracefulClose()
-	log.Printf("Received Sigterm or Kill signal. Bot will terminate in 1 second")
-	time.Sleep(time.Second)
+	log.Printf("Received Sigterm or Kill signal. Handing off the running games before terminating")
+	bot.GracefulClose()
 
 	bot.Close()
 	return nil
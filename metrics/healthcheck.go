@@ -1,46 +1,127 @@
 package metrics
 
 import (
-	"github.com/gorilla/mux"
+	"context"
+	"encoding/json"
 	"log"
 	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// HealthCheckTimeout is how long each dependency has to respond to a health check
+const HealthCheckTimeout = 3 * time.Second
+
+const (
+	StatusOK       = "ok"
+	StatusFailing  = "failing"
+	StatusStarting = "starting"
 )
 
 var GlobalReady = false
 
-func StartHealthCheckServer(port string) {
-	r := mux.NewRouter()
+// HealthCheck checks one dependency of the bot
+type HealthCheck struct {
+	Name string
+	// Live checks also fail the liveness probe, so the pod is restarted. The others only make it unready; restarting
+	// the bot doesn't fix Redis or Postgres being down
+	Live  bool
+	Check func(ctx context.Context) error
+}
 
-	r.HandleFunc("/live", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("hell yeah"))
-	})
+type CheckStatus struct {
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+}
 
-	r.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
-		if GlobalReady {
-			resp, err := http.Get("https://discordapp.com/api/v8/gateway")
-			if err != nil {
-				log.Println(err)
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
-				return
+type HealthStatus struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckStatus `json:"checks"`
+}
+
+// RunHealthChecks runs the checks concurrently, and reports the status of each of them
+func RunHealthChecks(ctx context.Context, checks []HealthCheck) HealthStatus {
+	health := HealthStatus{
+		Status: StatusOK,
+		Checks: make(map[string]CheckStatus, len(checks)),
+	}
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, HealthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Check(checkCtx)
+			status := CheckStatus{
+				Status:    StatusOK,
+				LatencyMs: time.Since(start).Milliseconds(),
 			}
-			defer resp.Body.Close()
-			w.WriteHeader(resp.StatusCode)
-			if resp.StatusCode == http.StatusOK {
-				w.Write([]byte("ready"))
-			} else {
-				w.Write([]byte("unready"))
+			if err != nil {
+				status.Status = StatusFailing
+				status.Error = err.Error()
 			}
 
-			if resp.StatusCode == http.StatusTooManyRequests {
-				w.WriteHeader(http.StatusTooManyRequests)
-				log.Println("I'M BEING RATE-LIMITED BY DISCORD")
-				return
+			lock.Lock()
+			defer lock.Unlock()
+			health.Checks[check.Name] = status
+			if err != nil {
+				health.Status = StatusFailing
 			}
-		} else {
-			w.WriteHeader(http.StatusTooEarly)
+		}(check)
+	}
+	wg.Wait()
+	return health
+}
+
+func writeHealth(w http.ResponseWriter, health HealthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	switch health.Status {
+	case StatusOK:
+		w.WriteHeader(http.StatusOK)
+	case StatusStarting:
+		w.WriteHeader(http.StatusTooEarly)
+	default:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	err := json.NewEncoder(w).Encode(health)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// StartHealthCheckServer serves /live, which only runs the Live checks, and /ready, which runs all of them once the bot
+// is done starting. Both respond with the status of every check they ran as JSON
+func StartHealthCheckServer(port string, checks []HealthCheck) {
+	var liveChecks []HealthCheck
+	for _, check := range checks {
+		if check.Live {
+			liveChecks = append(liveChecks, check)
+		}
+	}
+
+	r := mux.NewRouter()
+
+	r.HandleFunc("/live", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, RunHealthChecks(r.Context(), liveChecks))
+	})
+
+	r.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		if !GlobalReady {
+			writeHealth(w, HealthStatus{Status: StatusStarting, Checks: map[string]CheckStatus{}})
+			return
+		}
+		health := RunHealthChecks(r.Context(), checks)
+		if health.Status != StatusOK {
+			log.Printf("Failing readiness check: %v\n", health.Checks)
 		}
+		writeHealth(w, health)
 	})
 
 	http.ListenAndServe(":"+port, r)
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRunHealthChecks(t *testing.T) {
+	checks := []HealthCheck{
+		{Name: "up", Check: func(ctx context.Context) error { return nil }},
+		{Name: "down", Check: func(ctx context.Context) error { return errors.New("connection refused") }},
+	}
+
+	health := RunHealthChecks(context.Background(), checks)
+	if health.Status != StatusFailing {
+		t.Error("a failing check should fail the health check")
+	}
+	if health.Checks["up"].Status != StatusOK {
+		t.Error("the passing check should be ok")
+	}
+	if down := health.Checks["down"]; down.Status != StatusFailing || down.Error != "connection refused" {
+		t.Error("the failing check should report its error", down)
+	}
+
+	health = RunHealthChecks(context.Background(), checks[:1])
+	if health.Status != StatusOK {
+		t.Error("passing checks should pass the health check")
+	}
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/automuteus/utils/pkg/game"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NoTransition labels the latencies that aren't caused by a phase transition (like players joining, or unmuting
+// everyone at the end of a game)
+const NoTransition = "none"
+
+var phaseLabels = map[game.Phase]string{
+	game.LOBBY:         "lobby",
+	game.TASKS:         "tasks",
+	game.DISCUSS:       "discussion",
+	game.MENU:          "menu",
+	game.GAMEOVER:      "gameover",
+	game.UNINITIALIZED: "uninitialized",
+}
+
+// Transition is the label of a phase transition, like lobby_tasks
+func Transition(from, to game.Phase) string {
+	return phaseLabels[from] + "_" + phaseLabels[to]
+}
+
+// transition durations include the configured delays, so they need longer buckets than the default ones
+var delayedBuckets = []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 15, 20, 30, 60}
+
+var (
+	JobPopLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "automuteus_job_pop_latency_seconds",
+		Help: "Time between the capture pushing a job (when its notification is received) and the bot popping it",
+	}, []string{"transition"})
+
+	TransitionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "automuteus_transition_duration_seconds",
+		Help:    "Time taken to process a phase transition, including the configured delay before muting",
+		Buckets: delayedBuckets,
+	}, []string{"transition"})
+
+	GalactusModifyDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "automuteus_galactus_modify_duration_seconds",
+		Help: "Round-trip time of the mute/deafen requests to Galactus",
+	}, []string{"transition"})
+
+	MuteDelaySkew = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "automuteus_mute_delay_skew_seconds",
+		Help: "Time taken to apply the mutes/deafens of a transition, beyond the configured delay",
+	}, []string{"transition"})
+)
+
+func latencyCollectors() []prometheus.Collector {
+	return []prometheus.Collector{JobPopLatency, TransitionDuration, GalactusModifyDuration, MuteDelaySkew}
+}
+
+// ObserveSince records the time elapsed since start in the histogram, for the transition
+func ObserveSince(histogram *prometheus.HistogramVec, transition string, start time.Time) {
+	histogram.WithLabelValues(transition).Observe(time.Since(start).Seconds())
+}
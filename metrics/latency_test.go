@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/automuteus/utils/pkg/game"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		from, to game.Phase
+		expected string
+	}{
+		{game.LOBBY, game.TASKS, "lobby_tasks"},
+		{game.TASKS, game.DISCUSS, "tasks_discussion"},
+		{game.DISCUSS, game.TASKS, "discussion_tasks"},
+		{game.TASKS, game.GAMEOVER, "tasks_gameover"},
+		{game.MENU, game.LOBBY, "menu_lobby"},
+		{game.UNINITIALIZED, game.MENU, "uninitialized_menu"},
+	}
+	for _, test := range tests {
+		if transition := Transition(test.from, test.to); transition != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, transition)
+		}
+	}
+}
+
+func TestObserveSince(t *testing.T) {
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "test_transition_duration_seconds",
+		Buckets: delayedBuckets,
+	}, []string{"transition"})
+
+	ObserveSince(histogram, Transition(game.LOBBY, game.TASKS), time.Now().Add(-3*time.Second))
+	ObserveSince(histogram, Transition(game.LOBBY, game.TASKS), time.Now())
+	ObserveSince(histogram, NoTransition, time.Now())
+
+	if count := testutil.CollectAndCount(histogram); count != 2 {
+		t.Errorf("expected a histogram per transition, got %d", count)
+	}
+	m := &dto.Metric{}
+	if err := histogram.WithLabelValues("lobby_tasks").(prometheus.Metric).Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if count := m.GetHistogram().GetSampleCount(); count != 2 {
+		t.Errorf("expected both lobby_tasks transitions to be observed, got %d", count)
+	}
+	if sum := m.GetHistogram().GetSampleSum(); sum < 3 {
+		t.Errorf("expected the time since the start of the transition to be observed, got %f seconds", sum)
+	}
+}
@@ -22,6 +22,7 @@ const (
 	MuteDeafenCapture
 	MuteDeafenWorker
 	InvalidRequest
+	MemberMove
 	OfficialRequest //must be the last metric
 )
 
@@ -33,6 +34,7 @@ var MetricTypeStrings = []string{
 	"mute_deafen_capture",
 	"mute_deafen_worker",
 	"invalid_request",
+	"member_move",
 	"official_request", //must be the last request
 }
 
@@ -89,6 +91,10 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 }
 
 func RecordDiscordRequests(client *redis.Client, requestType EventType, num int64) {
+	// nothing to record to (replays of recorded games run without Redis)
+	if client == nil {
+		return
+	}
 	for i := int64(0); i < num; i++ {
 		typeStr := MetricTypeStrings[requestType]
 		client.Incr(context.Background(), rediskey.RequestsByType(typeStr))
@@ -105,6 +111,8 @@ func NewCollector(client *redis.Client, nodeID string) *Collector {
 
 func PrometheusMetricsServer(client *redis.Client, nodeID, port string) error {
 	prometheus.MustRegister(NewCollector(client, nodeID))
+	prometheus.MustRegister(latencyCollectors()...)
+	prometheus.MustRegister(reconcileCollectors()...)
 
 	http.Handle("/metrics", promhttp.Handler())
 
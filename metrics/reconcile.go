@@ -0,0 +1,26 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	// ReconcilePeriodic labels the reconciliations that run periodically for every game
+	ReconcilePeriodic = "periodic"
+	// ReconcileRecovery labels the reconciliations that run once Galactus recovers
+	ReconcileRecovery = "recovery"
+)
+
+var (
+	VoiceReconciliations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "automuteus_voice_reconciliations_total",
+		Help: "Number of times the voice states of a game were compared to the ones the players should be in",
+	}, []string{"source"})
+
+	VoiceDrift = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "automuteus_voice_drift_total",
+		Help: "Number of players found in the wrong mute/deafen state, and fixed, when reconciling",
+	}, []string{"source"})
+)
+
+func reconcileCollectors() []prometheus.Collector {
+	return []prometheus.Collector{VoiceReconciliations, VoiceDrift}
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SettingsHistoryLength is roughly how many settings changes are kept for each guild; older changes are trimmed
+const SettingsHistoryLength = 1000
+
+// SettingsChange records a single setting that was changed, who changed it, and when
+type SettingsChange struct {
+	UserID   string `json:"userID"`
+	Time     int64  `json:"time"`
+	Setting  string `json:"setting"`
+	OldValue string `json:"oldValue"`
+	NewValue string `json:"newValue"`
+}
+
+func settingsHistoryKey(guildID string) string {
+	return "automuteus:settings:history:" + string(HashGuildID(guildID))
+}
+
+// AddSettingsChanges appends the changes to the guild's settings history (a Redis stream)
+func (storageInterface *StorageInterface) AddSettingsChanges(guildID string, changes []SettingsChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	key := settingsHistoryKey(guildID)
+	pipe := storageInterface.client.Pipeline()
+	for _, change := range changes {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream:       key,
+			MaxLenApprox: SettingsHistoryLength,
+			Values: map[string]interface{}{
+				"userID":   change.UserID,
+				"time":     change.Time,
+				"setting":  change.Setting,
+				"oldValue": change.OldValue,
+				"newValue": change.NewValue,
+			},
+		})
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetSettingsChanges returns up to count changes from the guild's settings history, newest first, skipping the
+// offset newest ones
+func (storageInterface *StorageInterface) GetSettingsChanges(guildID string, offset, count int64) ([]SettingsChange, error) {
+	msgs, err := storageInterface.client.XRevRangeN(ctx, settingsHistoryKey(guildID), "+", "-", offset+count).Result()
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(msgs)) <= offset {
+		return []SettingsChange{}, nil
+	}
+
+	changes := make([]SettingsChange, 0, int64(len(msgs))-offset)
+	for _, msg := range msgs[offset:] {
+		change := SettingsChange{}
+		change.UserID, _ = msg.Values["userID"].(string)
+		change.Setting, _ = msg.Values["setting"].(string)
+		change.OldValue, _ = msg.Values["oldValue"].(string)
+		change.NewValue, _ = msg.Values["newValue"].(string)
+		if t, ok := msg.Values["time"].(string); ok {
+			change.Time, _ = strconv.ParseInt(t, 10, 64)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+func (storageInterface *StorageInterface) DeleteSettingsHistory(guildID string) error {
+	return storageInterface.client.Del(ctx, settingsHistoryKey(guildID)).Err()
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"strconv"
+	"time"
+
+	storageutils "github.com/automuteus/utils/pkg/storage"
+)
+
+// LinkConfidence is how sure we are that a user is the player with an in-game name, by how they were linked
+type LinkConfidence int16
+
+const (
+	LinkByNameMatch LinkConfidence = iota + 1
+	LinkByReaction
+	LinkManual
+)
+
+func (confidence LinkConfidence) String() string {
+	switch confidence {
+	case LinkManual:
+		return "manual"
+	case LinkByReaction:
+		return "reaction"
+	case LinkByNameMatch:
+		return "name match"
+	default:
+		return "unknown"
+	}
+}
+
+// IdentityLink is a Discord user linked to an in-game name in a guild
+type IdentityLink struct {
+	GuildID    string
+	UserID     string
+	InGameName string
+	// FriendCode is only set if the capture provided it
+	FriendCode string
+	Confidence LinkConfidence
+	LinkedAt   int64
+	LastSeen   int64
+}
+
+// IdentityInterface stores the links between Discord users and in-game names in Postgres
+type IdentityInterface struct {
+	psql *storageutils.PsqlInterface
+}
+
+func NewIdentityInterface(psql *storageutils.PsqlInterface) *IdentityInterface {
+	return &IdentityInterface{psql: psql}
+}
+
+const identityLinkColumns = "guild_id, user_id, in_game_name, coalesce(friend_code, ''), confidence, linked_at, last_seen"
+
+func (identityInterface *IdentityInterface) queryIdentityLinks(sql string, args ...interface{}) ([]IdentityLink, error) {
+	rows, err := identityInterface.psql.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []IdentityLink
+	for rows.Next() {
+		var guildID, userID uint64
+		var confidence int16
+		var linkedAt, lastSeen int32
+		link := IdentityLink{}
+		err := rows.Scan(&guildID, &userID, &link.InGameName, &link.FriendCode, &confidence, &linkedAt, &lastSeen)
+		if err != nil {
+			return nil, err
+		}
+		link.GuildID = strconv.FormatUint(guildID, 10)
+		link.UserID = strconv.FormatUint(userID, 10)
+		link.Confidence = LinkConfidence(confidence)
+		link.LinkedAt = int64(linkedAt)
+		link.LastSeen = int64(lastSeen)
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// AddLink links the user to the in-game name, or marks the link as seen if it exists. A link only ever gains
+// confidence. Users who opted out of data collection aren't linked. Returns the links of the other users claiming the
+// same name, if any
+func (identityInterface *IdentityInterface) AddLink(link IdentityLink) ([]IdentityLink, error) {
+	ids, err := parseIDs(link.GuildID, link.UserID)
+	if err != nil {
+		return nil, err
+	}
+	var friendCode *string
+	if link.FriendCode != "" {
+		friendCode = &link.FriendCode
+	}
+	now := int32(time.Now().Unix())
+	tag, err := identityInterface.psql.Pool.Exec(ctx, "INSERT INTO identity_links "+
+		"SELECT $1::numeric, $2::numeric, $3::text, $4::text, $5::smallint, $6::integer, $6::integer "+
+		"WHERE NOT EXISTS (SELECT 1 FROM users WHERE user_id = $2 AND opt = false) "+
+		"ON CONFLICT (guild_id, user_id, in_game_name) DO UPDATE SET "+
+		"confidence = greatest(identity_links.confidence, excluded.confidence), "+
+		"friend_code = coalesce(excluded.friend_code, identity_links.friend_code), "+
+		"last_seen = excluded.last_seen;",
+		ids[0], ids[1], link.InGameName, friendCode, int16(link.Confidence), now)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		// opted out
+		return nil, nil
+	}
+	return identityInterface.queryIdentityLinks("SELECT "+identityLinkColumns+" FROM identity_links WHERE guild_id = $1 AND lower(in_game_name) = lower($2) AND user_id != $3 ORDER BY confidence DESC, last_seen DESC;",
+		ids[0], link.InGameName, ids[1])
+}
+
+// GetLinksByUserID returns the in-game names linked to the user, most confident first
+func (identityInterface *IdentityInterface) GetLinksByUserID(guildID, userID string) ([]IdentityLink, error) {
+	ids, err := parseIDs(guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return identityInterface.queryIdentityLinks("SELECT "+identityLinkColumns+" FROM identity_links WHERE guild_id = $1 AND user_id = $2 ORDER BY confidence DESC, last_seen DESC;",
+		ids[0], ids[1])
+}
+
+// GetLinksByName returns the users linked to the in-game name (ignoring case), most confident first
+func (identityInterface *IdentityInterface) GetLinksByName(guildID, inGameName string) ([]IdentityLink, error) {
+	ids, err := parseIDs(guildID)
+	if err != nil {
+		return nil, err
+	}
+	return identityInterface.queryIdentityLinks("SELECT "+identityLinkColumns+" FROM identity_links WHERE guild_id = $1 AND lower(in_game_name) = lower($2) ORDER BY confidence DESC, last_seen DESC;",
+		ids[0], inGameName)
+}
+
+func (identityInterface *IdentityInterface) DeleteLinksByUserID(guildID, userID string) error {
+	ids, err := parseIDs(guildID, userID)
+	if err != nil {
+		return err
+	}
+	_, err = identityInterface.psql.Pool.Exec(ctx, "DELETE FROM identity_links WHERE guild_id = $1 AND user_id = $2;", ids[0], ids[1])
+	return err
+}
+
+// DeleteAllLinksByUserID deletes the links of the user in every guild, like when they opt out of data collection
+func (identityInterface *IdentityInterface) DeleteAllLinksByUserID(userID string) error {
+	ids, err := parseIDs(userID)
+	if err != nil {
+		return err
+	}
+	_, err = identityInterface.psql.Pool.Exec(ctx, "DELETE FROM identity_links WHERE user_id = $1;", ids[0])
+	return err
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func settingsImportKey(guildID, importID string) string {
+	return "automuteus:settings:import:" + string(HashGuildID(guildID)) + ":" + importID
+}
+
+// SetPendingSettingsImport keeps the changes (as settings command args) an imported settings file would make, until an
+// admin confirms (or cancels) the import, or it expires
+func (storageInterface *StorageInterface) SetPendingSettingsImport(guildID, importID string, changes [][]string, expiration time.Duration) error {
+	jBytes, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	return storageInterface.client.Set(ctx, settingsImportKey(guildID, importID), jBytes, expiration).Err()
+}
+
+// TakePendingSettingsImport returns the changes of a pending import, and removes it, so the import can only be
+// confirmed (or cancelled) once. Returns nil if there's no such import, or it has expired
+func (storageInterface *StorageInterface) TakePendingSettingsImport(guildID, importID string) ([][]string, error) {
+	key := settingsImportKey(guildID, importID)
+	pipe := storageInterface.client.TxPipeline()
+	get := pipe.Get(ctx, key)
+	pipe.Del(ctx, key)
+	_, err := pipe.Exec(ctx)
+	switch {
+	case errors.Is(err, redis.Nil):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	var changes [][]string
+	err = json.Unmarshal([]byte(get.Val()), &changes)
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
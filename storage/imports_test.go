@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTakePendingSettingsImport(t *testing.T) {
+	storageInterface, client := newTestStorage()
+	if changes, err := storageInterface.TakePendingSettingsImport("1", "10"); changes != nil || err != nil {
+		t.Errorf("An import that was never started (or expired) shouldn't be found, got %v (%v)", changes, err)
+	}
+
+	changes := [][]string{{"settings", "commandPrefix", "!"}}
+	storageInterface.SetPendingSettingsImport("1", "10", changes, time.Minute)
+	if _, ok := client.strings["automuteus:settings:import:1:10"]; ok {
+		t.Error("Pending imports should be stored under the hashed guild ID")
+	}
+	if taken, _ := storageInterface.TakePendingSettingsImport("2", "10"); taken != nil {
+		t.Error("An import shouldn't be found from another guild")
+	}
+	if taken, _ := storageInterface.TakePendingSettingsImport("1", "10"); len(taken) != 1 || taken[0][2] != "!" {
+		t.Errorf("Expected the pending import, got %v", taken)
+	}
+	if taken, _ := storageInterface.TakePendingSettingsImport("1", "10"); taken != nil {
+		t.Error("An import should only be taken once")
+	}
+}
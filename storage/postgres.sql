@@ -47,6 +47,39 @@ create table if not exists users_games
     PRIMARY KEY (user_id, game_id)
 );
 
+-- games that start on their own at start_time, in the text channel where they were scheduled
+create table if not exists scheduled_games
+(
+    schedule_id      bigserial PRIMARY KEY,
+    guild_id         numeric  NOT NULL references guilds ON DELETE CASCADE, --if the guild is deleted, delete their scheduled games, too
+    text_channel_id  numeric  NOT NULL,
+    voice_channel_id numeric  NOT NULL,
+    host_id          numeric  NOT NULL, --who scheduled the game, and gets the capture link
+    message_id       numeric,           --the RSVP message
+    start_time       integer  NOT NULL, --2038 problem, but I do not care
+    remind_minutes   smallint NOT NULL, --how long before start_time the RSVP'd users are pinged; 0 to not ping them
+    reminded         bool     NOT NULL DEFAULT false
+);
+
+create table if not exists scheduled_games_rsvps
+(
+    schedule_id bigint REFERENCES scheduled_games ON DELETE CASCADE, --if a scheduled game is deleted, delete its RSVPs
+    user_id     numeric NOT NULL,
+    PRIMARY KEY (schedule_id, user_id)
+);
+
+create table if not exists identity_links
+(
+    guild_id     numeric  NOT NULL references guilds ON DELETE CASCADE, --if the guild is deleted, delete their links, too
+    user_id      numeric  NOT NULL,
+    in_game_name text     NOT NULL,
+    friend_code  text,              --only if the capture provides it
+    confidence   smallint NOT NULL, --how the link was made; 1 for a name match, 2 for a reaction, 3 for a manual link
+    linked_at    integer  NOT NULL,
+    last_seen    integer  NOT NULL,
+    PRIMARY KEY (guild_id, user_id, in_game_name)
+);
+
 create index if not exists guilds_id_index ON guilds (guild_id); --query guilds by ID
 create index if not exists guilds_premium_index ON guilds (premium); --query guilds by prem status
 
@@ -64,4 +97,8 @@ create index if not exists users_games_role_index ON users_games (player_role);
 create index if not exists users_games_won_index ON users_games (player_won); --query games by win status
 
 create index if not exists game_events_game_id_index on game_events (game_id); --query for game events by the game ID
-create index if not exists game_events_user_id_index on game_events (user_id); --query for game events by the user ID
\ No newline at end of file
+create index if not exists game_events_user_id_index on game_events (user_id); --query for game events by the user ID
+
+create index if not exists scheduled_games_guild_id_index on scheduled_games (guild_id); --query scheduled games by guild ID
+create index if not exists scheduled_games_start_time_index on scheduled_games (start_time); --query scheduled games that are due
+create index if not exists identity_links_name_index on identity_links (guild_id, lower(in_game_name)); --query who claims an in-game name
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+
+	"github.com/automuteus/utils/pkg/game"
+)
+
+// MaxVoicePresets is how many custom voice presets a guild can save
+const MaxVoicePresets = 10
+
+// VoicePreset is a named set of the settings that decide who is muted/deafened and when, so they can be switched
+// together instead of one voiceRules/delays command at a time
+type VoicePreset struct {
+	VoiceRules            game.VoiceRules `json:"voiceRules"`
+	Delays                game.GameDelays `json:"delays"`
+	UnmuteDeadDuringTasks bool            `json:"unmuteDeadDuringTasks"`
+	MuteSpectator         bool            `json:"muteSpectator"`
+}
+
+// BuiltinVoicePresets are the presets every guild has, by name. They're functions so every preset applied gets its
+// own rules and delays, instead of sharing (and modifying) the same maps
+var BuiltinVoicePresets = map[string]func() VoicePreset{
+	// the default settings: alive players are muted and deafened during tasks, dead players are muted in meetings
+	"classic": func() VoicePreset {
+		return VoicePreset{
+			VoiceRules: game.MakeMuteAndDeafenRules(),
+			Delays:     game.MakeDefaultDelays(),
+		}
+	},
+	// dead players are unmuted as soon as they die, to talk with the other ghosts (and spectators) during tasks
+	"ghosts-talk": func() VoicePreset {
+		return VoicePreset{
+			VoiceRules:            game.MakeMuteAndDeafenRules(),
+			Delays:                game.MakeDefaultDelays(),
+			UnmuteDeadDuringTasks: true,
+			MuteSpectator:         true,
+		}
+	},
+	// there are no meetings; seekers and hiders stay silent, and caught players are free to talk right away
+	"hide-and-seek": func() VoicePreset {
+		preset := VoicePreset{
+			VoiceRules:            game.MakeMuteAndDeafenRules(),
+			Delays:                game.MakeDefaultDelays(),
+			UnmuteDeadDuringTasks: true,
+		}
+		preset.Delays.Delays[game.PhaseNames[game.LOBBY]][game.PhaseNames[game.TASKS]] = 0
+		return preset
+	},
+	// longer delays, so a stream running behind the game doesn't give away who's still talking, and spectators (who
+	// might be watching the stream) are muted like dead players
+	"streamer-safe": func() VoicePreset {
+		preset := VoicePreset{
+			VoiceRules:    game.MakeMuteAndDeafenRules(),
+			Delays:        game.MakeDefaultDelays(),
+			MuteSpectator: true,
+		}
+		delays := preset.Delays.Delays
+		delays[game.PhaseNames[game.LOBBY]][game.PhaseNames[game.TASKS]] = 10
+		delays[game.PhaseNames[game.DISCUSS]][game.PhaseNames[game.TASKS]] = 10
+		delays[game.PhaseNames[game.DISCUSS]][game.PhaseNames[game.LOBBY]] = 10
+		return preset
+	},
+	// nobody is ever deafened; alive players are only muted during tasks
+	"casual": func() VoicePreset {
+		preset := VoicePreset{
+			VoiceRules: game.MakeMuteAndDeafenRules(),
+			Delays:     game.MakeDefaultDelays(),
+		}
+		for _, rules := range preset.VoiceRules.DeafRules {
+			rules["alive"] = false
+			rules["dead"] = false
+		}
+		return preset
+	},
+}
+
+// VoicePresetNames are the names of the builtin presets, then the custom ones of the guild, in alphabetical order
+func (gs *GuildSettings) VoicePresetNames() (builtin, custom []string) {
+	for name := range BuiltinVoicePresets {
+		builtin = append(builtin, name)
+	}
+	for name := range gs.VoicePresets {
+		custom = append(custom, name)
+	}
+	sort.Strings(builtin)
+	sort.Strings(custom)
+	return builtin, custom
+}
+
+// GetVoicePreset returns the builtin or custom preset with that name
+func (gs *GuildSettings) GetVoicePreset(name string) (VoicePreset, bool) {
+	if preset, ok := BuiltinVoicePresets[name]; ok {
+		return preset(), true
+	}
+	preset, ok := gs.VoicePresets[name]
+	return preset, ok
+}
+
+// CurrentVoicePreset is the current voice settings, as a preset
+func (gs *GuildSettings) CurrentVoicePreset() VoicePreset {
+	preset := VoicePreset{
+		VoiceRules:            gs.VoiceRules,
+		Delays:                gs.Delays,
+		UnmuteDeadDuringTasks: gs.UnmuteDeadDuringTasks,
+		MuteSpectator:         gs.MuteSpectator,
+	}
+	// don't share the maps with the settings, so changing one doesn't change the other
+	var cpy VoicePreset
+	jBytes, err := json.Marshal(preset)
+	if err == nil {
+		err = json.Unmarshal(jBytes, &cpy)
+	}
+	if err != nil {
+		log.Println(err)
+		return preset
+	}
+	return cpy
+}
+
+func (gs *GuildSettings) SetVoicePreset(name string, preset VoicePreset) {
+	if gs.VoicePresets == nil {
+		gs.VoicePresets = map[string]VoicePreset{}
+	}
+	gs.VoicePresets[name] = preset
+}
+
+func (gs *GuildSettings) DeleteVoicePreset(name string) {
+	delete(gs.VoicePresets, name)
+}
+
+// ApplyVoicePreset replaces the voice settings with the ones of the preset
+func (gs *GuildSettings) ApplyVoicePreset(preset VoicePreset) {
+	gs.VoiceRules = preset.VoiceRules
+	gs.Delays = preset.Delays
+	gs.UnmuteDeadDuringTasks = preset.UnmuteDeadDuringTasks
+	gs.MuteSpectator = preset.MuteSpectator
+}
@@ -5,16 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/automuteus/utils/pkg/rediskey"
-	"github.com/automuteus/utils/pkg/settings"
 	"github.com/go-redis/redis/v8"
 	"log"
-	"os"
 )
 
 var ctx = context.Background()
 
 type StorageInterface struct {
-	client *redis.Client
+	client redis.UniversalClient
+	// GlobalPrefix is the command prefix of guilds that haven't changed it
+	GlobalPrefix string
 }
 
 type RedisParameters struct {
@@ -35,18 +35,18 @@ func (storageInterface *StorageInterface) Init(params interface{}) error {
 	return nil
 }
 
-func (storageInterface *StorageInterface) GetGuildSettings(guildID string) *settings.GuildSettings {
-	globalPrefix := os.Getenv("AUTOMUTEUS_GLOBAL_PREFIX")
+func (storageInterface *StorageInterface) GetGuildSettings(guildID string) *GuildSettings {
+	globalPrefix := storageInterface.GlobalPrefix
 	key := rediskey.GuildSettings(string(HashGuildID(guildID)))
 
 	j, err := storageInterface.client.Get(ctx, key).Result()
 	switch {
 	case errors.Is(err, redis.Nil):
-		s := settings.MakeGuildSettings(globalPrefix)
+		s := MakeGuildSettings(globalPrefix)
 		jBytes, err := json.MarshalIndent(s, "", "  ")
 		if err != nil {
 			log.Println(err)
-			return settings.MakeGuildSettings(globalPrefix)
+			return MakeGuildSettings(globalPrefix)
 		}
 		err = storageInterface.client.Set(ctx, key, jBytes, 0).Err()
 		if err != nil {
@@ -55,33 +55,41 @@ func (storageInterface *StorageInterface) GetGuildSettings(guildID string) *sett
 		return s
 	case err != nil:
 		log.Println(err)
-		return settings.MakeGuildSettings(globalPrefix)
+		return MakeGuildSettings(globalPrefix)
 	default:
-		s := settings.GuildSettings{}
+		s := GuildSettings{}
 		err := json.Unmarshal([]byte(j), &s)
 		if err != nil {
 			log.Println(err)
-			return settings.MakeGuildSettings(globalPrefix)
+			return MakeGuildSettings(globalPrefix)
 		}
 		return &s
 	}
 }
 
-func (storageInterface *StorageInterface) SetGuildSettings(guildID string, guildSettings *settings.GuildSettings) error {
+func (storageInterface *StorageInterface) SetGuildSettings(guildID string, guildSettings *GuildSettings) error {
 	key := rediskey.GuildSettings(string(HashGuildID(guildID)))
 
 	jbytes, err := json.MarshalIndent(guildSettings, "", "  ")
 	if err != nil {
 		return err
 	}
-	err = storageInterface.client.Set(ctx, key, jbytes, 0).Err()
-	return err
+	previous, err := storageInterface.client.GetSet(ctx, key, jbytes).Result()
+	switch {
+	case errors.Is(err, redis.Nil):
+		return nil
+	case err != nil:
+		return err
+	case previous == string(jbytes):
+		return nil
+	}
+	return storageInterface.addSettingsRevision(guildID, previous)
 }
 
 func (storageInterface *StorageInterface) DeleteGuildSettings(guildID string) error {
 	key := rediskey.GuildSettings(string(HashGuildID(guildID)))
 
-	err := storageInterface.client.Del(ctx, key).Err()
+	err := storageInterface.client.Del(ctx, key, settingsRevisionsKey(guildID)).Err()
 	return err
 }
 
@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// fakeRedis keeps strings and lists in memory. Only the commands used by the settings are implemented; any other
+// command panics on the nil client it embeds
+type fakeRedis struct {
+	redis.UniversalClient
+	strings map[string]string
+	lists   map[string][]string
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{
+		strings: map[string]string{},
+		lists:   map[string][]string{},
+	}
+}
+
+func newTestStorage() (*StorageInterface, *fakeRedis) {
+	client := newFakeRedis()
+	return &StorageInterface{client: client, GlobalPrefix: ".au"}, client
+}
+
+func redisString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(value)
+}
+
+// listRange only supports non-negative indexes, like the ones the storage uses
+func listRange(list []string, start, stop int64) []string {
+	if stop >= int64(len(list)) {
+		stop = int64(len(list)) - 1
+	}
+	if start > stop {
+		return []string{}
+	}
+	return append([]string{}, list[start:stop+1]...)
+}
+
+func (r *fakeRedis) Get(_ context.Context, key string) *redis.StringCmd {
+	val, ok := r.strings[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(val, nil)
+}
+
+func (r *fakeRedis) GetSet(c context.Context, key string, value interface{}) *redis.StringCmd {
+	cmd := r.Get(c, key)
+	r.strings[key] = redisString(value)
+	return cmd
+}
+
+func (r *fakeRedis) Set(_ context.Context, key string, value interface{}, _ time.Duration) *redis.StatusCmd {
+	r.strings[key] = redisString(value)
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (r *fakeRedis) Del(_ context.Context, keys ...string) *redis.IntCmd {
+	var deleted int64
+	for _, key := range keys {
+		if _, ok := r.strings[key]; ok {
+			delete(r.strings, key)
+			deleted++
+		}
+		if _, ok := r.lists[key]; ok {
+			delete(r.lists, key)
+			deleted++
+		}
+	}
+	return redis.NewIntResult(deleted, nil)
+}
+
+func (r *fakeRedis) LPush(_ context.Context, key string, values ...interface{}) *redis.IntCmd {
+	for _, value := range values {
+		r.lists[key] = append([]string{redisString(value)}, r.lists[key]...)
+	}
+	return redis.NewIntResult(int64(len(r.lists[key])), nil)
+}
+
+func (r *fakeRedis) LTrim(_ context.Context, key string, start, stop int64) *redis.StatusCmd {
+	r.lists[key] = listRange(r.lists[key], start, stop)
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (r *fakeRedis) LRange(_ context.Context, key string, start, stop int64) *redis.StringSliceCmd {
+	return redis.NewStringSliceResult(listRange(r.lists[key], start, stop), nil)
+}
+
+func (r *fakeRedis) TxPipeline() redis.Pipeliner {
+	return &fakePipeline{r: r}
+}
+
+// fakePipeline runs the commands right away instead of on Exec, which the storage can't tell apart
+type fakePipeline struct {
+	redis.Pipeliner
+	r    *fakeRedis
+	cmds []redis.Cmder
+}
+
+func (p *fakePipeline) Get(c context.Context, key string) *redis.StringCmd {
+	cmd := p.r.Get(c, key)
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+func (p *fakePipeline) Del(c context.Context, keys ...string) *redis.IntCmd {
+	cmd := p.r.Del(c, keys...)
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+func (p *fakePipeline) LPush(c context.Context, key string, values ...interface{}) *redis.IntCmd {
+	cmd := p.r.LPush(c, key, values...)
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+func (p *fakePipeline) LTrim(c context.Context, key string, start, stop int64) *redis.StatusCmd {
+	cmd := p.r.LTrim(c, key, start, stop)
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+// Exec returns the error of the first failed command, like go-redis does
+func (p *fakePipeline) Exec(_ context.Context) ([]redis.Cmder, error) {
+	for _, cmd := range p.cmds {
+		if err := cmd.Err(); err != nil {
+			return p.cmds, err
+		}
+	}
+	return p.cmds, nil
+}
+
+func TestSetGuildSettings(t *testing.T) {
+	storageInterface, _ := newTestStorage()
+
+	sett := MakeGuildSettings(".au")
+	if err := storageInterface.SetGuildSettings("1", sett); err != nil {
+		t.Fatal(err)
+	}
+	if revisions, _ := storageInterface.GetGuildSettingsRevisions("1"); len(revisions) != 0 {
+		t.Errorf("The first save of the settings shouldn't create a revision, got %d", len(revisions))
+	}
+
+	// saving the exact same settings again, like a setting set to the value it already has
+	if err := storageInterface.SetGuildSettings("1", MakeGuildSettings(".au")); err != nil {
+		t.Fatal(err)
+	}
+	if revisions, _ := storageInterface.GetGuildSettingsRevisions("1"); len(revisions) != 0 {
+		t.Errorf("Saving unchanged settings shouldn't create a revision, got %d", len(revisions))
+	}
+
+	sett.SetAuditChannelID("754465589958803548")
+	if err := storageInterface.SetGuildSettings("1", sett); err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := storageInterface.GetGuildSettingsRevisions("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 || revisions[0].GetAuditChannelID() != "" {
+		t.Errorf("Changing the settings should keep the previous version as a revision, got %v", revisions)
+	}
+	if storageInterface.GetGuildSettings("1").GetAuditChannelID() != "754465589958803548" {
+		t.Error("The changed settings weren't saved")
+	}
+
+	if revisions, _ := storageInterface.GetGuildSettingsRevisions("2"); len(revisions) != 0 {
+		t.Errorf("Revisions should be kept per guild, got %d for another guild", len(revisions))
+	}
+}
+
+func TestDeleteGuildSettings(t *testing.T) {
+	storageInterface, _ := newTestStorage()
+	storageInterface.SetGuildSettings("1", MakeGuildSettings(".au"))
+	storageInterface.SetGuildSettings("1", MakeGuildSettings("!"))
+
+	if err := storageInterface.DeleteGuildSettings("1"); err != nil {
+		t.Fatal(err)
+	}
+	if revisions, _ := storageInterface.GetGuildSettingsRevisions("1"); len(revisions) != 0 {
+		t.Errorf("Deleting the settings should delete their revisions too, got %d", len(revisions))
+	}
+	if storageInterface.GetGuildSettings("1").CommandPrefix != ".au" {
+		t.Error("Deleted settings should go back to the defaults")
+	}
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SettingsRevisions is how many previous versions of the settings are kept for each guild, so they can be rolled back
+const SettingsRevisions = 10
+
+func settingsRevisionsKey(guildID string) string {
+	return "automuteus:settings:revisions:" + string(HashGuildID(guildID))
+}
+
+func settingsRollbackKey(guildID, rollbackID string) string {
+	return "automuteus:settings:rollback:" + string(HashGuildID(guildID)) + ":" + rollbackID
+}
+
+func (storageInterface *StorageInterface) addSettingsRevision(guildID, jsonStr string) error {
+	key := settingsRevisionsKey(guildID)
+	pipe := storageInterface.client.TxPipeline()
+	pipe.LPush(ctx, key, jsonStr)
+	pipe.LTrim(ctx, key, 0, SettingsRevisions-1)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetGuildSettingsRevisions returns the previous versions of the guild's settings, newest first. The current settings
+// aren't included
+func (storageInterface *StorageInterface) GetGuildSettingsRevisions(guildID string) ([]*GuildSettings, error) {
+	revisions, err := storageInterface.client.LRange(ctx, settingsRevisionsKey(guildID), 0, SettingsRevisions-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	setts := make([]*GuildSettings, 0, len(revisions))
+	for _, jsonStr := range revisions {
+		s := GuildSettings{}
+		err := json.Unmarshal([]byte(jsonStr), &s)
+		if err != nil {
+			return nil, err
+		}
+		setts = append(setts, &s)
+	}
+	return setts, nil
+}
+
+// SetPendingSettingsRollback keeps the version of the settings a rollback would restore, until an admin confirms (or
+// cancels) the rollback, or it expires
+func (storageInterface *StorageInterface) SetPendingSettingsRollback(guildID, rollbackID string, revision *GuildSettings, expiration time.Duration) error {
+	jBytes, err := json.Marshal(revision)
+	if err != nil {
+		return err
+	}
+	return storageInterface.client.Set(ctx, settingsRollbackKey(guildID, rollbackID), jBytes, expiration).Err()
+}
+
+// TakePendingSettingsRollback returns the version of the settings a rollback would restore, and removes it, so the
+// rollback can only be confirmed (or cancelled) once. Returns nil if there's no such rollback, or it has expired
+func (storageInterface *StorageInterface) TakePendingSettingsRollback(guildID, rollbackID string) (*GuildSettings, error) {
+	key := settingsRollbackKey(guildID, rollbackID)
+	pipe := storageInterface.client.TxPipeline()
+	get := pipe.Get(ctx, key)
+	pipe.Del(ctx, key)
+	_, err := pipe.Exec(ctx)
+	switch {
+	case errors.Is(err, redis.Nil):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	revision := GuildSettings{}
+	err = json.Unmarshal([]byte(get.Val()), &revision)
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
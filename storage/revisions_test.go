@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGetGuildSettingsRevisionsTrimmed(t *testing.T) {
+	storageInterface, client := newTestStorage()
+	storageInterface.SetGuildSettings("1", MakeGuildSettings("0"))
+	for i := 1; i <= SettingsRevisions+5; i++ {
+		if err := storageInterface.SetGuildSettings("1", MakeGuildSettings(strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if stored := len(client.lists[settingsRevisionsKey("1")]); stored != SettingsRevisions {
+		t.Errorf("Expected only the last %d revisions to be stored, got %d", SettingsRevisions, stored)
+	}
+	revisions, err := storageInterface.GetGuildSettingsRevisions("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != SettingsRevisions {
+		t.Fatalf("Expected %d revisions, got %d", SettingsRevisions, len(revisions))
+	}
+	// newest first; the current settings ("15") aren't a revision
+	for i, revision := range revisions {
+		if expected := strconv.Itoa(SettingsRevisions + 4 - i); revision.CommandPrefix != expected {
+			t.Errorf("Expected revision %d to have the prefix %s, got %s", i, expected, revision.CommandPrefix)
+		}
+	}
+}
+
+func TestRollbackSettingsReset(t *testing.T) {
+	storageInterface, _ := newTestStorage()
+	sett := MakeGuildSettings("!")
+	sett.SetAuditChannelID("754465589958803548")
+	sett.SetVoiceOverride("140581066283941888", OverrideExempt)
+	storageInterface.SetGuildSettings("1", sett)
+
+	// settings reset
+	storageInterface.SetGuildSettings("1", MakeGuildSettings(storageInterface.GlobalPrefix))
+
+	revisions, err := storageInterface.GetGuildSettingsRevisions("1")
+	if err != nil || len(revisions) != 1 {
+		t.Fatalf("Expected the reset to keep the settings before it as a revision, got %v (%v)", revisions, err)
+	}
+	if err := storageInterface.SetPendingSettingsRollback("1", "10", revisions[0], time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	revision, err := storageInterface.TakePendingSettingsRollback("1", "10")
+	if err != nil || revision == nil {
+		t.Fatalf("Expected the pending rollback, got %v (%v)", revision, err)
+	}
+	if err := storageInterface.SetGuildSettings("1", revision); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := storageInterface.GetGuildSettings("1")
+	if restored.CommandPrefix != "!" || restored.GetAuditChannelID() != "754465589958803548" {
+		t.Errorf("Rolling back the reset should restore the settings before it, got %+v", restored)
+	}
+	if override, _ := restored.GetVoiceOverride("140581066283941888"); override != OverrideExempt {
+		t.Errorf("Rolling back the reset should restore the voice overrides, got %s", override)
+	}
+	// the rollback can be undone as well
+	revisions, _ = storageInterface.GetGuildSettingsRevisions("1")
+	if len(revisions) != 2 || revisions[0].CommandPrefix != storageInterface.GlobalPrefix {
+		t.Errorf("Expected the reset settings to be the newest revision, got %v", revisions)
+	}
+}
+
+func TestTakePendingSettingsRollback(t *testing.T) {
+	storageInterface, _ := newTestStorage()
+	if revision, err := storageInterface.TakePendingSettingsRollback("1", "10"); revision != nil || err != nil {
+		t.Errorf("A rollback that was never started (or expired) shouldn't be found, got %v (%v)", revision, err)
+	}
+
+	storageInterface.SetPendingSettingsRollback("1", "10", MakeGuildSettings("!"), time.Minute)
+	if revision, _ := storageInterface.TakePendingSettingsRollback("2", "10"); revision != nil {
+		t.Error("A rollback shouldn't be found from another guild")
+	}
+	if revision, _ := storageInterface.TakePendingSettingsRollback("1", "10"); revision == nil || revision.CommandPrefix != "!" {
+		t.Errorf("Expected the pending rollback, got %v", revision)
+	}
+	// applying and cancelling both take the rollback, so it's never applied twice
+	if revision, _ := storageInterface.TakePendingSettingsRollback("1", "10"); revision != nil {
+		t.Error("A rollback should only be taken once")
+	}
+}
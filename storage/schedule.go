@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"errors"
+	"strconv"
+
+	storageutils "github.com/automuteus/utils/pkg/storage"
+)
+
+// MaxScheduledGames is how many upcoming games a guild can schedule at once
+const MaxScheduledGames = 10
+
+var ErrScheduledGameNotFound = errors.New("no scheduled game found by that ID")
+
+// ScheduledGame is a game that starts on its own at StartTime, in the text channel it was scheduled in, tracking the
+// voice channel. The users that RSVP'd to it are pinged RemindMinutes before it starts
+type ScheduledGame struct {
+	ScheduleID     int64
+	GuildID        string
+	TextChannelID  string
+	VoiceChannelID string
+	HostID         string
+	// MessageID is the RSVP message, once it's sent
+	MessageID     string
+	StartTime     int64
+	RemindMinutes int
+	Reminded      bool
+}
+
+// ScheduleInterface stores the scheduled games, and who RSVP'd to them, in Postgres
+type ScheduleInterface struct {
+	psql *storageutils.PsqlInterface
+}
+
+func NewScheduleInterface(psql *storageutils.PsqlInterface) *ScheduleInterface {
+	return &ScheduleInterface{psql: psql}
+}
+
+const scheduledGameColumns = "schedule_id, guild_id, text_channel_id, voice_channel_id, host_id, coalesce(message_id, 0), start_time, remind_minutes, reminded"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanScheduledGame(row rowScanner) (ScheduledGame, error) {
+	var guildID, textChannelID, voiceChannelID, hostID, messageID uint64
+	var startTime int32
+	var remindMinutes int16
+	sg := ScheduledGame{}
+	err := row.Scan(&sg.ScheduleID, &guildID, &textChannelID, &voiceChannelID, &hostID, &messageID, &startTime, &remindMinutes, &sg.Reminded)
+	if err != nil {
+		return sg, err
+	}
+	sg.GuildID = strconv.FormatUint(guildID, 10)
+	sg.TextChannelID = strconv.FormatUint(textChannelID, 10)
+	sg.VoiceChannelID = strconv.FormatUint(voiceChannelID, 10)
+	sg.HostID = strconv.FormatUint(hostID, 10)
+	if messageID != 0 {
+		sg.MessageID = strconv.FormatUint(messageID, 10)
+	}
+	sg.StartTime = int64(startTime)
+	sg.RemindMinutes = int(remindMinutes)
+	return sg, nil
+}
+
+func (scheduleInterface *ScheduleInterface) queryScheduledGames(sql string, args ...interface{}) ([]ScheduledGame, error) {
+	rows, err := scheduleInterface.psql.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var games []ScheduledGame
+	for rows.Next() {
+		sg, err := scanScheduledGame(rows)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, sg)
+	}
+	return games, rows.Err()
+}
+
+func parseIDs(ids ...string) ([]uint64, error) {
+	parsed := make([]uint64, len(ids))
+	for i, id := range ids {
+		var err error
+		parsed[i], err = strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parsed, nil
+}
+
+// AddScheduledGame stores the game, and returns its ID
+func (scheduleInterface *ScheduleInterface) AddScheduledGame(sg ScheduledGame) (int64, error) {
+	ids, err := parseIDs(sg.GuildID, sg.TextChannelID, sg.VoiceChannelID, sg.HostID)
+	if err != nil {
+		return 0, err
+	}
+	var scheduleID int64
+	err = scheduleInterface.psql.Pool.QueryRow(ctx, "INSERT INTO scheduled_games (guild_id, text_channel_id, voice_channel_id, host_id, start_time, remind_minutes) VALUES ($1, $2, $3, $4, $5, $6) RETURNING schedule_id;",
+		ids[0], ids[1], ids[2], ids[3], int32(sg.StartTime), int16(sg.RemindMinutes)).Scan(&scheduleID)
+	return scheduleID, err
+}
+
+func (scheduleInterface *ScheduleInterface) SetScheduledGameMessage(scheduleID int64, messageID string) error {
+	ids, err := parseIDs(messageID)
+	if err != nil {
+		return err
+	}
+	_, err = scheduleInterface.psql.Pool.Exec(ctx, "UPDATE scheduled_games SET message_id = $1 WHERE schedule_id = $2;", ids[0], scheduleID)
+	return err
+}
+
+// GetScheduledGame returns the game, as long as it's one of the guild's
+func (scheduleInterface *ScheduleInterface) GetScheduledGame(guildID string, scheduleID int64) (*ScheduledGame, error) {
+	ids, err := parseIDs(guildID)
+	if err != nil {
+		return nil, err
+	}
+	games, err := scheduleInterface.queryScheduledGames("SELECT "+scheduledGameColumns+" FROM scheduled_games WHERE schedule_id = $1 AND guild_id = $2;", scheduleID, ids[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(games) == 0 {
+		return nil, ErrScheduledGameNotFound
+	}
+	return &games[0], nil
+}
+
+// GetScheduledGames returns the upcoming games of the guild, soonest first
+func (scheduleInterface *ScheduleInterface) GetScheduledGames(guildID string) ([]ScheduledGame, error) {
+	ids, err := parseIDs(guildID)
+	if err != nil {
+		return nil, err
+	}
+	return scheduleInterface.queryScheduledGames("SELECT "+scheduledGameColumns+" FROM scheduled_games WHERE guild_id = $1 ORDER BY start_time;", ids[0])
+}
+
+// DeleteScheduledGame deletes the game (and its RSVPs), as long as it's one of the guild's
+func (scheduleInterface *ScheduleInterface) DeleteScheduledGame(guildID string, scheduleID int64) error {
+	ids, err := parseIDs(guildID)
+	if err != nil {
+		return err
+	}
+	tag, err := scheduleInterface.psql.Pool.Exec(ctx, "DELETE FROM scheduled_games WHERE schedule_id = $1 AND guild_id = $2;", scheduleID, ids[0])
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrScheduledGameNotFound
+	}
+	return nil
+}
+
+// ToggleRSVP RSVPs the user to the game, or takes back their RSVP if they had one. Returns whether they're going
+func (scheduleInterface *ScheduleInterface) ToggleRSVP(scheduleID int64, userID string) (bool, error) {
+	ids, err := parseIDs(userID)
+	if err != nil {
+		return false, err
+	}
+	tag, err := scheduleInterface.psql.Pool.Exec(ctx, "DELETE FROM scheduled_games_rsvps WHERE schedule_id = $1 AND user_id = $2;", scheduleID, ids[0])
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() > 0 {
+		return false, nil
+	}
+	// the foreign key fails the insert if the game was cancelled (or started) since
+	_, err = scheduleInterface.psql.Pool.Exec(ctx, "INSERT INTO scheduled_games_rsvps VALUES ($1, $2) ON CONFLICT DO NOTHING;", scheduleID, ids[0])
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetRSVPs returns the IDs of the users going to the game
+func (scheduleInterface *ScheduleInterface) GetRSVPs(scheduleID int64) ([]string, error) {
+	rows, err := scheduleInterface.psql.Pool.Query(ctx, "SELECT user_id FROM scheduled_games_rsvps WHERE schedule_id = $1 ORDER BY user_id;", scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID uint64
+		err := rows.Scan(&userID)
+		if err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, strconv.FormatUint(userID, 10))
+	}
+	return userIDs, rows.Err()
+}
+
+// shardFilter only matches the guilds of the shard, the same way Discord splits the guilds between shards
+const shardFilter = "(guild_id::bigint >> 22) % $2 = $3"
+
+// ClaimDueReminders marks the games of the shard that need their reminder sent by now as reminded, and returns them.
+// Claiming them in one statement means only one instance sends each reminder
+func (scheduleInterface *ScheduleInterface) ClaimDueReminders(now int64, shardCount, shardID int) ([]ScheduledGame, error) {
+	return scheduleInterface.queryScheduledGames("UPDATE scheduled_games SET reminded = true WHERE NOT reminded AND remind_minutes > 0 AND start_time - remind_minutes * 60 <= $1 AND start_time > $1 AND "+shardFilter+" RETURNING "+scheduledGameColumns+";",
+		int32(now), shardCount, shardID)
+}
+
+// ClaimDueScheduledGames deletes the games of the shard that should have started by now, and returns them, so only one
+// instance starts each game
+func (scheduleInterface *ScheduleInterface) ClaimDueScheduledGames(now int64, shardCount, shardID int) ([]ScheduledGame, error) {
+	return scheduleInterface.queryScheduledGames("DELETE FROM scheduled_games WHERE start_time <= $1 AND "+shardFilter+" RETURNING "+scheduledGameColumns+";",
+		int32(now), shardCount, shardID)
+}
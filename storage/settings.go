@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"github.com/automuteus/utils/pkg/settings"
+)
+
+// GuildSettings are the shared guild settings, plus the ones specific to this bot. The shared settings are embedded
+// so they're stored exactly as before (new fields are simply added alongside them)
+type GuildSettings struct {
+	settings.GuildSettings
+
+	VoiceOverrides map[string]VoiceOverride `json:"voiceOverrides"`
+
+	MoveDeadPlayers bool   `json:"moveDeadPlayers"`
+	GhostChannelID  string `json:"ghostChannelID"`
+
+	AuditChannelID string `json:"auditChannelID"`
+
+	// BenchChannelID is the voice channel players sitting out are moved to when the waitlist of a game rotates
+	BenchChannelID string `json:"benchChannelID,omitempty"`
+
+	// VoicePresets are the guild's own voice presets, by name
+	VoicePresets map[string]VoicePreset `json:"voicePresets,omitempty"`
+}
+
+func MakeGuildSettings(prefix string) *GuildSettings {
+	return &GuildSettings{
+		GuildSettings:  *settings.MakeGuildSettings(prefix),
+		VoiceOverrides: map[string]VoiceOverride{},
+	}
+}
+
+// VoiceOverride changes how the voice rules are applied to a single User, regardless of the guild-wide rules
+type VoiceOverride string
+
+const (
+	// OverrideExempt users are never muted or deafened (or unmuted/undeafened) by the bot
+	OverrideExempt VoiceOverride = "exempt"
+	// OverrideSpectator users are treated like spectators (dead, unlinked players), even if they're linked
+	OverrideSpectator VoiceOverride = "spectator"
+	// OverrideDeafenTasks users are always deafened during tasks, even if they're dead
+	OverrideDeafenTasks VoiceOverride = "deafentasks"
+)
+
+var AllVoiceOverrides = []VoiceOverride{OverrideExempt, OverrideSpectator, OverrideDeafenTasks}
+
+func (gs *GuildSettings) GetVoiceOverride(userID string) (VoiceOverride, bool) {
+	override, ok := gs.VoiceOverrides[userID]
+	return override, ok
+}
+
+func (gs *GuildSettings) SetVoiceOverride(userID string, override VoiceOverride) {
+	if gs.VoiceOverrides == nil {
+		gs.VoiceOverrides = map[string]VoiceOverride{}
+	}
+	gs.VoiceOverrides[userID] = override
+}
+
+func (gs *GuildSettings) ClearVoiceOverride(userID string) {
+	delete(gs.VoiceOverrides, userID)
+}
+
+func (gs *GuildSettings) GetMoveDeadPlayers() bool {
+	return gs.MoveDeadPlayers
+}
+
+func (gs *GuildSettings) SetMoveDeadPlayers(move bool) {
+	gs.MoveDeadPlayers = move
+}
+
+func (gs *GuildSettings) GetGhostChannelID() string {
+	return gs.GhostChannelID
+}
+
+func (gs *GuildSettings) SetGhostChannelID(channelID string) {
+	gs.GhostChannelID = channelID
+}
+
+func (gs *GuildSettings) GetAuditChannelID() string {
+	return gs.AuditChannelID
+}
+
+func (gs *GuildSettings) SetAuditChannelID(channelID string) {
+	gs.AuditChannelID = channelID
+}
+
+func (gs *GuildSettings) GetBenchChannelID() string {
+	return gs.BenchChannelID
+}
+
+func (gs *GuildSettings) SetBenchChannelID(channelID string) {
+	gs.BenchChannelID = channelID
+}